@@ -1,12 +1,26 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
+	"github.com/WalnutBagel/go-marketplace/internal/api"
 	"github.com/WalnutBagel/go-marketplace/internal/db"
+	"github.com/WalnutBagel/go-marketplace/internal/events"
+	"github.com/WalnutBagel/go-marketplace/internal/imaging"
+	"github.com/WalnutBagel/go-marketplace/internal/mail"
 	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/ratelimit"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
 	"github.com/WalnutBagel/go-marketplace/internal/router"
+	"github.com/WalnutBagel/go-marketplace/internal/screening"
+	"github.com/WalnutBagel/go-marketplace/internal/storage"
+	"github.com/WalnutBagel/go-marketplace/internal/worker"
 )
 
 func main() {
@@ -16,10 +30,210 @@ func main() {
 	}
 	log.Println("✅ Успешное подключение к БД")
 
-	if err := db.GetDB().AutoMigrate(&models.User{}, &models.Ad{}); err != nil {
+	err = db.GetDB().AutoMigrate(
+		&models.User{},
+		&models.Ad{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.UserToken{},
+		&models.Category{},
+		&models.AdImage{},
+		&models.Favorite{},
+		&models.Thread{},
+		&models.Message{},
+		&models.SavedSearch{},
+		&models.Notification{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.OutboxEvent{},
+		&models.Offer{},
+		&models.Auction{},
+		&models.Bid{},
+		&models.Review{},
+		&models.ModerationAction{},
+		&models.Report{},
+	)
+	if err != nil {
 		log.Fatalf("Ошибка миграции: %v", err)
 	}
+	if err := repository.MigrateAdSearch(db.GetDB()); err != nil {
+		log.Fatalf("Ошибка миграции полнотекстового поиска: %v", err)
+	}
+
+	blobs, err := newStorage()
+	if err != nil {
+		log.Fatalf("Ошибка инициализации хранилища: %v", err)
+	}
+
+	images := repository.NewGormAdImageRepository(db.GetDB())
+	thumbnails := imaging.NewProcessor(blobs, images, 100)
+	go thumbnails.Run(context.Background(), 2)
+
+	lifetime, err := durationFromEnv("AD_LIFETIME", api.DefaultAdLifetime)
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации: %v", err)
+	}
+
+	offerLifetime, err := durationFromEnv("OFFER_LIFETIME", api.DefaultOfferLifetime)
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации: %v", err)
+	}
+
+	reportThreshold, err := intFromEnv("REPORT_HIDE_THRESHOLD", api.DefaultReportHideThreshold)
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации: %v", err)
+	}
+
+	mailer, err := newMailer()
+	if err != nil {
+		log.Fatalf("Ошибка инициализации почты: %v", err)
+	}
+
+	srv := &api.Server{
+		Users:         repository.NewGormUserRepository(db.GetDB()),
+		Ads:           repository.NewGormAdRepository(db.GetDB()),
+		Sessions:      repository.NewGormSessionRepository(db.GetDB()),
+		Tokens:        repository.NewGormUserTokenRepository(db.GetDB()),
+		Categories:    repository.NewGormCategoryRepository(db.GetDB()),
+		Images:        images,
+		Favorites:     repository.NewGormFavoriteRepository(db.GetDB()),
+		Threads:       repository.NewGormThreadRepository(db.GetDB()),
+		SavedSearches: repository.NewGormSavedSearchRepository(db.GetDB()),
+		Notifications: repository.NewGormNotificationRepository(db.GetDB()),
+		Webhooks:      repository.NewGormWebhookRepository(db.GetDB()),
+		Offers:        repository.NewGormOfferRepository(db.GetDB()),
+		Auctions:      repository.NewGormAuctionRepository(db.GetDB()),
+		Reviews:       repository.NewGormReviewRepository(db.GetDB()),
+		Moderation:    repository.NewGormModerationRepository(db.GetDB()),
+		Reports:       repository.NewGormReportRepository(db.GetDB()),
+		Outbox:        repository.NewGormOutboxRepository(db.GetDB()),
+		Tx:            repository.NewGormTransactor(db.GetDB()),
+		Storage:       blobs,
+		Thumbnails:    thumbnails,
+		Events:        events.NewHub(events.NewLocalBackend()),
+		AdLifetime:    lifetime,
+		OfferLifetime: offerLifetime,
+	}
+
+	srv.ReportHideThreshold = reportThreshold
+	// Не больше 3 писем в час на адрес и 20 запросов в час с одного IP
+	srv.MailLimitPerAddress = ratelimit.New(3, time.Hour)
+	srv.MailLimitPerIP = ratelimit.New(20, time.Hour)
+	srv.AppURL = os.Getenv("APP_URL")
+	srv.RequireEmailVerification = os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+
+	screeningConfig, err := screening.LoadConfig(os.Getenv("CONTENT_FILTER_CONFIG"))
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации фильтров: %v", err)
+	}
+	screener, err := screening.NewChain(screeningConfig, srv.Ads)
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации фильтров: %v", err)
+	}
+	srv.Screener = screener
+
+	webhooks := worker.NewWebhookDispatcher(srv.Webhooks, nil)
+	go webhooks.Run(context.Background(), 5*time.Second)
+
+	matcher := worker.NewSearchMatcher(srv.Ads, srv.SavedSearches, srv.Categories, srv.Notifications)
+	favoriters := worker.NewFavoritesNotifier(srv.Favorites, srv.DeliverFavoriteUpdate)
+
+	// Новые потребители событий объявлений подключаются сюда
+	outbox := worker.NewOutboxDispatcher(srv.Outbox, worker.Publishers{webhooks, matcher, favoriters})
+	go outbox.Run(context.Background(), time.Second)
+
+	go worker.RunAdExpiry(context.Background(), srv.Ads, time.Minute)
+	go worker.RunOfferExpiry(context.Background(), srv.Offers, time.Minute)
+
+	mailQueue := mail.NewQueue(mailer, 1000)
+	srv.Mailer = mailQueue
+	go mailQueue.Run(context.Background(), 2)
+
+	closer := worker.NewAuctionCloser(srv.Tx, srv.Auctions, srv.Ads, srv.Outbox, srv.Notifications)
+	go closer.Run(context.Background(), 10*time.Second)
 
 	log.Println("Сервер запущен на :8080")
-	log.Fatal(http.ListenAndServe(":8080", router.NewRouter()))
+	log.Fatal(http.ListenAndServe(":8080", router.NewRouter(srv)))
+}
+
+// newStorage выбирает хранилище картинок по STORAGE_DRIVER: local (по умолчанию) или s3.
+func newStorage() (storage.Storage, error) {
+	if os.Getenv("STORAGE_DRIVER") == "s3" {
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}, nil), nil
+	}
+
+	dir := os.Getenv("STORAGE_DIR")
+	if dir == "" {
+		dir = "./uploads"
+	}
+	return storage.NewLocalStorage(dir)
+}
+
+// newMailer выбирает способ отправки писем по MAIL_DRIVER: smtp или file
+// (письма складываются в MAIL_DIR). Значения по умолчанию нет: file,
+// включённый по ошибке в рабочем окружении, хранил бы на диске живые
+// ссылки восстановления пароля.
+func newMailer() (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "noreply@localhost"
+	}
+
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		port, err := intFromEnv("SMTP_PORT", 587)
+		if err != nil {
+			return nil, err
+		}
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}), nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./mail"
+		}
+		log.Printf("⚠️ MAIL_DRIVER=file: письма не отправляются, а сохраняются в %s вместе со ссылками восстановления пароля. Только для локальной разработки", dir)
+		return mail.NewFileMailer(dir, from)
+	case "":
+		return nil, fmt.Errorf("MAIL_DRIVER не задан: укажите smtp или file")
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER: неизвестный способ отправки %q", driver)
+	}
+}
+
+// intFromEnv читает положительное целое число.
+func intFromEnv(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s: ожидалось положительное целое число, получено %q", name, v)
+	}
+	return n, nil
+}
+
+// durationFromEnv читает длительность в формате time.ParseDuration (например, 720h).
+func durationFromEnv(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
 }
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/WalnutBagel/go-marketplace/internal/mail"
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/services"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+const (
+	// emailVerificationTTL — сколько действует ссылка подтверждения почты.
+	emailVerificationTTL = 48 * time.Hour
+	// passwordResetTTL — сколько действует ссылка восстановления пароля.
+	passwordResetTTL = time.Hour
+)
+
+// EmailRequest — адрес почты для повторного письма или восстановления пароля.
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+// VerifyEmailRequest — токен из письма подтверждения почты.
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ResetPasswordRequest — токен из письма восстановления и новый пароль.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailHandler подтверждает почту по токену из письма.
+func (s *Server) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	err := s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		user, err := s.useToken(ctx, models.TokenEmailVerification, req.Token)
+		if err != nil {
+			return err
+		}
+		return s.Users.MarkEmailVerified(ctx, user.ID, time.Now())
+	})
+	if err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerificationHandler повторно отправляет письмо подтверждения.
+// Ответ не зависит от того, есть ли такой адрес, чтобы по нему нельзя
+// было узнать, зарегистрирован ли пользователь.
+func (s *Server) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	email := normalizeEmail(req.Email)
+	if !s.allowMailRequest(w, r, email) {
+		return
+	}
+
+	user, err := s.Users.GetByEmail(r.Context(), email)
+	if err == nil && user.EmailVerifiedAt == nil {
+		if err := s.sendVerificationEmail(r.Context(), user); err != nil {
+			log.Printf("Ошибка отправки письма подтверждения пользователю %d: %v", user.ID, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ForgotPasswordHandler отправляет письмо со ссылкой восстановления пароля.
+// Прежние ссылки перестают действовать. Как и ResendVerificationHandler,
+// отвечает одинаково для известных и неизвестных адресов.
+func (s *Server) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req EmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	email := normalizeEmail(req.Email)
+	if !s.allowMailRequest(w, r, email) {
+		return
+	}
+
+	user, err := s.Users.GetByEmail(r.Context(), email)
+	if err == nil {
+		if err := s.sendPasswordResetEmail(r.Context(), user); err != nil {
+			log.Printf("Ошибка отправки письма восстановления пароля пользователю %d: %v", user.ID, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPasswordHandler задаёт новый пароль по токену из письма и
+// завершает все сессии пользователя.
+func (s *Server) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+	req.Password = strings.TrimSpace(req.Password)
+	if err := validatePassword(req.Password); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при хэшировании пароля")
+		return
+	}
+
+	err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		user, err := s.useToken(ctx, models.TokenPasswordReset, req.Token)
+		if err != nil {
+			return err
+		}
+		if err := s.Users.SetPassword(ctx, user.ID, string(hashedPassword)); err != nil {
+			return err
+		}
+		// Ссылка пришла на почту — значит, адрес принадлежит пользователю
+		if err := s.Users.MarkEmailVerified(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+		if err := s.Tokens.InvalidateUser(ctx, user.ID, models.TokenPasswordReset); err != nil {
+			return err
+		}
+		return s.Sessions.RevokeUserSessions(ctx, user.ID)
+	})
+	if err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errInvalidToken — токен из письма не найден, истёк или уже использован.
+var errInvalidToken = errors.New("ссылка недействительна или устарела")
+
+// useToken проверяет токен из письма, помечает его использованным и
+// возвращает владельца. Вызывается в транзакции: если дальнейшие
+// изменения не сохранятся, токен останется действительным.
+func (s *Server) useToken(ctx context.Context, purpose models.TokenPurpose, raw string) (*models.User, error) {
+	token, err := s.Tokens.Get(ctx, purpose, services.HashToken(raw))
+	if err != nil || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, errInvalidToken
+	}
+	consumed, err := s.Tokens.Consume(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errInvalidToken
+	}
+	return s.Users.GetByID(ctx, token.UserID)
+}
+
+func writeTokenError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidToken) {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при обработке ссылки")
+}
+
+// sendVerificationEmail отправляет письмо со ссылкой подтверждения почты.
+func (s *Server) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	return s.sendMailToken(ctx, user, models.TokenEmailVerification, emailVerificationTTL, "Подтверждение почты",
+		"Чтобы подтвердить адрес почты, перейдите по ссылке:\n\n%s\n\nСсылка действует 48 часов.",
+		"/verify-email")
+}
+
+// sendPasswordResetEmail отправляет письмо со ссылкой восстановления пароля.
+func (s *Server) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	return s.sendMailToken(ctx, user, models.TokenPasswordReset, passwordResetTTL, "Восстановление пароля",
+		"Чтобы задать новый пароль, перейдите по ссылке:\n\n%s\n\nСсылка действует 1 час. Если вы не запрашивали восстановление, просто проигнорируйте письмо.",
+		"/reset-password")
+}
+
+// sendAccountExistsEmail сообщает владельцу адреса, что на него пытались
+// зарегистрировать ещё одну учётную запись.
+func (s *Server) sendAccountExistsEmail(user *models.User) {
+	if s.Mailer == nil {
+		return
+	}
+	s.Mailer.Enqueue(mail.Message{
+		To:      user.Email,
+		Subject: "Учётная запись уже существует",
+		Body: fmt.Sprintf("Кто-то пытался зарегистрироваться с этим адресом почты, но у вас уже есть учётная запись %s.\n\n"+
+			"Если забыли пароль, запросите восстановление на сайте %s. Если это были не вы, просто проигнорируйте письмо.",
+			user.Username, s.appURL()),
+	})
+}
+
+// sendMailToken выпускает одноразовый токен с назначением purpose взамен
+// прежних и отправляет пользователю письмо со ссылкой path?token=...
+// В body ссылка подставляется вместо %s.
+func (s *Server) sendMailToken(ctx context.Context, user *models.User, purpose models.TokenPurpose, ttl time.Duration, subject, body, path string) error {
+	if s.Mailer == nil || user.Email == "" {
+		return nil
+	}
+
+	raw, err := services.NewOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+	err = s.Tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.Tokens.InvalidateUser(ctx, user.ID, purpose); err != nil {
+			return err
+		}
+		return s.Tokens.Create(ctx, &models.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenHash: services.HashToken(raw),
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.appURL() + path + "?token=" + url.QueryEscape(raw)
+	s.Mailer.Enqueue(mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf(body, link),
+	})
+	return nil
+}
+
+// allowMailRequest проверяет лимиты писем для адреса и IP клиента и при
+// превышении отвечает 429. Адрес учитывается независимо от того, есть ли
+// такой пользователь, чтобы лимит ничего не выдавал.
+func (s *Server) allowMailRequest(w http.ResponseWriter, r *http.Request, email string) bool {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if s.MailLimitPerIP != nil && !s.MailLimitPerIP.Allow(ip) ||
+		s.MailLimitPerAddress != nil && !s.MailLimitPerAddress.Allow(email) {
+		utils.WriteJSONError(w, http.StatusTooManyRequests, "слишком много запросов, попробуйте позже")
+		return false
+	}
+	return true
+}
+
+func (s *Server) appURL() string {
+	if s.AppURL == "" {
+		return DefaultAppURL
+	}
+	return strings.TrimRight(s.AppURL, "/")
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -8,10 +9,12 @@ import (
 	"strings"
 	"time"
 
-	"github.com/WalnutBagel/go-marketplace/internal/db"
 	"github.com/WalnutBagel/go-marketplace/internal/middleware"
 	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/screening"
 	"github.com/WalnutBagel/go-marketplace/internal/utils"
+	"github.com/WalnutBagel/go-marketplace/internal/worker"
 )
 
 // CreateAdRequest описывает структуру входящих данных для создания или обновления объявления.
@@ -20,23 +23,44 @@ type CreateAdRequest struct {
 	Description string  `json:"description"`
 	ImageURL    string  `json:"image_url"`
 	Price       float64 `json:"price"`
+	CategoryID  *uint   `json:"category_id"`
+	// Status учитывается только при создании: draft или published (по умолчанию).
+	// Дальше состояние меняется действиями /ads/{id}/publish и т.п.
+	Status models.AdStatus `json:"status,omitempty"`
+	// Auction — условия аукциона; учитывается только при создании. Цена
+	// аукциона задаётся start_price, поле price игнорируется.
+	Auction *AuctionRequest `json:"auction,omitempty"`
 }
 
 // AdResponse описывает структуру JSON-ответа с данными объявления.
 type AdResponse struct {
-	ID          uint         `json:"id"`
-	Title       string       `json:"title"`
-	Description string       `json:"description"`
-	ImageURL    string       `json:"image_url"`
-	Price       float64      `json:"price"`
-	CreatedAt   time.Time    `json:"created_at"`
-	User        UserResponse `json:"user"`
+	ID          uint               `json:"id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	ImageURL    string             `json:"image_url"`
+	Price       float64            `json:"price"`
+	CategoryID  *uint              `json:"category_id"`
+	ListingType models.ListingType `json:"listing_type"`
+	Auction     *AuctionResponse   `json:"auction,omitempty"`
+	Status      models.AdStatus    `json:"status"`
+	ExpiresAt   *time.Time         `json:"expires_at"`
+	Hidden      bool               `json:"hidden,omitempty"` // скрыто модерацией
+	Images      []ImageResponse    `json:"images"`
+	CreatedAt   time.Time          `json:"created_at"`
+	User        UserResponse       `json:"user"`
 }
 
-// UserResponse описывает пользователя в ответе.
+// UserResponse описывает пользователя в ответе. Rating — средняя оценка
+// в отзывах (0, если отзывов нет).
 type UserResponse struct {
-	ID       uint   `json:"id"`
-	Username string `json:"username"`
+	ID          uint    `json:"id"`
+	Username    string  `json:"username"`
+	Rating      float64 `json:"rating"`
+	ReviewCount int64   `json:"review_count"`
+}
+
+func toUserResponse(u *models.User) UserResponse {
+	return UserResponse{ID: u.ID, Username: u.Username, Rating: u.Rating(), ReviewCount: u.ReviewCount}
 }
 
 // getUsernameFromContext извлекает username из контекста запроса.
@@ -49,17 +73,99 @@ func getUsernameFromContext(r *http.Request) (string, error) {
 }
 
 // getUserByUsername загружает пользователя из базы по username.
-func getUserByUsername(username string) (*models.User, error) {
-	var user models.User
-	err := db.GetDB().Where("username = ?", username).First(&user).Error
+func (s *Server) getUserByUsername(r *http.Request, username string) (*models.User, error) {
+	user, err := s.Users.GetByUsername(r.Context(), username)
 	if err != nil {
 		return nil, errors.New("пользователь не найден в БД")
 	}
-	return &user, nil
+	return user, nil
+}
+
+// currentUser загружает текущего пользователя. При ошибке ответ уже записан.
+func (s *Server) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	username, err := getUsernameFromContext(r)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusUnauthorized, err.Error())
+		return nil, false
+	}
+	user, err := s.getUserByUsername(r, username)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusUnauthorized, err.Error())
+		return nil, false
+	}
+	return user, true
+}
+
+// pathID разбирает числовой сегмент пути с указанным номером:
+// для /ads/5/images/7 сегмент 1 — это 5, сегмент 3 — 7.
+func pathID(r *http.Request, index int) (uint, error) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if index >= len(segments) {
+		return 0, errors.New("сегмент пути отсутствует")
+	}
+	id, err := strconv.ParseUint(segments[index], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// pageParams разбирает параметры page и limit с теми же ограничениями,
+// что и в ленте. При ошибке ответ уже записан.
+func pageParams(w http.ResponseWriter, r *http.Request) (page, limit int, ok bool) {
+	page, limit = 1, 10
+
+	if p := r.URL.Query().Get("page"); p != "" {
+		val, err := strconv.Atoi(p)
+		if err != nil || val <= 0 {
+			utils.WriteJSONError(w, http.StatusBadRequest, "невалидный параметр page")
+			return 0, 0, false
+		}
+		page = val
+	}
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		val, err := strconv.Atoi(l)
+		if err != nil || val <= 0 || val > 100 {
+			utils.WriteJSONError(w, http.StatusBadRequest, "невалидный параметр limit")
+			return 0, 0, false
+		}
+		limit = val
+	}
+
+	return page, limit, true
+}
+
+// loadOwnedAd загружает объявление из пути /ads/{id}/... и проверяет,
+// что текущий пользователь — его владелец. При отказе ответ уже записан.
+func (s *Server) loadOwnedAd(w http.ResponseWriter, r *http.Request, deniedMsg string) (*models.Ad, bool) {
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return nil, false
+	}
+
+	username, err := getUsernameFromContext(r)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusUnauthorized, err.Error())
+		return nil, false
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return nil, false
+	}
+
+	if ad.User.Username != username {
+		utils.WriteJSONError(w, http.StatusForbidden, deniedMsg)
+		return nil, false
+	}
+	return ad, true
 }
 
 // validateCreateAdRequest проверяет корректность данных для объявления.
-func validateCreateAdRequest(req *CreateAdRequest) error {
+func (s *Server) validateCreateAdRequest(r *http.Request, req *CreateAdRequest) error {
 	req.Title = strings.TrimSpace(req.Title)
 	req.Description = strings.TrimSpace(req.Description)
 	req.ImageURL = strings.TrimSpace(req.ImageURL)
@@ -73,11 +179,16 @@ func validateCreateAdRequest(req *CreateAdRequest) error {
 	if req.Price <= 0 {
 		return errors.New("цена должна быть больше нуля")
 	}
+	if req.CategoryID != nil {
+		if _, err := s.Categories.GetByID(r.Context(), *req.CategoryID); err != nil {
+			return errors.New("раздел не найден")
+		}
+	}
 	return nil
 }
 
 // CreateAdHandler обрабатывает создание нового объявления.
-func CreateAdHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateAdHandler(w http.ResponseWriter, r *http.Request) {
 	username, err := getUsernameFromContext(r)
 	if err != nil {
 		utils.WriteJSONError(w, http.StatusUnauthorized, err.Error())
@@ -90,29 +201,87 @@ func CreateAdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := validateCreateAdRequest(&req); err != nil {
+	var auction *models.Auction
+	if req.Auction != nil {
+		if auction, err = newAuction(req.Auction); err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.Price = auction.StartPrice
+	}
+
+	if err := s.validateCreateAdRequest(r, &req); err != nil {
 		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	user, err := getUserByUsername(username)
+	user, err := s.getUserByUsername(r, username)
 	if err != nil {
 		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	status := models.AdStatusPublished
+	switch req.Status {
+	case "", models.AdStatusPublished:
+	case models.AdStatusDraft:
+		status = models.AdStatusDraft
+	default:
+		utils.WriteJSONError(w, http.StatusBadRequest, "новое объявление может быть только черновиком или опубликованным")
+		return
+	}
+
 	ad := models.Ad{
 		Title:       req.Title,
 		Description: req.Description,
 		ImageURL:    req.ImageURL,
 		Price:       req.Price,
+		CategoryID:  req.CategoryID,
+		ListingType: models.ListingFixed,
+		Status:      status,
 		UserID:      user.ID,
 	}
+	switch {
+	case auction != nil && status != models.AdStatusPublished:
+		utils.WriteJSONError(w, http.StatusBadRequest, "аукцион публикуется сразу, черновик не поддерживается")
+		return
+	case auction != nil:
+		// Срок показа аукциона — время торгов, снимает его AuctionCloser
+		ad.ListingType = models.ListingAuction
+	case status == models.AdStatusPublished:
+		ad.ExpiresAt = s.newExpiry()
+	}
 
-	if err := db.GetDB().Create(&ad).Error; err != nil {
+	decision, ok := s.screenAd(w, r, &screening.Content{UserID: user.ID, Title: ad.Title, Description: ad.Description})
+	if !ok {
+		return
+	}
+	flagged := decision.Verdict == screening.Flag
+
+	err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := s.Ads.Create(ctx, &ad); err != nil {
+			return err
+		}
+		if auction != nil {
+			auction.AdID = ad.ID
+			if err := s.Auctions.Create(ctx, auction); err != nil {
+				return err
+			}
+		}
+		if flagged {
+			if err := s.flagAd(ctx, &ad, decision); err != nil {
+				return err
+			}
+		}
+		return s.recordAdEvent(ctx, worker.WebhookAdCreated, &ad)
+	})
+	if err != nil {
 		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при создании объявления")
 		return
 	}
+	if flagged {
+		s.notify(r.Context(), models.NotificationAdHidden, AdModeratedPayload{AdID: ad.ID, Title: ad.Title, Note: screeningReasons(decision)}, ad.UserID)
+	}
 
 	resp := AdResponse{
 		ID:          ad.ID,
@@ -120,18 +289,24 @@ func CreateAdHandler(w http.ResponseWriter, r *http.Request) {
 		Description: ad.Description,
 		ImageURL:    ad.ImageURL,
 		Price:       ad.Price,
+		CategoryID:  ad.CategoryID,
+		ListingType: ad.ListingType,
+		Status:      ad.Status,
+		ExpiresAt:   ad.ExpiresAt,
+		Hidden:      ad.Hidden,
+		Images:      []ImageResponse{},
 		CreatedAt:   ad.CreatedAt,
-		User: UserResponse{
-			ID:       user.ID,
-			Username: user.Username,
-		},
+		User:        toUserResponse(user),
+	}
+	if auction != nil {
+		resp.Auction = toAuctionResponse(auction, user.ID, ad.UserID)
 	}
 
 	utils.WriteJSON(w, http.StatusCreated, resp)
 }
 
 // UpdateAdHandler обрабатывает обновление существующего объявления.
-func UpdateAdHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateAdHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/ads/")
 	adID, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
@@ -139,9 +314,8 @@ func UpdateAdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username, err := getUsernameFromContext(r)
-	if err != nil {
-		utils.WriteJSONError(w, http.StatusUnauthorized, err.Error())
+	user, ok := s.currentUser(w, r)
+	if !ok {
 		return
 	}
 
@@ -151,32 +325,84 @@ func UpdateAdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := validateCreateAdRequest(&req); err != nil {
-		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+	ad, err := s.Ads.GetByID(r.Context(), uint(adID))
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
 		return
 	}
 
-	dbConn := db.GetDB()
-	var ad models.Ad
-	if err := dbConn.Preload("User").First(&ad, adID).Error; err != nil {
-		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+	// Цену аукциона определяют ставки, поэтому в запросе её можно не передавать
+	if ad.ListingType == models.ListingAuction {
+		req.Price = ad.Price
+	}
+	if err := s.validateCreateAdRequest(r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ad.User.Username != username {
+	// Чужое объявление может изменить только модератор
+	moderated := ad.UserID != user.ID
+	if moderated && !middleware.HasPermission(r, models.PermModerateAds) {
 		utils.WriteJSONError(w, http.StatusForbidden, "нет прав для изменения объявления")
 		return
 	}
 
+	if !ad.Status.Editable() {
+		utils.WriteJSONError(w, http.StatusConflict, "проданное или архивное объявление нельзя изменить")
+		return
+	}
+
+	// Правки модератора фильтры не проверяют
+	decision := screening.Decision{Verdict: screening.Allow}
+	if !moderated {
+		content := &screening.Content{AdID: ad.ID, UserID: ad.UserID, Title: req.Title, Description: req.Description}
+		if decision, ok = s.screenAd(w, r, content); !ok {
+			return
+		}
+	}
+	flagged := decision.Verdict == screening.Flag
+
+	oldPrice := ad.Price
 	ad.Title = req.Title
 	ad.Description = req.Description
 	ad.ImageURL = req.ImageURL
+	ad.CategoryID = req.CategoryID
 	ad.Price = req.Price
 
-	if err := dbConn.Save(&ad).Error; err != nil {
+	err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := s.Ads.Update(ctx, ad); err != nil {
+			return err
+		}
+		if moderated {
+			if _, err := s.recordModeration(ctx, user, models.ModerationAdEdited, ad, ""); err != nil {
+				return err
+			}
+		}
+		if flagged {
+			if err := s.flagAd(ctx, ad, decision); err != nil {
+				return err
+			}
+		}
+		if ad.Price != oldPrice {
+			return s.recordAdPriceChange(ctx, ad, oldPrice)
+		}
+		return s.recordAdEvent(ctx, worker.WebhookAdUpdated, ad)
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		utils.WriteJSONError(w, http.StatusConflict, "проданное или архивное объявление нельзя изменить")
+		return
+	}
+	if err != nil {
 		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при обновлении объявления")
 		return
 	}
+	if flagged {
+		s.notify(r.Context(), models.NotificationAdHidden, AdModeratedPayload{AdID: ad.ID, Title: ad.Title, Note: screeningReasons(decision)}, ad.UserID)
+	}
+
+	if moderated {
+		s.notify(r.Context(), models.NotificationAdEdited, AdModeratedPayload{AdID: ad.ID, Title: ad.Title}, ad.UserID)
+	}
 
 	resp := AdResponse{
 		ID:          ad.ID,
@@ -184,18 +410,24 @@ func UpdateAdHandler(w http.ResponseWriter, r *http.Request) {
 		Description: ad.Description,
 		ImageURL:    ad.ImageURL,
 		Price:       ad.Price,
+		CategoryID:  ad.CategoryID,
+		ListingType: ad.ListingType,
+		Status:      ad.Status,
+		ExpiresAt:   ad.ExpiresAt,
+		Hidden:      ad.Hidden,
+		Images:      s.adImages(r, ad.ID),
 		CreatedAt:   ad.CreatedAt,
-		User: UserResponse{
-			ID:       ad.User.ID,
-			Username: ad.User.Username,
-		},
+		User:        toUserResponse(&ad.User),
+	}
+	if auction, err := s.Auctions.Get(r.Context(), ad.ID); err == nil {
+		resp.Auction = toAuctionResponse(auction, ad.UserID, ad.UserID)
 	}
 
 	utils.WriteJSON(w, http.StatusOK, resp)
 }
 
 // DeleteAdHandler обрабатывает удаление объявления.
-func DeleteAdHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DeleteAdHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/ads/")
 	adID, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
@@ -203,28 +435,116 @@ func DeleteAdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username, err := getUsernameFromContext(r)
-	if err != nil {
-		utils.WriteJSONError(w, http.StatusUnauthorized, err.Error())
+	user, ok := s.currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	dbConn := db.GetDB()
-	var ad models.Ad
-	if err := dbConn.Preload("User").First(&ad, adID).Error; err != nil {
+	ad, err := s.Ads.GetByID(r.Context(), uint(adID))
+	if err != nil {
 		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
 		return
 	}
 
-	if ad.User.Username != username {
+	// Чужое объявление может удалить только модератор
+	moderated := ad.UserID != user.ID
+	if moderated && !middleware.HasPermission(r, models.PermModerateAds) {
 		utils.WriteJSONError(w, http.StatusForbidden, "нет прав для удаления объявления")
 		return
 	}
 
-	if err := dbConn.Delete(&ad).Error; err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при удалении объявления")
+	var bidders []uint
+	err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		var err error
+		if bidders, err = s.deleteAd(ctx, ad, moderated); err != nil {
+			return err
+		}
+		if moderated {
+			if _, err := s.recordModeration(ctx, user, models.ModerationAdRemoved, ad, ""); err != nil {
+				return err
+			}
+		}
+		// Жалобы на удалённое объявление больше не требуют решения
+		_, err = s.Reports.Resolve(ctx, ad.ID, models.ReportResolutionAdDeleted, time.Now())
+		return err
+	})
+	if !writeAdDeletionError(w, err) {
 		return
 	}
 
+	if moderated {
+		s.notify(r.Context(), models.NotificationAdRemoved, AdModeratedPayload{AdID: ad.ID, Title: ad.Title}, ad.UserID)
+	}
+	s.notifyBidders(r.Context(), ad, bidders)
+
 	w.WriteHeader(http.StatusNoContent) // 204 No Content
 }
+
+// errAuctionHasBids — владелец пытается удалить аукцион, на который уже сделаны ставки.
+var errAuctionHasBids = errors.New("нельзя удалить аукцион, на который уже сделаны ставки")
+
+// deleteAd удаляет объявление и пишет событие ad.deleted. Вызывайте внутри
+// s.Tx.Transaction. Участников торгов нельзя оставить без лота: владелец
+// не может удалить аукцион со ставками (errAuctionHasBids), а при удалении
+// модератором (force) торги закрываются без победителя и возвращаются
+// их участники, которых нужно уведомить через notifyBidders. Если ставку
+// сделали параллельно, возвращается repository.ErrConflict.
+func (s *Server) deleteAd(ctx context.Context, ad *models.Ad, force bool) ([]uint, error) {
+	var bidders []uint
+	if ad.ListingType == models.ListingAuction {
+		auction, err := s.Auctions.Get(ctx, ad.ID)
+		if err != nil {
+			return nil, err
+		}
+		if auction.Status == models.AuctionOpen {
+			if auction.BidCount > 0 {
+				if !force {
+					return nil, errAuctionHasBids
+				}
+				if bidders, err = s.auctionBidders(ctx, ad.ID); err != nil {
+					return nil, err
+				}
+			}
+			now := time.Now()
+			auction.ClosedAt = &now
+			if err := s.Auctions.Close(ctx, auction); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.Ads.Delete(ctx, ad); err != nil {
+		return nil, err
+	}
+	return bidders, s.recordAdEvent(ctx, worker.WebhookAdDeleted, ad)
+}
+
+// writeAdDeletionError пишет ответ на ошибку deleteAd и сообщает, что ошибки не было.
+func writeAdDeletionError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errAuctionHasBids):
+		utils.WriteJSONError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, repository.ErrConflict):
+		utils.WriteJSONError(w, http.StatusConflict, "в аукционе появились новые ставки, повторите запрос")
+	default:
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при удалении объявления")
+	}
+	return false
+}
+
+// notifyBidders сообщает участникам торгов, что аукцион снят вместе с объявлением.
+func (s *Server) notifyBidders(ctx context.Context, ad *models.Ad, bidders []uint) {
+	if len(bidders) == 0 {
+		return
+	}
+	s.notify(ctx, models.NotificationAdRemoved, AdModeratedPayload{AdID: ad.ID, Title: ad.Title, Note: "торги отменены, ставки недействительны"}, bidders...)
+}
+
+// adAvailable сообщает, открыто ли объявление для покупателей: скрытые
+// модерацией объявления и объявления забаненных продавцов недоступны так же,
+// как в ленте.
+func adAvailable(ad *models.Ad) bool {
+	return !ad.Hidden && ad.User.StatusAt(time.Now()) != models.UserBanned
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// RoleRequest — новая роль пользователя.
+type RoleRequest struct {
+	Role models.Role `json:"role"`
+}
+
+// RoleResponse — роль пользователя и её права.
+type RoleResponse struct {
+	UserID      uint                `json:"user_id"`
+	Role        models.Role         `json:"role"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+// SetUserRoleHandler назначает пользователю роль. Права зашиты в токены,
+// поэтому сессии пользователя завершаются и новая роль действует со
+// следующего входа.
+func (s *Server) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := pathID(r, 2)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID пользователя")
+		return
+	}
+
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+	if !req.Role.Valid() {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неизвестная роль: "+string(req.Role))
+		return
+	}
+	if userID == admin.ID {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя изменить собственную роль")
+		return
+	}
+
+	user, err := s.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "пользователь не найден")
+		return
+	}
+
+	if user.Role != req.Role {
+		user.Role = req.Role
+		err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+			if err := s.Users.SetRole(ctx, user.ID, user.Role); err != nil {
+				return err
+			}
+			return s.Sessions.RevokeUserSessions(ctx, user.ID)
+		})
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при смене роли")
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, RoleResponse{UserID: user.ID, Role: user.Role, Permissions: user.Role.Permissions()})
+}
+
+// UserStatusRequest — новый статус учётной записи. Until — срок
+// ограничения (без него — бессрочно), Reason обязательна для suspended и banned.
+type UserStatusRequest struct {
+	Status models.UserStatus `json:"status"`
+	Until  *time.Time        `json:"until"`
+	Reason string            `json:"reason"`
+}
+
+// UserStatusResponse — действующий статус учётной записи.
+type UserStatusResponse struct {
+	UserID uint              `json:"user_id"`
+	Status models.UserStatus `json:"status"`
+	Until  *time.Time        `json:"until,omitempty"`
+	Reason string            `json:"reason,omitempty"`
+}
+
+// SetUserStatusHandler блокирует пользователя, банит его или снимает
+// ограничение. Сессии заблокированного пользователя завершаются сразу.
+func (s *Server) SetUserStatusHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := pathID(r, 2)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID пользователя")
+		return
+	}
+
+	var req UserStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if !req.Status.Valid() {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неизвестный статус: "+string(req.Status))
+		return
+	}
+	if req.Status == models.UserActive {
+		req.Until, req.Reason = nil, ""
+	} else {
+		if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 1000 {
+			utils.WriteJSONError(w, http.StatusBadRequest, "причина обязательна и должна быть не длиннее 1000 символов")
+			return
+		}
+		if req.Until != nil && !req.Until.After(time.Now()) {
+			utils.WriteJSONError(w, http.StatusBadRequest, "срок блокировки должен быть в будущем")
+			return
+		}
+	}
+	if userID == admin.ID {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя изменить собственный статус")
+		return
+	}
+
+	user, err := s.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "пользователь не найден")
+		return
+	}
+
+	user.Status, user.StatusUntil, user.StatusReason = req.Status, req.Until, req.Reason
+	err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := s.Users.SetStatus(ctx, user.ID, user.Status, user.StatusUntil, user.StatusReason); err != nil {
+			return err
+		}
+		if user.Status == models.UserActive {
+			return nil
+		}
+		return s.Sessions.RevokeUserSessions(ctx, user.ID)
+	})
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при смене статуса")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, UserStatusResponse{
+		UserID: user.ID,
+		Status: user.Status,
+		Until:  user.StatusUntil,
+		Reason: user.StatusReason,
+	})
+}
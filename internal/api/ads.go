@@ -5,25 +5,144 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/WalnutBagel/go-marketplace/internal/db"
+	"github.com/WalnutBagel/go-marketplace/internal/middleware"
 	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
 	"github.com/WalnutBagel/go-marketplace/internal/services"
 )
 
-func GetAdsHandler(w http.ResponseWriter, r *http.Request) {
-	// Попытка получить username из токена, если есть
-	username := ""
-	tokenStr := r.Header.Get("Authorization")
-	if tokenStr != "" {
-		// Обычно Authorization: "Bearer <token>", нужно убрать "Bearer "
-		tokenStr = strings.TrimPrefix(tokenStr, "Bearer ")
+// HighlightResp — фрагменты объявления, где совпадения с запросом q
+// обёрнуты в <mark>. Остальной текст экранирован для вставки в HTML.
+type HighlightResp struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// AdFeedItem — объявление в ленте GET /ads. IsOwner и IsFavorite
+// относятся к текущему пользователю.
+type AdFeedItem struct {
+	ID             uint               `json:"id"`
+	Title          string             `json:"title"`
+	Description    string             `json:"description"`
+	ImageURL       string             `json:"image_url"`
+	Price          float64            `json:"price"`
+	CategoryID     *uint              `json:"category_id"`
+	ListingType    models.ListingType `json:"listing_type"`
+	Auction        *AuctionResponse   `json:"auction,omitempty"`
+	Status         models.AdStatus    `json:"status"`
+	ExpiresAt      *time.Time         `json:"expires_at"`
+	Hidden         bool               `json:"hidden,omitempty"` // скрыто модерацией
+	Images         []ImageResponse    `json:"images"`
+	Highlight      *HighlightResp     `json:"highlight,omitempty"`
+	CreatedAt      string             `json:"created_at"`
+	IsOwner        bool               `json:"is_owner"`
+	IsFavorite     bool               `json:"is_favorite"`
+	FavoritesCount int64              `json:"favorites_count"`
+	User           UserResponse       `json:"user"`
+}
+
+// AdCursorPage — ответ ленты при пагинации курсором. NextCursor пуст
+// на последней странице.
+type AdCursorPage struct {
+	Items      []AdFeedItem `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// AdsV2MediaType — версия ответа ленты с конвертом AdPageResponse.
+// Запрашивается заголовком Accept или параметром envelope=true.
+const AdsV2MediaType = "application/vnd.marketplace.v2+json"
 
-		userFromToken, err := services.ParseJWT(tokenStr)
-		if err == nil {
-			username = userFromToken
+// AdPageResponse — конверт постраничной ленты (версия 2).
+type AdPageResponse struct {
+	Items   []AdFeedItem `json:"items"`
+	Page    int          `json:"page"`
+	Limit   int          `json:"limit"`
+	Total   int64        `json:"total"`
+	HasNext bool         `json:"has_next"`
+}
+
+// wantsAdsEnvelope сообщает, запросил ли клиент ответ версии 2.
+// Старые клиенты по-прежнему получают голый массив.
+func wantsAdsEnvelope(r *http.Request) bool {
+	if v, err := strconv.ParseBool(r.URL.Query().Get("envelope")); err == nil {
+		return v
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), AdsV2MediaType) {
+				return true
+			}
 		}
 	}
+	return false
+}
+
+// pageLinks собирает заголовок Link (RFC 8288) со ссылками на соседние
+// страницы. Остальные параметры запроса сохраняются.
+func pageLinks(r *http.Request, page, limit int, total int64) string {
+	link := func(p int, rel string) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("limit", strconv.Itoa(limit))
+		return "<" + r.URL.Path + "?" + q.Encode() + ">; rel=\"" + rel + "\""
+	}
+
+	lastPage := int((total + int64(limit) - 1) / int64(limit))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := []string{link(1, "first")}
+	if page > 1 {
+		links = append(links, link(min(page-1, lastPage), "prev"))
+	}
+	if page < lastPage {
+		links = append(links, link(page+1, "next"))
+	}
+	links = append(links, link(lastPage, "last"))
+	return strings.Join(links, ", ")
+}
+
+// cursorLink собирает заголовок Link на следующую страницу ленты курсором.
+func cursorLink(r *http.Request, cursor string) string {
+	q := r.URL.Query()
+	q.Set("cursor", cursor)
+	return "<" + r.URL.Path + "?" + q.Encode() + ">; rel=\"next\""
+}
+
+// feedCursor — содержимое подписанного курсора ленты. Сортировка
+// зашита в курсор, чтобы его нельзя было применить к другой выдаче.
+type feedCursor struct {
+	Sort      string    `json:"s"`
+	Order     string    `json:"o"`
+	CreatedAt time.Time `json:"c"`
+	Price     float64   `json:"p"`
+	Title     string    `json:"t"`
+	ID        uint      `json:"id"`
+}
+
+func newFeedCursor(ad *models.Ad, sortField, order string) (string, error) {
+	return services.SignCursor(feedCursor{
+		Sort:      sortField,
+		Order:     order,
+		CreatedAt: ad.CreatedAt,
+		Price:     ad.Price,
+		Title:     ad.Title,
+		ID:        ad.ID,
+	})
+}
+
+func (s *Server) GetAdsHandler(w http.ResponseWriter, r *http.Request) {
+	// Текущий пользователь нужен для признаков is_owner и is_favorite
+	username, _ := middleware.GetUsername(r)
+	viewer, err := s.getUserByUsername(r, username)
+	if err != nil {
+		http.Error(w, "Пользователь не найден", http.StatusUnauthorized)
+		return
+	}
 
 	// Парсим параметры пагинации и сортировки с дефолтами
 	page := 1
@@ -49,8 +168,18 @@ func GetAdsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Полнотекстовый поиск по заголовку и описанию
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(q)) > 200 {
+		http.Error(w, "Слишком длинный поисковый запрос", http.StatusBadRequest)
+		return
+	}
+	if q != "" {
+		sortField = "relevance"
+	}
+
 	if sf := r.URL.Query().Get("sort"); sf != "" {
-		allowedSorts := map[string]bool{"created_at": true, "price": true, "title": true}
+		allowedSorts := map[string]bool{"created_at": true, "price": true, "title": true, "relevance": q != ""}
 		if allowedSorts[sf] {
 			sortField = sf
 		} else {
@@ -63,14 +192,51 @@ func GetAdsHandler(w http.ResponseWriter, r *http.Request) {
 		order = o
 	}
 
-	offset := (page - 1) * limit
+	// Пагинация курсором включается параметром cursor; пустое значение —
+	// первая страница. Без него ответ остаётся прежним массивом.
+	useCursor := r.URL.Query().Has("cursor")
+	if useCursor && r.URL.Query().Get("page") != "" {
+		http.Error(w, "Параметры page и cursor несовместимы", http.StatusBadRequest)
+		return
+	}
+	if useCursor && sortField == "relevance" {
+		http.Error(w, "Курсор не поддерживается для сортировки relevance", http.StatusBadRequest)
+		return
+	}
+
+	filter := repository.AdFilter{
+		Statuses:    []models.AdStatus{models.AdStatusPublished},
+		ActiveAt:    time.Now(),
+		VisibleOnly: true,
+		Query:       q,
+		SortField:   sortField,
+		Order:       order,
+		Limit:       limit,
+		Offset:      (page - 1) * limit,
+	}
 
-	query := db.GetDB().Preload("User")
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		var fc feedCursor
+		if err := services.ParseCursor(c, &fc); err != nil || fc.Sort != sortField || fc.Order != order {
+			http.Error(w, "Невалидный параметр cursor", http.StatusBadRequest)
+			return
+		}
+		filter.After = &repository.AdCursor{
+			CreatedAt: fc.CreatedAt,
+			Price:     fc.Price,
+			Title:     fc.Title,
+			ID:        fc.ID,
+		}
+	}
+	if useCursor {
+		// Лишняя запись показывает, есть ли следующая страница
+		filter.Limit = limit + 1
+	}
 
 	// Фильтрация по цене
 	if minStr := r.URL.Query().Get("min_price"); minStr != "" {
 		if min, err := strconv.ParseFloat(minStr, 64); err == nil {
-			query = query.Where("price >= ?", min)
+			filter.MinPrice = &min
 		} else {
 			http.Error(w, "Невалидный параметр min_price", http.StatusBadRequest)
 			return
@@ -78,52 +244,157 @@ func GetAdsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if maxStr := r.URL.Query().Get("max_price"); maxStr != "" {
 		if max, err := strconv.ParseFloat(maxStr, 64); err == nil {
-			query = query.Where("price <= ?", max)
+			filter.MaxPrice = &max
 		} else {
 			http.Error(w, "Невалидный параметр max_price", http.StatusBadRequest)
 			return
 		}
 	}
 
-	var ads []models.Ad
-	err := query.
-		Order(sortField + " " + order).
-		Limit(limit).
-		Offset(offset).
-		Find(&ads).Error
+	// Чужие объявления видны только опубликованными и не скрытыми модерацией.
+	// С параметром status пользователь получает свои объявления в указанном
+	// состоянии, в том числе скрытые.
+	if st := r.URL.Query().Get("status"); st != "" {
+		status := models.AdStatus(st)
+		if !status.Valid() {
+			http.Error(w, "Невалидный параметр status", http.StatusBadRequest)
+			return
+		}
+		filter.Statuses = []models.AdStatus{status}
+		filter.UserID = &viewer.ID
+		filter.ActiveAt = time.Time{}
+		filter.VisibleOnly = false
+	}
 
+	// Фильтрация по разделу вместе со всеми подразделами
+	if catStr := r.URL.Query().Get("category_id"); catStr != "" {
+		catID, err := strconv.ParseUint(catStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Невалидный параметр category_id", http.StatusBadRequest)
+			return
+		}
+		ids, err := s.Categories.DescendantIDs(r.Context(), uint(catID))
+		if err != nil {
+			http.Error(w, "Раздел не найден", http.StatusBadRequest)
+			return
+		}
+		filter.CategoryIDs = ids
+	}
+
+	ads, err := s.Ads.List(r.Context(), filter)
 	if err != nil {
 		http.Error(w, "Ошибка при получении объявлений", http.StatusInternalServerError)
 		return
 	}
 
-	type AdResp struct {
-		ID          uint    `json:"id"`
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		ImageURL    string  `json:"image_url"`
-		Price       float64 `json:"price"`
-		CreatedAt   string  `json:"created_at"`
-		IsOwner     bool    `json:"is_owner"`
-		User        struct {
-			ID       uint   `json:"id"`
-			Username string `json:"username"`
-		} `json:"user"`
+	var nextCursor string
+	if useCursor && len(ads) > limit {
+		ads = ads[:limit]
+		nextCursor, err = newFeedCursor(&ads[limit-1], sortField, order)
+		if err != nil {
+			http.Error(w, "Ошибка при получении объявлений", http.StatusInternalServerError)
+			return
+		}
 	}
 
-	resp := make([]AdResp, len(ads))
-	for i, ad := range ads {
-		resp[i].ID = ad.ID
-		resp[i].Title = ad.Title
-		resp[i].Description = ad.Description
-		resp[i].ImageURL = ad.ImageURL
-		resp[i].Price = ad.Price
-		resp[i].CreatedAt = ad.CreatedAt.Format("2006-01-02T15:04:05Z07:00")
-		resp[i].User.ID = ad.User.ID
-		resp[i].User.Username = ad.User.Username
-		resp[i].IsOwner = (ad.User.Username == username)
+	resp, err := s.feedItems(r, ads, viewer, q)
+	if err != nil {
+		http.Error(w, "Ошибка при получении объявлений", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	w.Header().Add("Vary", "Accept")
+	switch {
+	case useCursor:
+		if nextCursor != "" {
+			w.Header().Set("Link", cursorLink(r, nextCursor))
+		}
+		json.NewEncoder(w).Encode(AdCursorPage{Items: resp, NextCursor: nextCursor})
+	case wantsAdsEnvelope(r):
+		total, err := s.Ads.Count(r.Context(), filter)
+		if err != nil {
+			http.Error(w, "Ошибка при получении объявлений", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", AdsV2MediaType)
+		w.Header().Set("Link", pageLinks(r, page, limit, total))
+		json.NewEncoder(w).Encode(AdPageResponse{
+			Items:   resp,
+			Page:    page,
+			Limit:   limit,
+			Total:   total,
+			HasNext: int64(page*limit) < total,
+		})
+	default:
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// feedItems собирает элементы ленты: картинки, подсветку совпадений с q
+// и признаки, зависящие от пользователя viewer.
+func (s *Server) feedItems(r *http.Request, ads []models.Ad, viewer *models.User, q string) ([]AdFeedItem, error) {
+	adIDs := make([]uint, len(ads))
+	for i, ad := range ads {
+		adIDs[i] = ad.ID
+	}
+	images, err := s.Images.ListByAds(r.Context(), adIDs)
+	if err != nil {
+		return nil, err
+	}
+	imagesByAd := make(map[uint][]models.AdImage)
+	for _, img := range images {
+		imagesByAd[img.AdID] = append(imagesByAd[img.AdID], img)
+	}
+
+	var highlights map[uint]repository.AdHighlight
+	if q != "" {
+		highlights, err = s.Ads.Highlight(r.Context(), q, adIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	favorited, err := s.Favorites.FavoritedAdIDs(r.Context(), viewer.ID, adIDs)
+	if err != nil {
+		return nil, err
+	}
+	favoritesCount, err := s.Favorites.CountByAds(r.Context(), adIDs)
+	if err != nil {
+		return nil, err
+	}
+	auctions, err := s.Auctions.ListByAds(r.Context(), adIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]AdFeedItem, len(ads))
+	for i, ad := range ads {
+		items[i].ID = ad.ID
+		items[i].Title = ad.Title
+		items[i].Description = ad.Description
+		items[i].ImageURL = ad.ImageURL
+		items[i].Price = ad.Price
+		items[i].CategoryID = ad.CategoryID
+		items[i].ListingType = ad.ListingType
+		items[i].Hidden = ad.Hidden
+		if a, ok := auctions[ad.ID]; ok {
+			items[i].Auction = toAuctionResponse(&a, viewer.ID, ad.UserID)
+		}
+		items[i].Status = ad.Status
+		items[i].ExpiresAt = ad.ExpiresAt
+		items[i].Images = toImageResponses(imagesByAd[ad.ID])
+		items[i].CreatedAt = ad.CreatedAt.Format("2006-01-02T15:04:05Z07:00")
+		items[i].User = toUserResponse(&ad.User)
+		items[i].IsOwner = (ad.UserID == viewer.ID)
+		items[i].IsFavorite = favorited[ad.ID]
+		items[i].FavoritesCount = favoritesCount[ad.ID]
+		if h, ok := highlights[ad.ID]; ok {
+			items[i].Highlight = &HighlightResp{
+				Title:       repository.FormatHighlight(h.Title),
+				Description: repository.FormatHighlight(h.Description),
+			}
+		}
+	}
+	return items, nil
 }
@@ -1,39 +1,186 @@
 package api_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/WalnutBagel/go-marketplace/internal/db"
+	"github.com/WalnutBagel/go-marketplace/internal/api"
+	"github.com/WalnutBagel/go-marketplace/internal/events"
+	"github.com/WalnutBagel/go-marketplace/internal/imaging"
+	"github.com/WalnutBagel/go-marketplace/internal/mail"
 	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/ratelimit"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
 	"github.com/WalnutBagel/go-marketplace/internal/router"
+	"github.com/WalnutBagel/go-marketplace/internal/screening"
+	"github.com/WalnutBagel/go-marketplace/internal/services"
+	"github.com/WalnutBagel/go-marketplace/internal/storage"
+	"github.com/WalnutBagel/go-marketplace/internal/worker"
 )
 
 func TestMain(m *testing.M) {
 	// Установка переменных окружения, например JWT_SECRET
 	os.Setenv("JWT_SECRET", "testsecret")
 
-	// Подключение к базе данных перед тестами
-	_, err := db.ConnectWithRetry()
+	os.Exit(m.Run())
+}
+
+// syncProcessor обрабатывает картинки сразу при постановке в очередь,
+// чтобы тестам не нужно было ждать фоновые обработчики.
+type syncProcessor struct {
+	*imaging.Processor
+}
+
+func (p syncProcessor) Enqueue(imageID uint) {
+	p.Process(context.Background(), imageID)
+}
+
+// memoryMailer запоминает письма, поставленные в очередь, вместо отправки.
+type memoryMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *memoryMailer) Enqueue(msg mail.Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+}
+
+// lastToken возвращает токен из ссылки в последнем письме получателю to.
+func (m *memoryMailer) lastToken(t *testing.T, to string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To != to {
+			continue
+		}
+		_, rest, ok := strings.Cut(m.sent[i].Body, "?token=")
+		if !ok {
+			t.Fatalf("В письме нет ссылки с токеном: %s", m.sent[i].Body)
+		}
+		token, _, _ := strings.Cut(rest, "\n")
+		unescaped, err := url.QueryUnescape(token)
+		if err != nil {
+			t.Fatalf("Ошибка разбора токена: %v", err)
+		}
+		return unescaped
+	}
+	t.Fatalf("Писем для %s не было", to)
+	return ""
+}
+
+// newTestServer собирает сервер поверх репозиториев в памяти,
+// поэтому каждый тест начинает с пустого хранилища и не требует Postgres.
+func newTestServer(t *testing.T) (*api.Server, http.Handler) {
+	t.Helper()
+
+	blobs, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Ошибка создания хранилища: %v", err)
+	}
+
+	users := repository.NewMemoryUserRepository()
+	ads := repository.NewMemoryAdRepository(users)
+	srv := &api.Server{
+		Users:         users,
+		Ads:           ads,
+		Sessions:      repository.NewMemorySessionRepository(),
+		Tokens:        repository.NewMemoryUserTokenRepository(),
+		Categories:    repository.NewMemoryCategoryRepository(),
+		Images:        repository.NewMemoryAdImageRepository(),
+		Favorites:     repository.NewMemoryFavoriteRepository(ads),
+		Threads:       repository.NewMemoryThreadRepository(users),
+		SavedSearches: repository.NewMemorySavedSearchRepository(),
+		Notifications: repository.NewMemoryNotificationRepository(),
+		Webhooks:      repository.NewMemoryWebhookRepository(users),
+		Outbox:        repository.NewMemoryOutboxRepository(),
+		Offers:        repository.NewMemoryOfferRepository(users),
+		Auctions:      repository.NewMemoryAuctionRepository(ads),
+		Reviews:       repository.NewMemoryReviewRepository(users),
+		Moderation:    repository.NewMemoryModerationRepository(users),
+		Reports:       repository.NewMemoryReportRepository(users),
+		Tx:            repository.MemoryTransactor{},
+		Storage:       blobs,
+	}
+	srv.Thumbnails = syncProcessor{imaging.NewProcessor(blobs, srv.Images, 0)}
+	return srv, router.NewRouter(srv)
+}
+
+// dispatchOutbox публикует накопившиеся события outbox для сверки
+// с сохранёнными поисками и рассылки изменений избранного, как это
+// делает фоновый диспетчер.
+func dispatchOutbox(t *testing.T, srv *api.Server) {
+	t.Helper()
+	publishers := worker.Publishers{
+		worker.NewSearchMatcher(srv.Ads, srv.SavedSearches, srv.Categories, srv.Notifications),
+		worker.NewFavoritesNotifier(srv.Favorites, srv.DeliverFavoriteUpdate),
+	}
+	if _, err := worker.NewOutboxDispatcher(srv.Outbox, publishers).Dispatch(context.Background()); err != nil {
+		t.Fatalf("Ошибка публикации событий outbox: %v", err)
+	}
+}
+
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	_, h := newTestServer(t)
+	return h
+}
+
+// grantRole назначает пользователю роль напрямую в хранилище и возвращает
+// новый токен: права фиксируются в токене при входе.
+func grantRole(t *testing.T, srv *api.Server, h http.Handler, username string, role models.Role) string {
+	t.Helper()
+
+	user, err := srv.Users.GetByUsername(context.Background(), username)
 	if err != nil {
-		panic("Ошибка подключения к БД в тестах: " + err.Error())
+		t.Fatalf("Пользователь %s не найден: %v", username, err)
 	}
+	if err := srv.Users.SetRole(context.Background(), user.ID, role); err != nil {
+		t.Fatalf("Ошибка обновления пользователя: %v", err)
+	}
+	return login(t, h, username)
+}
 
-	code := m.Run()
+// login выполняет вход существующего пользователя и возвращает его токен.
+func login(t *testing.T, h http.Handler, username string) string {
+	t.Helper()
 
-	// Очистка таблиц после всех тестов
-	db.GetDB().Exec("DELETE FROM users")
-	db.GetDB().Exec("DELETE FROM ads")
+	creds := map[string]string{"username": username, "password": "password123"}
+	w := doJSON(t, h, http.MethodPost, "/login", "", creds)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Логин %s: статус %d", username, w.Code)
+	}
 
-	os.Exit(code)
+	var resp api.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	return resp.Token
 }
 
 func TestRegisterHandler(t *testing.T) {
-	router := router.NewRouter()
+	router := newTestRouter(t)
 
 	// Подготовка тела запроса
 	payload := map[string]string{
@@ -50,8 +197,8 @@ func TestRegisterHandler(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusOK {
-		t.Fatalf("Ожидали статус 200 OK, получили: %d", w.Code)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидали статус 201 Created, получили: %d", w.Code)
 	}
 
 	var respUser models.User
@@ -63,3 +210,2167 @@ func TestRegisterHandler(t *testing.T) {
 		t.Errorf("Ожидали username 'testuser', получили: %s", respUser.Username)
 	}
 }
+
+// doJSON выполняет запрос к роутеру с JSON-телом и необязательным токеном.
+func doJSON(t *testing.T, h http.Handler, method, path, token string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			t.Fatalf("Ошибка сериализации тела запроса: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// registerAndLogin создаёт пользователя и возвращает его токен.
+func registerAndLogin(t *testing.T, h http.Handler, username string) string {
+	t.Helper()
+	return registerAndLoginTokens(t, h, username).Token
+}
+
+// registerAndLoginTokens создаёт пользователя и возвращает выданную пару токенов.
+func registerAndLoginTokens(t *testing.T, h http.Handler, username string) api.TokenResponse {
+	t.Helper()
+
+	creds := map[string]string{"username": username, "password": "password123"}
+	if w := doJSON(t, h, http.MethodPost, "/register", "", creds); w.Code != http.StatusCreated {
+		t.Fatalf("Регистрация %s: статус %d", username, w.Code)
+	}
+
+	w := doJSON(t, h, http.MethodPost, "/login", "", creds)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Логин %s: статус %d", username, w.Code)
+	}
+
+	var resp api.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	return resp
+}
+
+func TestAdsCRUD(t *testing.T) {
+	router := newTestRouter(t)
+	owner := registerAndLogin(t, router, "seller")
+	other := registerAndLogin(t, router, "buyer")
+
+	ad := map[string]any{
+		"title":       "Велосипед",
+		"description": "Почти новый горный велосипед",
+		"price":       15000,
+	}
+	w := doJSON(t, router, http.MethodPost, "/ads", owner, ad)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидали статус 201 Created, получили: %d", w.Code)
+	}
+
+	var created struct {
+		ID uint `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/ads", other, nil)
+	var list []struct {
+		ID      uint `json:"id"`
+		IsOwner bool `json:"is_owner"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != created.ID || list[0].IsOwner {
+		t.Fatalf("Неожиданная лента: %+v", list)
+	}
+
+	path := fmt.Sprintf("/ads/%d", created.ID)
+	if w := doJSON(t, router, http.MethodDelete, path, other, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Чужой пользователь: ожидали 403, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodDelete, path, owner, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Владелец: ожидали 204, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodDelete, path, owner, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Повторное удаление: ожидали 404, получили %d", w.Code)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	router := newTestRouter(t)
+	first := registerAndLoginTokens(t, router, "rotator")
+
+	w := doJSON(t, router, http.MethodPost, "/token/refresh", "", api.RefreshRequest{RefreshToken: first.RefreshToken})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Обновление: ожидали 200, получили %d", w.Code)
+	}
+	var second api.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &second); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh-токен не был заменён")
+	}
+
+	// Повторное использование старого токена отзывает всю сессию
+	w = doJSON(t, router, http.MethodPost, "/token/refresh", "", api.RefreshRequest{RefreshToken: first.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Повторное использование: ожидали 401, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodPost, "/token/refresh", "", api.RefreshRequest{RefreshToken: second.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Токен отозванной сессии: ожидали 401, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/ads", second.Token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Access-токен отозванной сессии: ожидали 401, получили %d", w.Code)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	router := newTestRouter(t)
+	tokens := registerAndLoginTokens(t, router, "leaver")
+
+	w := doJSON(t, router, http.MethodPost, "/logout", "", api.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Выход: ожидали 204, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/ads", tokens.Token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("После выхода: ожидали 401, получили %d", w.Code)
+	}
+}
+
+func TestCategoryFilterIncludesDescendants(t *testing.T) {
+	srv, router := newTestServer(t)
+	registerAndLogin(t, router, "admin")
+	admin := grantRole(t, srv, router, "admin", models.RoleAdmin)
+	seller := registerAndLogin(t, router, "seller")
+
+	createCategory := func(name string, parentID *uint) uint {
+		t.Helper()
+		w := doJSON(t, router, http.MethodPost, "/categories", admin, api.CategoryRequest{Name: name, ParentID: parentID})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Создание раздела %s: статус %d", name, w.Code)
+		}
+		var c struct {
+			ID uint `json:"id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &c)
+		return c.ID
+	}
+
+	transport := createCategory("Транспорт", nil)
+	bikes := createCategory("Велосипеды", &transport)
+	books := createCategory("Книги", nil)
+
+	if w := doJSON(t, router, http.MethodPost, "/categories", seller, api.CategoryRequest{Name: "Чужое"}); w.Code != http.StatusForbidden {
+		t.Errorf("Не-админ: ожидали 403, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPut, fmt.Sprintf("/categories/%d", transport), admin, api.CategoryRequest{Name: "Транспорт", ParentID: &bikes}); w.Code != http.StatusBadRequest {
+		t.Errorf("Цикл в дереве: ожидали 400, получили %d", w.Code)
+	}
+
+	for _, catID := range []uint{bikes, books} {
+		ad := api.CreateAdRequest{Title: "Объявление", Description: "Описание объявления", Price: 100, CategoryID: &catID}
+		if w := doJSON(t, router, http.MethodPost, "/ads", seller, ad); w.Code != http.StatusCreated {
+			t.Fatalf("Создание объявления: статус %d", w.Code)
+		}
+	}
+
+	w := doJSON(t, router, http.MethodGet, fmt.Sprintf("/ads?category_id=%d", transport), seller, nil)
+	var list []struct {
+		CategoryID *uint `json:"category_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	if len(list) != 1 || *list[0].CategoryID != bikes {
+		t.Fatalf("Ожидали одно объявление из подраздела, получили %+v", list)
+	}
+
+	if w := doJSON(t, router, http.MethodDelete, fmt.Sprintf("/categories/%d", transport), admin, nil); w.Code != http.StatusConflict {
+		t.Errorf("Удаление раздела с подразделами: ожидали 409, получили %d", w.Code)
+	}
+}
+
+// uploadImage отправляет картинку объявлению через multipart-форму.
+func uploadImage(t *testing.T, h http.Handler, token string, adID uint, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("image", "photo.bin")
+	part.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/ads/%d/images", adID), &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAdImages(t *testing.T) {
+	srv, router := newTestServer(t)
+	owner := registerAndLogin(t, router, "seller")
+	other := registerAndLogin(t, router, "buyer")
+
+	ad := api.CreateAdRequest{Title: "Фотоаппарат", Description: "Плёночный фотоаппарат", Price: 3000}
+	w := doJSON(t, router, http.MethodPost, "/ads", owner, ad)
+	var created api.AdResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	if w := uploadImage(t, router, owner, created.ID, []byte("definitely not an image")); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Не картинка: ожидали 415, получили %d", w.Code)
+	}
+	if w := uploadImage(t, router, other, created.ID, pngBytes(t)); w.Code != http.StatusForbidden {
+		t.Errorf("Чужое объявление: ожидали 403, получили %d", w.Code)
+	}
+
+	var images []api.ImageResponse
+	for range 2 {
+		w := uploadImage(t, router, owner, created.ID, pngBytes(t))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Загрузка: ожидали 201, получили %d: %s", w.Code, w.Body)
+		}
+		var img api.ImageResponse
+		json.Unmarshal(w.Body.Bytes(), &img)
+		images = append(images, img)
+	}
+
+	w = doJSON(t, router, http.MethodPut, fmt.Sprintf("/ads/%d/images", created.ID), owner,
+		api.ReorderImagesRequest{Order: []uint{images[1].ID, images[0].ID}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Смена порядка: ожидали 200, получили %d", w.Code)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/ads", other, nil)
+	var feed []api.AdResponse
+	json.Unmarshal(w.Body.Bytes(), &feed)
+	if len(feed) != 1 || len(feed[0].Images) != 2 || feed[0].Images[0].ID != images[1].ID {
+		t.Fatalf("Неожиданные картинки в ленте: %+v", feed)
+	}
+
+	first := feed[0].Images[0]
+	for url, contentType := range map[string]string{first.URL: "image/png", first.ThumbnailURL: "image/jpeg", first.MediumURL: "image/jpeg"} {
+		if url == "" {
+			t.Fatalf("Нет адреса уменьшенной копии: %+v", first)
+		}
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != contentType {
+			t.Fatalf("Отдача %s: статус %d, тип %q", url, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	// Параллельные загрузки не превышают лимит картинок
+	var wg sync.WaitGroup
+	for range api.MaxImagesPerAd {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uploadImage(t, router, owner, created.ID, pngBytes(t))
+		}()
+	}
+	wg.Wait()
+	if stored, _ := srv.Images.ListByAd(context.Background(), created.ID); len(stored) != api.MaxImagesPerAd {
+		t.Errorf("После параллельных загрузок ожидали %d картинок, получили %d", api.MaxImagesPerAd, len(stored))
+	}
+
+	// Картинки скрытого объявления не отдаются
+	srv.Ads.SetHidden(context.Background(), created.ID, models.HiddenByModerator)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, first.URL, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Картинка скрытого объявления: ожидали 404, получили %d", rec.Code)
+	}
+}
+
+func TestSearchAds(t *testing.T) {
+	router := newTestRouter(t)
+	token := registerAndLogin(t, router, "seller")
+
+	for _, ad := range []api.CreateAdRequest{
+		{Title: "Горный велосипед", Description: "Велосипед в хорошем состоянии, <b>торг</b>", Price: 20000},
+		{Title: "Шлем", Description: "Шлем для велосипед-прогулок", Price: 1500},
+		{Title: "Диван", Description: "Раскладной диван, самовывоз", Price: 7000},
+	} {
+		if w := doJSON(t, router, http.MethodPost, "/ads", token, ad); w.Code != http.StatusCreated {
+			t.Fatalf("Создание объявления: статус %d", w.Code)
+		}
+	}
+
+	w := doJSON(t, router, http.MethodGet, "/ads?q=велосипед", token, nil)
+	var results []struct {
+		Title     string            `json:"title"`
+		Highlight api.HighlightResp `json:"highlight"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Ожидали 2 результата, получили %d", len(results))
+	}
+	// Совпадение в заголовке весит больше, поэтому велосипед идёт первым
+	if results[0].Title != "Горный велосипед" {
+		t.Errorf("Неожиданный порядок по релевантности: %+v", results)
+	}
+	if results[0].Highlight.Title != "Горный <mark>велосипед</mark>" {
+		t.Errorf("Неожиданная подсветка заголовка: %q", results[0].Highlight.Title)
+	}
+	if !strings.Contains(results[0].Highlight.Description, "&lt;b&gt;") {
+		t.Errorf("HTML из описания должен экранироваться: %q", results[0].Highlight.Description)
+	}
+
+	if w := doJSON(t, router, http.MethodGet, "/ads?sort=relevance", token, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("sort=relevance без q: ожидали 400, получили %d", w.Code)
+	}
+}
+
+// createAd создаёт объявление и возвращает его ID.
+func createAd(t *testing.T, h http.Handler, token string, req api.CreateAdRequest) uint {
+	t.Helper()
+
+	w := doJSON(t, h, http.MethodPost, "/ads", token, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Создание объявления: статус %d: %s", w.Code, w.Body)
+	}
+	var created api.AdResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	return created.ID
+}
+
+// feedIDs возвращает ID объявлений из ответа GET по указанному пути.
+func feedIDs(t *testing.T, h http.Handler, token, path string) []uint {
+	t.Helper()
+
+	w := doJSON(t, h, http.MethodGet, path, token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: статус %d", path, w.Code)
+	}
+	var list []struct {
+		ID uint `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	ids := make([]uint, len(list))
+	for i, ad := range list {
+		ids[i] = ad.ID
+	}
+	return ids
+}
+
+func TestAdLifecycle(t *testing.T) {
+	router := newTestRouter(t)
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+
+	id := createAd(t, router, seller, api.CreateAdRequest{
+		Title: "Книжная полка", Description: "Полка из массива дуба", Price: 4000, Status: models.AdStatusDraft,
+	})
+
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 0 {
+		t.Fatalf("Черновик не должен быть виден покупателю: %v", ids)
+	}
+	if ids := feedIDs(t, router, seller, "/ads?status=draft"); len(ids) != 1 || ids[0] != id {
+		t.Fatalf("Владелец должен видеть свой черновик: %v", ids)
+	}
+
+	action := func(token, name string) int {
+		return doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/%s", id, name), token, nil).Code
+	}
+
+	if code := action(seller, "mark-sold"); code != http.StatusConflict {
+		t.Errorf("Черновик нельзя продать: ожидали 409, получили %d", code)
+	}
+	if code := action(buyer, "publish"); code != http.StatusForbidden {
+		t.Errorf("Чужое объявление: ожидали 403, получили %d", code)
+	}
+	if code := action(seller, "publish"); code != http.StatusOK {
+		t.Fatalf("Публикация: ожидали 200, получили %d", code)
+	}
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 1 {
+		t.Fatalf("Опубликованное объявление должно быть в ленте: %v", ids)
+	}
+	if code := action(seller, "mark-sold"); code != http.StatusOK {
+		t.Fatalf("Продажа: ожидали 200, получили %d", code)
+	}
+
+	update := api.CreateAdRequest{Title: "Книжная полка", Description: "Полка из массива дуба", Price: 1}
+	if w := doJSON(t, router, http.MethodPut, fmt.Sprintf("/ads/%d", id), seller, update); w.Code != http.StatusConflict {
+		t.Errorf("Проданное объявление нельзя изменить: ожидали 409, получили %d", w.Code)
+	}
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 0 {
+		t.Errorf("Проданное объявление не должно быть в ленте: %v", ids)
+	}
+}
+
+func TestAdExpiryAndRenew(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+
+	srv.AdLifetime = time.Millisecond
+	id := createAd(t, router, seller, api.CreateAdRequest{Title: "Самокат", Description: "Детский самокат", Price: 900})
+	time.Sleep(5 * time.Millisecond)
+
+	// Истёкшее объявление пропадает из ленты ещё до фоновой задачи
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 0 {
+		t.Fatalf("Истёкшее объявление не должно быть в ленте: %v", ids)
+	}
+
+	if n, err := srv.Ads.ExpirePublished(context.Background(), time.Now()); err != nil || n != 1 {
+		t.Fatalf("ExpirePublished: n=%d, err=%v", n, err)
+	}
+	if ids := feedIDs(t, router, seller, "/ads?status=expired"); len(ids) != 1 || ids[0] != id {
+		t.Fatalf("Владелец должен видеть истёкшее объявление: %v", ids)
+	}
+
+	srv.AdLifetime = time.Hour
+	w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/renew", id), buyer, nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Чужое объявление: ожидали 403, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/renew", id), seller, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Продление: ожидали 200, получили %d", w.Code)
+	}
+	var renewed api.AdStatusResponse
+	json.Unmarshal(w.Body.Bytes(), &renewed)
+	if renewed.Status != models.AdStatusPublished || renewed.ExpiresAt == nil || time.Until(*renewed.ExpiresAt) < 59*time.Minute {
+		t.Fatalf("Неожиданный результат продления: %+v", renewed)
+	}
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 1 {
+		t.Errorf("Продлённое объявление должно вернуться в ленту: %v", ids)
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	router := newTestRouter(t)
+	token := registerAndLogin(t, router, "seller")
+
+	// Одинаковые цены проверяют, что ID разрешает ничьи без пропусков и повторов
+	for _, price := range []float64{300, 100, 200, 100, 300} {
+		createAd(t, router, token, api.CreateAdRequest{Title: "Лот", Description: "Описание лота", Price: price})
+	}
+
+	for _, sortParams := range []string{"sort=price&order=asc", "sort=price&order=desc", "sort=created_at", "sort=title&order=asc"} {
+		want := feedIDs(t, router, token, "/ads?limit=100&"+sortParams)
+
+		var got []uint
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > len(want) {
+				t.Fatalf("%s: курсор зациклился", sortParams)
+			}
+			w := doJSON(t, router, http.MethodGet, "/ads?limit=2&cursor="+url.QueryEscape(cursor)+"&"+sortParams, token, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: статус %d", sortParams, w.Code)
+			}
+			var page api.AdCursorPage
+			if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+				t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+			}
+			for _, item := range page.Items {
+				got = append(got, item.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: курсором получили %v, ожидали %v", sortParams, got, want)
+		}
+	}
+
+	w := doJSON(t, router, http.MethodGet, "/ads?limit=2&cursor=&sort=price", token, nil)
+	var page api.AdCursorPage
+	json.Unmarshal(w.Body.Bytes(), &page)
+
+	// Курсор привязан к сортировке, а подделка ломает подпись
+	for _, path := range []string{
+		"/ads?sort=title&cursor=" + url.QueryEscape(page.NextCursor),
+		"/ads?sort=price&cursor=" + url.QueryEscape(page.NextCursor+"x"),
+		"/ads?sort=price&page=2&cursor=" + url.QueryEscape(page.NextCursor),
+	} {
+		if w := doJSON(t, router, http.MethodGet, path, token, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: ожидали 400, получили %d", path, w.Code)
+		}
+	}
+}
+
+func TestAdsPageEnvelope(t *testing.T) {
+	router := newTestRouter(t)
+	token := registerAndLogin(t, router, "seller")
+
+	for i := 0; i < 5; i++ {
+		createAd(t, router, token, api.CreateAdRequest{Title: "Лот", Description: "Описание лота", Price: 100})
+	}
+
+	// Без явного запроса версии ответ остаётся массивом
+	if ids := feedIDs(t, router, token, "/ads?limit=2"); len(ids) != 2 {
+		t.Fatalf("Ожидали массив из 2 объявлений, получили %v", ids)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ads?limit=2&page=2&sort=price", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", api.AdsV2MediaType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Статус %d", w.Code)
+	}
+
+	var page api.AdPageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	if len(page.Items) != 2 || page.Page != 2 || page.Limit != 2 || page.Total != 5 || !page.HasNext {
+		t.Errorf("Неожиданный конверт: %+v", page)
+	}
+
+	link := w.Header().Get("Link")
+	for _, want := range []string{
+		`</ads?limit=2&page=1&sort=price>; rel="first"`,
+		`</ads?limit=2&page=1&sort=price>; rel="prev"`,
+		`</ads?limit=2&page=3&sort=price>; rel="next"`,
+		`</ads?limit=2&page=3&sort=price>; rel="last"`,
+	} {
+		if !strings.Contains(link, want) {
+			t.Errorf("В Link нет %s: %s", want, link)
+		}
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/ads?limit=2&page=3&envelope=true", token, nil)
+	page = api.AdPageResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	if len(page.Items) != 1 || page.HasNext {
+		t.Errorf("Последняя страница: %+v", page)
+	}
+	if strings.Contains(w.Header().Get("Link"), `rel="next"`) {
+		t.Errorf("На последней странице не должно быть rel=next: %s", w.Header().Get("Link"))
+	}
+}
+
+func TestFavorites(t *testing.T) {
+	router := newTestRouter(t)
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+	other := registerAndLogin(t, router, "other")
+
+	bike := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	sofa := createAd(t, router, seller, api.CreateAdRequest{Title: "Диван", Description: "Раскладной диван", Price: 200})
+
+	for _, tc := range []struct {
+		token string
+		path  string
+		want  int
+	}{
+		{buyer, fmt.Sprintf("/ads/%d/favorite", bike), http.StatusNoContent},
+		{buyer, fmt.Sprintf("/ads/%d/favorite", bike), http.StatusNoContent}, // повторно — без ошибки
+		{buyer, fmt.Sprintf("/ads/%d/favorite", sofa), http.StatusNoContent},
+		{other, fmt.Sprintf("/ads/%d/favorite", bike), http.StatusNoContent},
+		{seller, fmt.Sprintf("/ads/%d/favorite", bike), http.StatusBadRequest},
+		{buyer, "/ads/999/favorite", http.StatusNotFound},
+	} {
+		if w := doJSON(t, router, http.MethodPost, tc.path, tc.token, nil); w.Code != tc.want {
+			t.Errorf("POST %s: ожидали %d, получили %d", tc.path, tc.want, w.Code)
+		}
+	}
+
+	w := doJSON(t, router, http.MethodGet, "/ads?sort=price&order=asc", buyer, nil)
+	var feed []api.AdFeedItem
+	if err := json.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+	}
+	if len(feed) != 2 || !feed[0].IsFavorite || feed[0].FavoritesCount != 2 || feed[1].FavoritesCount != 1 {
+		t.Errorf("Неожиданные признаки избранного в ленте: %+v", feed)
+	}
+
+	if ids := feedIDs(t, router, buyer, "/me/favorites"); !slices.Equal(ids, []uint{sofa, bike}) {
+		t.Errorf("Избранное: ожидали %v, получили %v", []uint{sofa, bike}, ids)
+	}
+
+	// Снятое с публикации объявление пропадает из избранного
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/unpublish", sofa), seller, nil)
+	if ids := feedIDs(t, router, buyer, "/me/favorites"); !slices.Equal(ids, []uint{bike}) {
+		t.Errorf("После снятия с публикации: %v", ids)
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := doJSON(t, router, http.MethodDelete, fmt.Sprintf("/ads/%d/favorite", bike), buyer, nil); w.Code != http.StatusNoContent {
+			t.Errorf("DELETE favorite: статус %d", w.Code)
+		}
+	}
+	if ids := feedIDs(t, router, buyer, "/me/favorites"); len(ids) != 0 {
+		t.Errorf("После удаления избранное должно быть пустым: %v", ids)
+	}
+}
+
+func TestMessaging(t *testing.T) {
+	router := newTestRouter(t)
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+	stranger := registerAndLogin(t, router, "stranger")
+
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	startPath := fmt.Sprintf("/ads/%d/threads", adID)
+
+	if w := doJSON(t, router, http.MethodPost, startPath, seller, api.MessageRequest{Body: "Привет"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Переписка с самим собой: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, startPath, buyer, api.MessageRequest{Body: "  "}); w.Code != http.StatusBadRequest {
+		t.Errorf("Пустое сообщение: ожидали 400, получили %d", w.Code)
+	}
+
+	w := doJSON(t, router, http.MethodPost, startPath, buyer, api.MessageRequest{Body: "Ещё продаёте?"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Начало переписки: статус %d: %s", w.Code, w.Body.String())
+	}
+	var thread api.ThreadResponse
+	json.Unmarshal(w.Body.Bytes(), &thread)
+	if thread.Buyer.Username != "buyer" || thread.Seller.Username != "seller" {
+		t.Errorf("Неожиданные участники: %+v", thread)
+	}
+
+	// Повторное обращение продолжает ту же переписку
+	w = doJSON(t, router, http.MethodPost, startPath, buyer, api.MessageRequest{Body: "Торг уместен?"})
+	var again api.ThreadResponse
+	json.Unmarshal(w.Body.Bytes(), &again)
+	if w.Code != http.StatusOK || again.ID != thread.ID {
+		t.Errorf("Повторное обращение: статус %d, переписка %d вместо %d", w.Code, again.ID, thread.ID)
+	}
+
+	unread := func(token string) int64 {
+		t.Helper()
+		w := doJSON(t, router, http.MethodGet, "/threads", token, nil)
+		var threads []api.ThreadResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &threads); err != nil || len(threads) != 1 {
+			t.Fatalf("Список переписок: %s", w.Body.String())
+		}
+		return threads[0].UnreadCount
+	}
+	if n := unread(seller); n != 2 {
+		t.Errorf("У продавца ожидали 2 непрочитанных, получили %d", n)
+	}
+	if n := unread(buyer); n != 0 {
+		t.Errorf("Свои сообщения не считаются непрочитанными, получили %d", n)
+	}
+
+	messagesPath := fmt.Sprintf("/threads/%d/messages", thread.ID)
+	w = doJSON(t, router, http.MethodPost, messagesPath, seller, api.MessageRequest{Body: "Да, продаю"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ответ продавца: статус %d", w.Code)
+	}
+	var reply models.Message
+	json.Unmarshal(w.Body.Bytes(), &reply)
+
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/threads/%d/read", thread.ID), seller, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Отметка прочитанным: статус %d", w.Code)
+	}
+	if n := unread(seller); n != 0 {
+		t.Errorf("После прочтения ожидали 0 непрочитанных, получили %d", n)
+	}
+	if n := unread(buyer); n != 1 {
+		t.Errorf("У покупателя ожидали 1 непрочитанное, получили %d", n)
+	}
+
+	w = doJSON(t, router, http.MethodGet, messagesPath, buyer, nil)
+	var messages []models.Message
+	json.Unmarshal(w.Body.Bytes(), &messages)
+	if len(messages) != 3 || messages[2].ID != reply.ID {
+		t.Errorf("Неожиданные сообщения: %+v", messages)
+	}
+	w = doJSON(t, router, http.MethodGet, fmt.Sprintf("%s?after=%d", messagesPath, messages[1].ID), buyer, nil)
+	messages = nil
+	json.Unmarshal(w.Body.Bytes(), &messages)
+	if len(messages) != 1 || messages[0].ID != reply.ID {
+		t.Errorf("Сообщения после after: %+v", messages)
+	}
+
+	// Посторонний не видит переписку и не может в неё писать
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if w := doJSON(t, router, method, messagesPath, stranger, api.MessageRequest{Body: "Привет"}); w.Code != http.StatusForbidden {
+			t.Errorf("%s чужой переписки: ожидали 403, получили %d", method, w.Code)
+		}
+	}
+	if w := doJSON(t, router, http.MethodGet, "/threads", stranger, nil); w.Body.String() != "[]\n" {
+		t.Errorf("У постороннего не должно быть переписок: %s", w.Body.String())
+	}
+}
+
+// readEvent читает поток SSE до ближайшего события и возвращает его тип и данные.
+func readEvent(t *testing.T, stream *bufio.Reader) (string, string) {
+	t.Helper()
+
+	var eventType, data string
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Чтение потока событий: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && eventType != "":
+			return eventType, data
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	srv, router := newTestServer(t)
+	srv.Events = events.NewHub(events.NewLocalBackend())
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/favorite", adID), buyer, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+buyer)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Подключение к потоку событий: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Неожиданный Content-Type: %q", ct)
+	}
+	stream := bufio.NewReader(resp.Body)
+
+	update := api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 80}
+	doJSON(t, router, http.MethodPut, fmt.Sprintf("/ads/%d", adID), seller, update)
+	dispatchOutbox(t, srv)
+
+	eventType, data := readEvent(t, stream)
+	var adEvent api.AdEvent
+	json.Unmarshal([]byte(data), &adEvent)
+	if eventType != "ad.price_changed" || adEvent.Price != 80 || adEvent.OldPrice == nil || *adEvent.OldPrice != 100 {
+		t.Errorf("Неожиданное событие %s: %s", eventType, data)
+	}
+
+	// Вслед за событием приходит записанное уведомление
+	eventType, data = readEvent(t, stream)
+	var notification api.NotificationResponse
+	json.Unmarshal([]byte(data), &notification)
+	if eventType != "notification.new" || notification.Type != models.NotificationFavoriteUpdated {
+		t.Errorf("Ожидали уведомление favorite_updated, получили %s: %s", eventType, data)
+	}
+
+	w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/threads", adID), buyer, api.MessageRequest{Body: "Ещё продаёте?"})
+	var thread api.ThreadResponse
+	json.Unmarshal(w.Body.Bytes(), &thread)
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/threads/%d/messages", thread.ID), seller, api.MessageRequest{Body: "Да"})
+
+	for _, want := range []string{"Ещё продаёте?", "Да"} {
+		eventType, data = readEvent(t, stream)
+		var msgEvent api.MessageEvent
+		json.Unmarshal([]byte(data), &msgEvent)
+		if eventType != "message.new" || msgEvent.ThreadID != thread.ID || msgEvent.Message.Body != want {
+			t.Errorf("Ожидали сообщение %q, получили %s: %s", want, eventType, data)
+		}
+	}
+
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/mark-sold", adID), seller, nil)
+	dispatchOutbox(t, srv)
+	if eventType, data = readEvent(t, stream); eventType != "ad.sold" {
+		t.Errorf("Ожидали ad.sold, получили %s: %s", eventType, data)
+	}
+}
+
+func TestSavedSearches(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+
+	if w := doJSON(t, router, http.MethodPost, "/me/searches", buyer, api.SavedSearchRequest{Name: "Всё подряд"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Поиск без условий: ожидали 400, получили %d", w.Code)
+	}
+
+	maxPrice := 500.0
+	w := doJSON(t, router, http.MethodPost, "/me/searches", buyer, api.SavedSearchRequest{Query: "велосипед", MaxPrice: &maxPrice})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Сохранение поиска: статус %d: %s", w.Code, w.Body.String())
+	}
+	var search models.SavedSearch
+	json.Unmarshal(w.Body.Bytes(), &search)
+	if search.Name != "велосипед" {
+		t.Errorf("Название по умолчанию должно совпадать с запросом: %q", search.Name)
+	}
+	// Второй подходящий поиск не должен дублировать уведомления
+	doJSON(t, router, http.MethodPost, "/me/searches", buyer, api.SavedSearchRequest{Query: "горный"})
+
+	matching := createAd(t, router, seller, api.CreateAdRequest{Title: "Горный велосипед", Description: "Почти новый", Price: 300})
+	createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Дорогой шоссейный", Price: 1000})
+	createAd(t, router, seller, api.CreateAdRequest{Title: "Диван", Description: "Раскладной диван", Price: 100})
+	createAd(t, router, buyer, api.CreateAdRequest{Title: "Велосипед", Description: "Свой велосипед", Price: 100})
+	draft := createAd(t, router, seller, api.CreateAdRequest{Title: "Детский велосипед", Description: "Черновик", Price: 200, Status: models.AdStatusDraft})
+
+	notifiedAds := func() []uint {
+		t.Helper()
+		dispatchOutbox(t, srv)
+		w := doJSON(t, router, http.MethodGet, "/me/notifications", buyer, nil)
+		var list []api.NotificationResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+			t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+		}
+		var ids []uint
+		for _, n := range list {
+			var payload worker.SavedSearchMatchPayload
+			json.Unmarshal(n.Payload, &payload)
+			if n.Type != models.NotificationSavedSearchMatch || n.ReadAt != nil {
+				t.Errorf("Неожиданное уведомление: %+v", n)
+			}
+			ids = append(ids, payload.AdID)
+		}
+		return ids
+	}
+
+	if ids := notifiedAds(); !slices.Equal(ids, []uint{matching}) {
+		t.Errorf("Уведомления после создания: ожидали %v, получили %v", []uint{matching}, ids)
+	}
+
+	// Публикация черновика тоже проверяется, повторная — не дублирует уведомление
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/publish", draft), seller, nil)
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/unpublish", draft), seller, nil)
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/publish", draft), seller, nil)
+	if ids := notifiedAds(); !slices.Equal(ids, []uint{draft, matching}) {
+		t.Errorf("Уведомления после публикации: ожидали %v, получили %v", []uint{draft, matching}, ids)
+	}
+
+	path := fmt.Sprintf("/me/searches/%d", search.ID)
+	if w := doJSON(t, router, http.MethodDelete, path, seller, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Удаление чужого поиска: ожидали 404, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodDelete, path, buyer, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Удаление своего поиска: статус %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodGet, "/me/searches", buyer, nil)
+	var searches []models.SavedSearch
+	json.Unmarshal(w.Body.Bytes(), &searches)
+	if len(searches) != 1 || searches[0].Query != "горный" {
+		t.Errorf("Оставшиеся поиски: %+v", searches)
+	}
+}
+
+func TestNotificationCenter(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+	registerAndLogin(t, router, "admin")
+	admin := grantRole(t, srv, router, "admin", models.RoleAdmin)
+
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/favorite", adID), buyer, nil)
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/favorite", adID), buyer, nil) // повтор не уведомляет
+	doJSON(t, router, http.MethodPut, fmt.Sprintf("/ads/%d", adID), seller,
+		api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 90})
+	dispatchOutbox(t, srv)
+
+	list := func(token, query string) []api.NotificationResponse {
+		t.Helper()
+		w := doJSON(t, router, http.MethodGet, "/me/notifications"+query, token, nil)
+		var resp []api.NotificationResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Ошибка разбора JSON ответа: %v", err)
+		}
+		return resp
+	}
+	unread := func(token string) int64 {
+		t.Helper()
+		w := doJSON(t, router, http.MethodGet, "/me/notifications/unread-count", token, nil)
+		var resp api.UnreadCountResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Unread
+	}
+
+	sellerNotes := list(seller, "")
+	if len(sellerNotes) != 1 || sellerNotes[0].Type != models.NotificationAdFavorited {
+		t.Fatalf("Продавец должен получить одно уведомление ad_favorited: %+v", sellerNotes)
+	}
+	var favorited api.AdFavoritedPayload
+	json.Unmarshal(sellerNotes[0].Payload, &favorited)
+	if favorited.AdID != adID || favorited.FavoritesCount != 1 {
+		t.Errorf("Неожиданное содержимое уведомления: %s", sellerNotes[0].Payload)
+	}
+
+	buyerNotes := list(buyer, "")
+	if len(buyerNotes) != 1 || buyerNotes[0].Type != models.NotificationFavoriteUpdated {
+		t.Fatalf("Покупатель должен получить уведомление favorite_updated: %+v", buyerNotes)
+	}
+
+	// Чужое уведомление отметить нельзя
+	readPath := fmt.Sprintf("/me/notifications/%d/read", buyerNotes[0].ID)
+	if w := doJSON(t, router, http.MethodPost, readPath, seller, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Отметка чужого уведомления: ожидали 404, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, readPath, buyer, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Отметка уведомления: статус %d", w.Code)
+	}
+	if n := unread(buyer); n != 0 {
+		t.Errorf("После прочтения ожидали 0 непрочитанных, получили %d", n)
+	}
+	if notes := list(buyer, "?unread=true"); len(notes) != 0 {
+		t.Errorf("Фильтр unread вернул прочитанные: %+v", notes)
+	}
+
+	// Удалять чужие объявления может только администратор, владелец получает уведомление
+	if w := doJSON(t, router, http.MethodDelete, fmt.Sprintf("/ads/%d", adID), buyer, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Удаление чужого объявления: ожидали 403, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodDelete, fmt.Sprintf("/ads/%d", adID), admin, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Удаление администратором: статус %d", w.Code)
+	}
+	if n := unread(seller); n != 2 {
+		t.Errorf("У продавца ожидали 2 непрочитанных, получили %d", n)
+	}
+	if notes := list(seller, "?unread=true"); len(notes) != 2 || notes[0].Type != models.NotificationAdRemoved {
+		t.Errorf("Последним должно быть уведомление ad_removed: %+v", notes)
+	}
+
+	if w := doJSON(t, router, http.MethodPost, "/me/notifications/read-all", seller, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Отметка всех уведомлений: статус %d", w.Code)
+	}
+	if n := unread(seller); n != 0 {
+		t.Errorf("После read-all ожидали 0 непрочитанных, получили %d", n)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLogin(t, router, "seller")
+	other := registerAndLogin(t, router, "other")
+	registerAndLogin(t, router, "admin")
+	admin := grantRole(t, srv, router, "admin", models.RoleAdmin)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var got []received
+	failures := 1
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		got = append(got, received{r.Header.Clone(), body})
+	}))
+	defer target.Close()
+
+	register := func(token string, req api.WebhookRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		return doJSON(t, router, http.MethodPost, "/me/webhooks", token, req)
+	}
+
+	if w := register(seller, api.WebhookRequest{URL: "ftp://example.com"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Невалидный адрес: ожидали 400, получили %d", w.Code)
+	}
+	for _, internal := range []string{target.URL, "http://localhost/hook", "http://10.0.0.5/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]:8080/", "http://[::ffff:127.0.0.1]/"} {
+		if w := register(seller, api.WebhookRequest{URL: internal}); w.Code != http.StatusBadRequest {
+			t.Errorf("Внутренний адрес %s: ожидали 400, получили %d", internal, w.Code)
+		}
+	}
+
+	// Дальше вебхуки смотрят на локальный тестовый сервер
+	srv.AllowPrivateWebhooks = true
+	if w := register(seller, api.WebhookRequest{URL: target.URL, Events: []string{"ad.exploded"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Неизвестное событие: ожидали 400, получили %d", w.Code)
+	}
+	if w := register(seller, api.WebhookRequest{URL: target.URL, AllAds: true}); w.Code != http.StatusForbidden {
+		t.Errorf("all_ads без прав администратора: ожидали 403, получили %d", w.Code)
+	}
+
+	w := register(seller, api.WebhookRequest{URL: target.URL, Events: []string{worker.WebhookAdCreated}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Регистрация вебхука: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	var hook api.WebhookResponse
+	json.Unmarshal(w.Body.Bytes(), &hook)
+	if hook.Secret == "" {
+		t.Fatal("Секрет должен возвращаться при создании")
+	}
+	if w := register(admin, api.WebhookRequest{URL: target.URL, AllAds: true}); w.Code != http.StatusCreated {
+		t.Fatalf("Регистрация вебхука администратора: статус %d", w.Code)
+	}
+
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	createAd(t, router, other, api.CreateAdRequest{Title: "Самокат", Description: "Городской самокат", Price: 50})
+
+	// Вебхук продавца получает только свои ad.created, администраторский — всё
+	dispatcher := worker.NewWebhookDispatcher(srv.Webhooks, target.Client())
+	dispatcher.BaseBackoff = 0
+	if _, err := worker.NewOutboxDispatcher(srv.Outbox, dispatcher).Dispatch(context.Background()); err != nil {
+		t.Fatalf("Ошибка публикации outbox: %v", err)
+	}
+	for range 2 {
+		if _, err := dispatcher.DeliverDue(context.Background()); err != nil {
+			t.Fatalf("Ошибка доставки: %v", err)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("Ожидали 3 доставки, получили %d", len(got))
+	}
+
+	var sellerDelivery *received
+	for i := range got {
+		var payload worker.WebhookPayload
+		json.Unmarshal(got[i].body, &payload)
+		if payload.Event != worker.WebhookAdCreated {
+			t.Errorf("Неожиданное событие %s", payload.Event)
+		}
+		ts, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(got[i].header.Get(worker.WebhookSignatureHeader), ",")[0], "t="), 10, 64)
+		if err != nil {
+			t.Fatalf("Неверный формат подписи: %v", err)
+		}
+		if got[i].header.Get(worker.WebhookSignatureHeader) == worker.SignWebhook(hook.Secret, ts, got[i].body) {
+			sellerDelivery = &got[i]
+		}
+	}
+	if sellerDelivery == nil {
+		t.Fatal("Ни одна доставка не подписана секретом вебхука продавца")
+	}
+	var payload worker.WebhookPayload
+	json.Unmarshal(sellerDelivery.body, &payload)
+	var data worker.AdEventData
+	json.Unmarshal(payload.Data, &data)
+	if data.ID != adID || data.Title != "Велосипед" {
+		t.Errorf("Неожиданные данные события: %s", payload.Data)
+	}
+
+	// Журнал доставок: первая попытка упала, повтор прошёл
+	deliveriesPath := fmt.Sprintf("/me/webhooks/%d/deliveries", hook.ID)
+	if w := doJSON(t, router, http.MethodGet, deliveriesPath, other, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Журнал чужого вебхука: ожидали 404, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodGet, deliveriesPath, seller, nil)
+	var deliveries []models.WebhookDelivery
+	json.Unmarshal(w.Body.Bytes(), &deliveries)
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliverySucceeded {
+		t.Fatalf("Ожидали одну успешную доставку: %+v", deliveries)
+	}
+	if deliveries[0].Attempts != 2 || deliveries[0].ResponseStatus != http.StatusOK {
+		t.Errorf("Неожиданное число попыток: %d", deliveries[0].Attempts)
+	}
+
+	if w := doJSON(t, router, http.MethodDelete, fmt.Sprintf("/me/webhooks/%d", hook.ID), seller, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Удаление вебхука: статус %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, deliveriesPath, seller, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Журнал удалённого вебхука: ожидали 404, получили %d", w.Code)
+	}
+
+	// После снятия роли вебхук all_ads больше не получает чужие объявления
+	adminUser, _ := srv.Users.GetByUsername(context.Background(), "admin")
+	srv.Users.SetRole(context.Background(), adminUser.ID, models.RoleUser)
+	createAd(t, router, other, api.CreateAdRequest{Title: "Гитара", Description: "Акустическая гитара", Price: 70})
+	worker.NewOutboxDispatcher(srv.Outbox, dispatcher).Dispatch(context.Background())
+	dispatcher.DeliverDue(context.Background())
+	if len(got) != 3 {
+		t.Errorf("Вебхук бывшего администратора получил событие: %d доставок", len(got))
+	}
+}
+
+func TestWebhookRetriesUntilFailed(t *testing.T) {
+	calls := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer target.Close()
+
+	webhooks := repository.NewMemoryWebhookRepository(repository.NewMemoryUserRepository())
+	dispatcher := worker.NewWebhookDispatcher(webhooks, target.Client())
+	dispatcher.MaxAttempts = 3
+	dispatcher.BaseBackoff = 0
+
+	ctx := context.Background()
+	hook := models.Webhook{UserID: 1, URL: target.URL, Events: worker.WebhookAdDeleted, Secret: "s"}
+	webhooks.Create(ctx, &hook)
+	event := &models.OutboxEvent{ID: 1, Type: worker.WebhookAdDeleted, AggregateID: 7, UserID: 1, Payload: `{"id":7}`}
+	if err := dispatcher.Publish(ctx, event); err != nil {
+		t.Fatalf("Ошибка постановки события: %v", err)
+	}
+	// Вебхук не подписан на ad.created — доставка не создаётся
+	dispatcher.Publish(ctx, &models.OutboxEvent{ID: 2, Type: worker.WebhookAdCreated, UserID: 1, Payload: `{"id":7}`})
+
+	for range 5 {
+		dispatcher.DeliverDue(ctx)
+	}
+
+	deliveries, _ := webhooks.ListDeliveries(ctx, hook.ID, 10, 0)
+	if len(deliveries) != 1 {
+		t.Fatalf("Ожидали одну доставку, получили %d", len(deliveries))
+	}
+	d := deliveries[0]
+	if calls != 3 || d.Attempts != 3 || d.Status != models.DeliveryFailed || d.ResponseStatus != http.StatusServiceUnavailable {
+		t.Errorf("После исчерпания попыток: вызовов %d, доставка %+v", calls, d)
+	}
+}
+
+// Доставка клиентом по умолчанию не доходит до внутренних адресов, даже если
+// адрес попал в базу в обход проверки при регистрации, и не следует
+// перенаправлениям.
+func TestWebhookDeliveryBlocksInternalAddresses(t *testing.T) {
+	calls := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer target.Close()
+
+	webhooks := repository.NewMemoryWebhookRepository(repository.NewMemoryUserRepository())
+	dispatcher := worker.NewWebhookDispatcher(webhooks, nil)
+	ctx := context.Background()
+	hook := models.Webhook{UserID: 1, URL: target.URL, Events: worker.WebhookAdDeleted, Secret: "s"}
+	webhooks.Create(ctx, &hook)
+	dispatcher.Publish(ctx, &models.OutboxEvent{ID: 1, Type: worker.WebhookAdDeleted, UserID: 1, Payload: `{"id":7}`})
+	dispatcher.DeliverDue(ctx)
+
+	deliveries, _ := webhooks.ListDeliveries(ctx, hook.ID, 10, 0)
+	if calls != 0 || len(deliveries) != 1 || !strings.Contains(deliveries[0].LastError, worker.ErrWebhookAddress.Error()) {
+		t.Errorf("Доставка на loopback: вызовов %d, доставки %+v", calls, deliveries)
+	}
+
+	client := worker.NewWebhookClient(time.Second)
+	if err := client.CheckRedirect(nil, nil); err != http.ErrUseLastResponse {
+		t.Errorf("Перенаправления не должны выполняться: %v", err)
+	}
+}
+
+// flakyPublisher запоминает опубликованные события и падает на первых fail вызовах.
+type flakyPublisher struct {
+	fail      int
+	published []models.OutboxEvent
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, event *models.OutboxEvent) error {
+	if p.fail > 0 {
+		p.fail--
+		return fmt.Errorf("брокер недоступен")
+	}
+	p.published = append(p.published, *event)
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLogin(t, router, "seller")
+
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	doJSON(t, router, http.MethodPut, fmt.Sprintf("/ads/%d", adID), seller,
+		api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 90})
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/reserve", adID), seller, nil)
+	doJSON(t, router, http.MethodDelete, fmt.Sprintf("/ads/%d", adID), seller, nil)
+
+	publisher := &flakyPublisher{fail: 1}
+	dispatcher := worker.NewOutboxDispatcher(srv.Outbox, publisher)
+	dispatcher.BaseBackoff = 0
+
+	ctx := context.Background()
+	if n, err := dispatcher.Dispatch(ctx); err != nil || n != 4 {
+		t.Fatalf("Первый проход: обработано %d, ошибка %v", n, err)
+	}
+	// Упавшее событие повторяется, опубликованные — нет
+	if n, _ := dispatcher.Dispatch(ctx); n != 1 {
+		t.Fatalf("Повтор: ожидали 1 событие, получили %d", n)
+	}
+	if n, _ := dispatcher.Dispatch(ctx); n != 0 {
+		t.Fatalf("Все события опубликованы, а осталось %d", n)
+	}
+
+	var types []string
+	for _, e := range publisher.published {
+		types = append(types, e.Type)
+		if e.AggregateID != adID {
+			t.Errorf("Событие %s относится к объявлению %d", e.Type, e.AggregateID)
+		}
+	}
+	want := []string{worker.WebhookAdUpdated, worker.WebhookAdUpdated, worker.WebhookAdDeleted, worker.WebhookAdCreated}
+	if !slices.Equal(types, want) {
+		t.Errorf("Опубликованы %v, ожидали %v", types, want)
+	}
+
+	var reserved worker.AdEventData
+	json.Unmarshal([]byte(publisher.published[1].Payload), &reserved)
+	if reserved.Status != models.AdStatusReserved || reserved.Price != 90 {
+		t.Errorf("Неожиданные данные события смены состояния: %s", publisher.published[1].Payload)
+	}
+}
+
+func TestOffers(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+	rival := registerAndLogin(t, router, "rival")
+
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	offersPath := fmt.Sprintf("/ads/%d/offers", adID)
+
+	makeOffer := func(token string, amount float64) (*httptest.ResponseRecorder, api.OfferResponse) {
+		t.Helper()
+		w := doJSON(t, router, http.MethodPost, offersPath, token, api.OfferRequest{Amount: amount})
+		var resp api.OfferResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	act := func(token string, offerID uint, action string, payload any) (*httptest.ResponseRecorder, api.OfferResponse) {
+		t.Helper()
+		w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/offers/%d/%s", offerID, action), token, payload)
+		var resp api.OfferResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	listOffers := func(token, path string) []api.OfferResponse {
+		t.Helper()
+		w := doJSON(t, router, http.MethodGet, path, token, nil)
+		var resp []api.OfferResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	if w, _ := makeOffer(seller, 90); w.Code != http.StatusBadRequest {
+		t.Errorf("Предложение за своё объявление: ожидали 400, получили %d", w.Code)
+	}
+	if w, _ := makeOffer(buyer, 0); w.Code != http.StatusBadRequest {
+		t.Errorf("Нулевая сумма: ожидали 400, получили %d", w.Code)
+	}
+	w, offer := makeOffer(buyer, 80)
+	if w.Code != http.StatusCreated || offer.Status != models.OfferPending || offer.Buyer.Username != "buyer" {
+		t.Fatalf("Создание предложения: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w, _ := makeOffer(buyer, 85); w.Code != http.StatusConflict {
+		t.Errorf("Второе открытое предложение: ожидали 409, получили %d", w.Code)
+	}
+
+	// Отвечает только другая сторона
+	if w, _ := act(buyer, offer.ID, "accept", nil); w.Code != http.StatusConflict {
+		t.Errorf("Принятие своего предложения: ожидали 409, получили %d", w.Code)
+	}
+	if w, _ := act(rival, offer.ID, "accept", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Чужое предложение: ожидали 404, получили %d", w.Code)
+	}
+	w, countered := act(seller, offer.ID, "counter", api.OfferRequest{Amount: 90})
+	if w.Code != http.StatusOK || countered.Amount != 90 || countered.ProposedBy == offer.ProposedBy {
+		t.Fatalf("Встречное предложение: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w, _ := act(seller, offer.ID, "accept", nil); w.Code != http.StatusConflict {
+		t.Errorf("Принятие своего встречного: ожидали 409, получили %d", w.Code)
+	}
+
+	_, rivalOffer := makeOffer(rival, 85)
+	if got := listOffers(rival, offersPath); len(got) != 1 || got[0].ID != rivalOffer.ID {
+		t.Errorf("Покупатель должен видеть только свои предложения: %+v", got)
+	}
+	if got := listOffers(seller, offersPath); len(got) != 2 {
+		t.Errorf("Продавец должен видеть все предложения: %+v", got)
+	}
+
+	// Принятие резервирует объявление и отклоняет остальные предложения
+	w, accepted := act(buyer, offer.ID, "accept", nil)
+	if w.Code != http.StatusOK || accepted.Status != models.OfferAccepted || accepted.Amount != 90 {
+		t.Fatalf("Принятие встречного предложения: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	ad, _ := srv.Ads.GetByID(context.Background(), adID)
+	if ad.Status != models.AdStatusReserved {
+		t.Errorf("Объявление должно быть зарезервировано, а оно %s", ad.Status)
+	}
+	if got := listOffers(rival, "/me/offers"); len(got) != 1 || got[0].Status != models.OfferRejected {
+		t.Errorf("Конкурирующее предложение должно быть отклонено: %+v", got)
+	}
+	notes, _ := srv.Notifications.ListByUser(context.Background(), ad.UserID, false, 10, 0)
+	if len(notes) != 3 { // два offer_received и offer_accepted
+		t.Errorf("У продавца ожидали 3 уведомления, получили %d", len(notes))
+	}
+	if w, _ := makeOffer(rival, 95); w.Code != http.StatusNotFound {
+		t.Errorf("Предложение на зарезервированное объявление: ожидали 404, получили %d", w.Code)
+	}
+
+	// Возврат в продажу снимает резерв и отменяет принятое предложение
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/publish", adID), seller, nil); w.Code != http.StatusOK {
+		t.Fatalf("Возврат в продажу: статус %d: %s", w.Code, w.Body.String())
+	}
+	if got := listOffers(buyer, "/me/offers"); len(got) != 1 || got[0].Status != models.OfferCancelled {
+		t.Errorf("Принятое предложение должно быть отменено: %+v", got)
+	}
+	buyerNotes, _ := srv.Notifications.ListByUser(context.Background(), accepted.Buyer.ID, false, 1, 0)
+	if len(buyerNotes) != 1 || buyerNotes[0].Type != models.NotificationOfferCancelled {
+		t.Errorf("Покупатель должен получить offer_cancelled: %+v", buyerNotes)
+	}
+	w, again := makeOffer(buyer, 95)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Новое предложение после снятия резерва: ожидали 201, получили %d", w.Code)
+	}
+	act(buyer, again.ID, "withdraw", nil)
+
+	// Просроченное предложение принять нельзя
+	otherAd := createAd(t, router, seller, api.CreateAdRequest{Title: "Самокат", Description: "Городской самокат", Price: 50})
+	w = doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/offers", otherAd), buyer, api.OfferRequest{Amount: 40})
+	var stale api.OfferResponse
+	json.Unmarshal(w.Body.Bytes(), &stale)
+	if n, _ := srv.Offers.ExpirePending(context.Background(), stale.ExpiresAt); n != 1 {
+		t.Fatalf("Ожидали одно истёкшее предложение, получили %d", n)
+	}
+	if w, _ := act(seller, stale.ID, "accept", nil); w.Code != http.StatusConflict {
+		t.Errorf("Принятие истёкшего предложения: ожидали 409, получили %d", w.Code)
+	}
+	if w, _ := act(buyer, stale.ID, "withdraw", nil); w.Code != http.StatusConflict {
+		t.Errorf("Отзыв истёкшего предложения: ожидали 409, получили %d", w.Code)
+	}
+}
+
+func TestAuctions(t *testing.T) {
+	srv, router := newTestServer(t)
+	srv.AuctionExtension = time.Hour
+	seller := registerAndLogin(t, router, "seller")
+	alice := registerAndLogin(t, router, "alice")
+	bob := registerAndLogin(t, router, "bob")
+
+	reserve := 150.0
+	auctionReq := func(endsIn time.Duration) api.CreateAdRequest {
+		return api.CreateAdRequest{
+			Title:       "Картина",
+			Description: "Масло, холст",
+			Auction: &api.AuctionRequest{
+				StartPrice:   100,
+				ReservePrice: &reserve,
+				MinIncrement: 10,
+				EndsAt:       time.Now().Add(endsIn),
+			},
+		}
+	}
+
+	if w := doJSON(t, router, http.MethodPost, "/ads", seller, auctionReq(time.Minute)); w.Code != http.StatusBadRequest {
+		t.Errorf("Слишком короткий аукцион: ожидали 400, получили %d", w.Code)
+	}
+	draft := auctionReq(30 * time.Minute)
+	draft.Status = models.AdStatusDraft
+	if w := doJSON(t, router, http.MethodPost, "/ads", seller, draft); w.Code != http.StatusBadRequest {
+		t.Errorf("Аукцион-черновик: ожидали 400, получили %d", w.Code)
+	}
+
+	adID := createAd(t, router, seller, auctionReq(30*time.Minute))
+	bidsPath := fmt.Sprintf("/ads/%d/bids", adID)
+	bid := func(token string, amount float64) (*httptest.ResponseRecorder, api.BidResponse) {
+		t.Helper()
+		w := doJSON(t, router, http.MethodPost, bidsPath, token, api.BidRequest{Amount: amount})
+		var resp api.BidResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	if w, _ := bid(seller, 100); w.Code != http.StatusBadRequest {
+		t.Errorf("Ставка на свой аукцион: ожидали 400, получили %d", w.Code)
+	}
+	if w, _ := bid(alice, 90); w.Code != http.StatusBadRequest {
+		t.Errorf("Ставка ниже стартовой: ожидали 400, получили %d", w.Code)
+	}
+	w, first := bid(alice, 100)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Первая ставка: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	// Ставка в окне продления отодвигает окончание
+	if time.Until(first.Auction.EndsAt) < 59*time.Minute {
+		t.Errorf("Аукцион должен продлиться, окончание %v", first.Auction.EndsAt)
+	}
+	if first.Auction.ReservePrice != nil || first.Auction.ReserveMet {
+		t.Errorf("Резервная цена не должна раскрываться покупателю: %+v", first.Auction)
+	}
+	if w, _ := bid(alice, 120); w.Code != http.StatusConflict {
+		t.Errorf("Ставка лидера поверх своей: ожидали 409, получили %d", w.Code)
+	}
+	if w, _ := bid(bob, 105); w.Code != http.StatusBadRequest {
+		t.Errorf("Ставка меньше шага: ожидали 400, получили %d", w.Code)
+	}
+	if w, _ := bid(bob, 110); w.Code != http.StatusCreated {
+		t.Fatalf("Ставка Боба: статус %d", w.Code)
+	}
+	aliceUser, _ := srv.Users.GetByUsername(context.Background(), "alice")
+	notes, _ := srv.Notifications.ListByUser(context.Background(), aliceUser.ID, false, 10, 0)
+	if len(notes) != 1 || notes[0].Type != models.NotificationAuctionOutbid {
+		t.Errorf("Алиса должна получить уведомление auction_outbid: %+v", notes)
+	}
+
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/mark-sold", adID), seller, nil); w.Code != http.StatusConflict {
+		t.Errorf("Смена состояния во время торгов: ожидали 409, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/offers", adID), alice, api.OfferRequest{Amount: 200}); w.Code != http.StatusBadRequest {
+		t.Errorf("Предложение цены на аукционе: ожидали 400, получили %d", w.Code)
+	}
+
+	// Параллельные ставки: каждая принятая учтена, ни одна не потеряна
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	bidders := []string{alice, bob}
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := doJSON(t, router, http.MethodPost, bidsPath, bidders[i%2], api.BidRequest{Amount: float64(200 + i*10)})
+			if w.Code == http.StatusCreated {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	auction, _ := srv.Auctions.Get(context.Background(), adID)
+	if auction.BidCount != 2+accepted {
+		t.Errorf("Ставок в аукционе %d, принято ответами %d", auction.BidCount, 2+accepted)
+	}
+	w = doJSON(t, router, http.MethodGet, bidsPath+"?limit=100", bob, nil)
+	var bids []models.Bid
+	json.Unmarshal(w.Body.Bytes(), &bids)
+	if len(bids) != auction.BidCount || bids[0].Amount != *auction.CurrentBid {
+		t.Errorf("История ставок не совпадает с аукционом: %d ставок, последняя %v, текущая %v", len(bids), bids[0].Amount, *auction.CurrentBid)
+	}
+	ad, _ := srv.Ads.GetByID(context.Background(), adID)
+	if ad.Price != *auction.CurrentBid {
+		t.Errorf("Цена объявления %v должна равняться текущей ставке %v", ad.Price, *auction.CurrentBid)
+	}
+
+	// Правка владельца не трогает цену, которую ведут ставки, и может её не передавать
+	edit := api.CreateAdRequest{Title: "Картина маслом", Description: "Масло, холст"}
+	if w := doJSON(t, router, http.MethodPut, fmt.Sprintf("/ads/%d", adID), seller, edit); w.Code != http.StatusOK {
+		t.Fatalf("Правка аукциона: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if ad, _ := srv.Ads.GetByID(context.Background(), adID); ad.Price != *auction.CurrentBid || ad.Title != edit.Title {
+		t.Errorf("Правка аукциона: цена %v, заголовок %q", ad.Price, ad.Title)
+	}
+	if w := doJSON(t, router, http.MethodDelete, fmt.Sprintf("/ads/%d", adID), seller, nil); w.Code != http.StatusConflict {
+		t.Errorf("Удаление аукциона со ставками владельцем: ожидали 409, получили %d", w.Code)
+	}
+
+	// Второй аукцион без ставок не достигнет резерва
+	unsold := createAd(t, router, seller, auctionReq(30*time.Minute))
+
+	closer := worker.NewAuctionCloser(srv.Tx, srv.Auctions, srv.Ads, srv.Outbox, srv.Notifications)
+	if n, err := closer.CloseDue(context.Background(), time.Now().Add(2*time.Hour)); err != nil || n != 2 {
+		t.Fatalf("Завершение аукционов: %d, ошибка %v", n, err)
+	}
+
+	ad, _ = srv.Ads.GetByID(context.Background(), adID)
+	auction, _ = srv.Auctions.Get(context.Background(), adID)
+	if ad.Status != models.AdStatusReserved || auction.WinnerID == nil || *auction.WinnerID != *auction.LeaderID {
+		t.Errorf("Победитель не определён: объявление %s, аукцион %+v", ad.Status, auction)
+	}
+	winnerNotes, _ := srv.Notifications.ListByUser(context.Background(), *auction.WinnerID, false, 10, 0)
+	if len(winnerNotes) == 0 || winnerNotes[0].Type != models.NotificationAuctionWon {
+		t.Errorf("Победитель должен получить auction_won: %+v", winnerNotes)
+	}
+	if ad, _ := srv.Ads.GetByID(context.Background(), unsold); ad.Status != models.AdStatusExpired {
+		t.Errorf("Аукцион без ставок должен истечь, а он %s", ad.Status)
+	}
+	if w, _ := bid(alice, 10000); w.Code != http.StatusNotFound {
+		t.Errorf("Ставка после завершения: ожидали 404, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/publish", unsold), seller, nil); w.Code != http.StatusConflict {
+		t.Errorf("Повторная публикация аукциона: ожидали 409, получили %d", w.Code)
+	}
+
+	// Модератор может снять аукцион со ставками, участники узнают об этом
+	removed := createAd(t, router, seller, auctionReq(30*time.Minute))
+	bidsPath = fmt.Sprintf("/ads/%d/bids", removed)
+	if w, _ := bid(bob, 100); w.Code != http.StatusCreated {
+		t.Fatalf("Ставка на снимаемый аукцион: статус %d", w.Code)
+	}
+	registerAndLogin(t, router, "moderator")
+	moderator := grantRole(t, srv, router, "moderator", models.RoleModerator)
+	if w := doJSON(t, router, http.MethodDelete, fmt.Sprintf("/ads/%d", removed), moderator, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Удаление аукциона модератором: статус %d", w.Code)
+	}
+	bobUser, _ := srv.Users.GetByUsername(context.Background(), "bob")
+	bobNotes, _ := srv.Notifications.ListByUser(context.Background(), bobUser.ID, false, 10, 0)
+	if len(bobNotes) == 0 || bobNotes[0].Type != models.NotificationAdRemoved {
+		t.Errorf("Участник торгов должен получить ad_removed: %+v", bobNotes)
+	}
+	if auction, _ := srv.Auctions.Get(context.Background(), removed); auction.Status != models.AuctionClosed || auction.WinnerID != nil {
+		t.Errorf("Снятый аукцион должен закрыться без победителя: %+v", auction)
+	}
+
+	// Решение remove из очереди модерации снимает торги так же
+	reported := createAd(t, router, seller, auctionReq(30*time.Minute))
+	bidsPath = fmt.Sprintf("/ads/%d/bids", reported)
+	if w, _ := bid(alice, 100); w.Code != http.StatusCreated {
+		t.Fatalf("Ставка на снимаемый аукцион: статус %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodPost, fmt.Sprintf("/moderation/ads/%d/actions", reported), moderator,
+		api.ModerationRequest{Action: "remove", Note: "Подделка"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Снятие аукциона модератором: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	aliceNotes, _ := srv.Notifications.ListByUser(context.Background(), aliceUser.ID, false, 1, 0)
+	if len(aliceNotes) != 1 || aliceNotes[0].Type != models.NotificationAdRemoved {
+		t.Errorf("Участник торгов должен получить ad_removed: %+v", aliceNotes)
+	}
+	if auction, _ := srv.Auctions.Get(context.Background(), reported); auction.Status != models.AuctionClosed {
+		t.Errorf("Снятый аукцион должен закрыться: %+v", auction)
+	}
+}
+
+func TestReviews(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+	stranger := registerAndLogin(t, router, "stranger")
+	buyerUser, _ := srv.Users.GetByUsername(context.Background(), "buyer")
+	sellerUser, _ := srv.Users.GetByUsername(context.Background(), "seller")
+
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	reviewsPath := fmt.Sprintf("/ads/%d/reviews", adID)
+	review := func(token string, rating int) *httptest.ResponseRecorder {
+		t.Helper()
+		return doJSON(t, router, http.MethodPost, reviewsPath, token, api.ReviewRequest{Rating: rating, Text: "Всё отлично"})
+	}
+
+	if w := review(buyer, 5); w.Code != http.StatusConflict {
+		t.Errorf("Отзыв до сделки: ожидали 409, получили %d", w.Code)
+	}
+
+	// Покупателем можно указать только того, кто писал продавцу
+	markSold := fmt.Sprintf("/ads/%d/mark-sold", adID)
+	if w := doJSON(t, router, http.MethodPost, markSold, seller, api.MarkSoldRequest{BuyerID: &buyerUser.ID}); w.Code != http.StatusBadRequest {
+		t.Errorf("Покупатель без переписки: ожидали 400, получили %d", w.Code)
+	}
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/threads", adID), buyer, api.MessageRequest{Body: "Беру"})
+	w := doJSON(t, router, http.MethodPost, markSold, seller, api.MarkSoldRequest{BuyerID: &buyerUser.ID})
+	var sold api.AdStatusResponse
+	json.Unmarshal(w.Body.Bytes(), &sold)
+	if w.Code != http.StatusOK || sold.BuyerID == nil || *sold.BuyerID != buyerUser.ID {
+		t.Fatalf("Продажа с покупателем: статус %d, тело %s", w.Code, w.Body.String())
+	}
+
+	if w := review(stranger, 5); w.Code != http.StatusForbidden {
+		t.Errorf("Отзыв постороннего: ожидали 403, получили %d", w.Code)
+	}
+	if w := review(buyer, 6); w.Code != http.StatusBadRequest {
+		t.Errorf("Оценка вне диапазона: ожидали 400, получили %d", w.Code)
+	}
+	if w := review(buyer, 4); w.Code != http.StatusCreated {
+		t.Fatalf("Отзыв покупателя: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w := review(buyer, 5); w.Code != http.StatusConflict {
+		t.Errorf("Повторный отзыв: ожидали 409, получили %d", w.Code)
+	}
+	if w := review(seller, 5); w.Code != http.StatusCreated {
+		t.Fatalf("Отзыв продавца: статус %d, тело %s", w.Code, w.Body.String())
+	}
+
+	// Вторая сделка меняет средний рейтинг продавца
+	otherAd := createAd(t, router, seller, api.CreateAdRequest{Title: "Самокат", Description: "Городской самокат", Price: 50})
+	w = doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/offers", otherAd), buyer, api.OfferRequest{Amount: 45})
+	var offer api.OfferResponse
+	json.Unmarshal(w.Body.Bytes(), &offer)
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/offers/%d/accept", offer.ID), seller, nil)
+	doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/mark-sold", otherAd), seller, nil)
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/reviews", otherAd), buyer, api.ReviewRequest{Rating: 3}); w.Code != http.StatusCreated {
+		t.Fatalf("Отзыв после принятого предложения: статус %d, тело %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, router, http.MethodGet, fmt.Sprintf("/users/%d", sellerUser.ID), stranger, nil)
+	var profile api.UserResponse
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	if profile.Rating != 3.5 || profile.ReviewCount != 2 {
+		t.Errorf("Рейтинг продавца: ожидали 3.5 по 2 отзывам, получили %+v", profile)
+	}
+
+	w = doJSON(t, router, http.MethodGet, fmt.Sprintf("/users/%d/reviews", sellerUser.ID), stranger, nil)
+	var reviews []api.ReviewResponse
+	json.Unmarshal(w.Body.Bytes(), &reviews)
+	if len(reviews) != 2 || reviews[0].Rating != 3 || reviews[0].Author.Username != "buyer" {
+		t.Errorf("Отзывы о продавце: %s", w.Body.String())
+	}
+
+	// Рейтинг виден везде, где возвращается пользователь
+	w = doJSON(t, router, http.MethodGet, "/threads", buyer, nil)
+	var threads []api.ThreadResponse
+	json.Unmarshal(w.Body.Bytes(), &threads)
+	if len(threads) != 1 || threads[0].Seller.ReviewCount != 2 || threads[0].Buyer.Rating != 5 {
+		t.Errorf("В переписке ожидали рейтинги участников: %s", w.Body.String())
+	}
+	notes, _ := srv.Notifications.ListByUser(context.Background(), buyerUser.ID, false, 10, 0)
+	if !slices.ContainsFunc(notes, func(n models.Notification) bool { return n.Type == models.NotificationReviewReceived }) {
+		t.Errorf("Покупатель должен получить уведомление об отзыве: %+v", notes)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLogin(t, router, "seller")
+	user := registerAndLogin(t, router, "user")
+	registerAndLogin(t, router, "moderator")
+	moderator := grantRole(t, srv, router, "moderator", models.RoleModerator)
+	registerAndLogin(t, router, "admin")
+	admin := grantRole(t, srv, router, "admin", models.RoleAdmin)
+	sellerUser, _ := srv.Users.GetByUsername(context.Background(), "seller")
+	plainUser, _ := srv.Users.GetByUsername(context.Background(), "user")
+
+	category := api.CategoryRequest{Name: "Транспорт"}
+	if w := doJSON(t, router, http.MethodPost, "/categories", moderator, category); w.Code != http.StatusForbidden {
+		t.Errorf("Раздел от модератора: ожидали 403, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/categories", admin, category); w.Code != http.StatusCreated {
+		t.Errorf("Раздел от администратора: ожидали 201, получили %d", w.Code)
+	}
+
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+	adPath := fmt.Sprintf("/ads/%d", adID)
+	edit := api.CreateAdRequest{Title: "Велосипед", Description: "Описание без ссылок", Price: 100}
+
+	if w := doJSON(t, router, http.MethodPut, adPath, user, edit); w.Code != http.StatusForbidden {
+		t.Errorf("Правка чужого объявления: ожидали 403, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPut, adPath, moderator, edit); w.Code != http.StatusOK {
+		t.Fatalf("Правка модератором: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodDelete, adPath, moderator, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Удаление модератором: статус %d", w.Code)
+	}
+
+	// Действия модератора попадают в журнал, владелец получает уведомления
+	if w := doJSON(t, router, http.MethodGet, "/moderation/actions", user, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Журнал модерации для пользователя: ожидали 403, получили %d", w.Code)
+	}
+	w := doJSON(t, router, http.MethodGet, fmt.Sprintf("/moderation/actions?ad_id=%d", adID), moderator, nil)
+	var actions []api.ModerationActionResponse
+	json.Unmarshal(w.Body.Bytes(), &actions)
+	if len(actions) != 2 || actions[0].Action != models.ModerationAdRemoved || actions[1].Action != models.ModerationAdEdited ||
+		actions[0].Moderator.Username != "moderator" || actions[0].TargetUserID != sellerUser.ID {
+		t.Errorf("Журнал модерации: %s", w.Body.String())
+	}
+	notes, _ := srv.Notifications.ListByUser(context.Background(), sellerUser.ID, false, 10, 0)
+	if len(notes) != 2 || notes[0].Type != models.NotificationAdRemoved || notes[1].Type != models.NotificationAdEdited {
+		t.Errorf("Уведомления владельца: %+v", notes)
+	}
+
+	// Роли назначает только администратор; старые токены после смены роли недействительны
+	rolePath := fmt.Sprintf("/admin/users/%d/role", plainUser.ID)
+	if w := doJSON(t, router, http.MethodPut, rolePath, moderator, api.RoleRequest{Role: models.RoleAdmin}); w.Code != http.StatusForbidden {
+		t.Errorf("Назначение роли модератором: ожидали 403, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPut, rolePath, admin, api.RoleRequest{Role: "superuser"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Неизвестная роль: ожидали 400, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodPut, rolePath, admin, api.RoleRequest{Role: models.RoleModerator})
+	var role api.RoleResponse
+	json.Unmarshal(w.Body.Bytes(), &role)
+	if w.Code != http.StatusOK || role.Role != models.RoleModerator || !slices.Contains(role.Permissions, models.PermModerateAds) {
+		t.Fatalf("Назначение роли: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodGet, "/moderation/actions", user, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Старый токен после смены роли: ожидали 401, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/moderation/actions", login(t, router, "user"), nil); w.Code != http.StatusOK {
+		t.Errorf("Журнал модерации для нового модератора: ожидали 200, получили %d", w.Code)
+	}
+}
+
+func TestReports(t *testing.T) {
+	srv, router := newTestServer(t)
+	srv.ReportHideThreshold = 2
+	seller := registerAndLogin(t, router, "seller")
+	first := registerAndLogin(t, router, "first")
+	second := registerAndLogin(t, router, "second")
+	registerAndLogin(t, router, "moderator")
+	moderator := grantRole(t, srv, router, "moderator", models.RoleModerator)
+	sellerUser, _ := srv.Users.GetByUsername(context.Background(), "seller")
+
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Айфон", Description: "Почти даром, предоплата", Price: 10})
+	reportPath := fmt.Sprintf("/ads/%d/report", adID)
+	scam := api.ReportRequest{Reason: models.ReportScam, Comment: "Просит предоплату"}
+
+	if w := doJSON(t, router, http.MethodPost, reportPath, seller, scam); w.Code != http.StatusBadRequest {
+		t.Errorf("Жалоба на своё объявление: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, reportPath, first, api.ReportRequest{Reason: "boring"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Неизвестная причина: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, reportPath, first, scam); w.Code != http.StatusCreated {
+		t.Fatalf("Жалоба: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPost, reportPath, first, scam); w.Code != http.StatusConflict {
+		t.Errorf("Повторная жалоба: ожидали 409, получили %d", w.Code)
+	}
+	if ids := feedIDs(t, router, second, "/ads"); len(ids) != 1 {
+		t.Fatalf("До порога объявление остаётся в ленте: %v", ids)
+	}
+
+	w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/offers", adID), first, api.OfferRequest{Amount: 8})
+	var offer api.OfferResponse
+	json.Unmarshal(w.Body.Bytes(), &offer)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Предложение до скрытия: статус %d, тело %s", w.Code, w.Body.String())
+	}
+
+	// Жалоба второго пользователя достигает порога и скрывает объявление
+	doJSON(t, router, http.MethodPost, reportPath, second, api.ReportRequest{Reason: models.ReportSpam})
+	if ids := feedIDs(t, router, second, "/ads"); len(ids) != 0 {
+		t.Errorf("Скрытое объявление не должно быть в ленте: %v", ids)
+	}
+	if ids := feedIDs(t, router, seller, "/ads?status=published"); len(ids) != 1 {
+		t.Errorf("Владелец должен видеть своё скрытое объявление: %v", ids)
+	}
+	notes, _ := srv.Notifications.ListByUser(context.Background(), sellerUser.ID, false, 10, 0)
+	if len(notes) != 2 || notes[0].Type != models.NotificationAdHidden {
+		t.Errorf("Владелец должен получить уведомление о скрытии: %+v", notes)
+	}
+
+	// Скрытое объявление недоступно покупателям, начатый торг можно только прекратить
+	for path, req := range map[string]any{
+		fmt.Sprintf("/ads/%d/offers", adID):   api.OfferRequest{Amount: 9},
+		fmt.Sprintf("/ads/%d/threads", adID):  api.MessageRequest{Body: "Ещё продаёте?"},
+		fmt.Sprintf("/ads/%d/favorite", adID): nil,
+	} {
+		if w := doJSON(t, router, http.MethodPost, path, second, req); w.Code != http.StatusNotFound {
+			t.Errorf("%s для скрытого объявления: ожидали 404, получили %d", path, w.Code)
+		}
+	}
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/offers/%d/accept", offer.ID), seller, nil); w.Code != http.StatusConflict {
+		t.Errorf("Принятие предложения по скрытому объявлению: ожидали 409, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/offers/%d/reject", offer.ID), seller, nil); w.Code != http.StatusOK {
+		t.Errorf("Отклонение предложения по скрытому объявлению: статус %d", w.Code)
+	}
+
+	if w := doJSON(t, router, http.MethodGet, "/moderation/queue", first, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Очередь для пользователя: ожидали 403, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodGet, "/moderation/queue", moderator, nil)
+	var queue []api.ModerationQueueItem
+	json.Unmarshal(w.Body.Bytes(), &queue)
+	if len(queue) != 1 || queue[0].AdID != adID || queue[0].Reports != 2 || !queue[0].Hidden ||
+		queue[0].Reasons[models.ReportScam] != 1 || queue[0].Reasons[models.ReportSpam] != 1 {
+		t.Fatalf("Очередь модерации: %s", w.Body.String())
+	}
+	w = doJSON(t, router, http.MethodGet, fmt.Sprintf("/moderation/ads/%d/reports", adID), moderator, nil)
+	var reports []api.ReportResponse
+	json.Unmarshal(w.Body.Bytes(), &reports)
+	if len(reports) != 2 || reports[0].Reporter.Username != "first" || reports[0].Comment != "Просит предоплату" {
+		t.Errorf("Жалобы на объявление: %s", w.Body.String())
+	}
+
+	// Решение без пояснения не принимается; отклонение жалоб возвращает объявление в ленту
+	actionsPath := fmt.Sprintf("/moderation/ads/%d/actions", adID)
+	if w := doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "dismiss"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Решение без пояснения: ожидали 400, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "dismiss", Note: "Предоплата через сервис безопасна"})
+	var record api.ModerationActionResponse
+	json.Unmarshal(w.Body.Bytes(), &record)
+	if w.Code != http.StatusOK || record.Action != models.ModerationReportsDismissed || record.Moderator.Username != "moderator" {
+		t.Fatalf("Отклонение жалоб: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if ids := feedIDs(t, router, second, "/ads"); len(ids) != 1 {
+		t.Errorf("После отклонения жалоб объявление должно вернуться в ленту: %v", ids)
+	}
+	w = doJSON(t, router, http.MethodGet, "/moderation/queue", moderator, nil)
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("Очередь после решения должна быть пуста: %s", w.Body.String())
+	}
+
+	// После решения пожаловаться снова можно
+	if w := doJSON(t, router, http.MethodPost, reportPath, first, scam); w.Code != http.StatusCreated {
+		t.Errorf("Жалоба после решения по прежней: ожидали 201, получили %d", w.Code)
+	}
+
+	// Скрытое модератором объявление отклонение новых жалоб не возвращает
+	doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "hide", Note: "Проверяем продавца"})
+	doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "dismiss", Note: "Жалоб нет"})
+	if ids := feedIDs(t, router, second, "/ads"); len(ids) != 0 {
+		t.Errorf("Скрытое модератором объявление не должно вернуться в ленту: %v", ids)
+	}
+
+	// Предупреждение приходит владельцу, блокировка завершает его сессии
+	w = doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "warn", Note: "Не просите предоплату"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Предупреждение: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	notes, _ = srv.Notifications.ListByUser(context.Background(), sellerUser.ID, false, 10, 0)
+	if len(notes) != 4 || notes[0].Type != models.NotificationModerationWarn || !strings.Contains(notes[0].Payload, "Не просите предоплату") {
+		t.Errorf("Владелец должен получить предупреждение: %+v", notes)
+	}
+	if w := doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "suspend", Note: "Мошенничество"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Блокировка без срока: ожидали 400, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "suspend", Note: "Мошенничество", SuspendDays: 7})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Блокировка: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	suspended, _ := srv.Users.GetByID(context.Background(), sellerUser.ID)
+	if suspended.Status != models.UserSuspended || suspended.StatusUntil == nil || suspended.StatusReason != "Мошенничество" {
+		t.Errorf("Пользователь должен быть заблокирован: %+v", suspended)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/ads", seller, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Токен заблокированного: ожидали 401, получили %d", w.Code)
+	}
+
+	// Повторная блокировка на меньший срок не сокращает действующую, а бан не заменяет
+	doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "suspend", Note: "Ещё раз", SuspendDays: 1})
+	if again, _ := srv.Users.GetByID(context.Background(), sellerUser.ID); !again.StatusUntil.Equal(*suspended.StatusUntil) {
+		t.Errorf("Блокировка сократилась: была до %v, стала до %v", suspended.StatusUntil, again.StatusUntil)
+	}
+	srv.Users.SetStatus(context.Background(), sellerUser.ID, models.UserBanned, nil, "Бан")
+	w = doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "suspend", Note: "Мошенничество", SuspendDays: 7})
+	if w.Code != http.StatusConflict {
+		t.Errorf("Блокировка забаненного: ожидали 409, получили %d", w.Code)
+	}
+	if banned, _ := srv.Users.GetByID(context.Background(), sellerUser.ID); banned.Status != models.UserBanned {
+		t.Errorf("Бан не должен смениться блокировкой: %+v", banned)
+	}
+
+	// Удаление модератором убирает объявление и пишет решение в журнал
+	w = doJSON(t, router, http.MethodPost, actionsPath, moderator, api.ModerationRequest{Action: "remove", Note: "Запрещённый товар"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Удаление: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if _, err := srv.Ads.GetByID(context.Background(), adID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Объявление должно быть удалено, ошибка: %v", err)
+	}
+	w = doJSON(t, router, http.MethodGet, fmt.Sprintf("/moderation/actions?ad_id=%d", adID), moderator, nil)
+	var actions []api.ModerationActionResponse
+	json.Unmarshal(w.Body.Bytes(), &actions)
+	if len(actions) != 7 || actions[0].Action != models.ModerationAdRemoved || actions[0].Note != "Запрещённый товар" {
+		t.Errorf("Журнал модерации: %s", w.Body.String())
+	}
+}
+
+func TestContentScreening(t *testing.T) {
+	srv, router := newTestServer(t)
+	words, err := screening.NewWordFilter([]string{"оружие"}, nil, screening.Reject)
+	if err != nil {
+		t.Fatalf("NewWordFilter: %v", err)
+	}
+	srv.Screener = screening.Chain{
+		words,
+		screening.NewContactFilter(screening.Flag),
+		screening.NewDuplicateFilter(srv.Ads, 0.9, screening.Reject),
+	}
+	seller := registerAndLogin(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+	registerAndLogin(t, router, "moderator")
+	moderator := grantRole(t, srv, router, "moderator", models.RoleModerator)
+
+	w := doJSON(t, router, http.MethodPost, "/ads", seller, api.CreateAdRequest{Title: "Оружие", Description: "Недорого", Price: 10})
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "запрещённое содержимое") {
+		t.Fatalf("Запрещённое слово: ожидали 422 с причиной, получили %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "оружие") {
+		t.Errorf("Найденное слово не должно сообщаться автору: %s", w.Body.String())
+	}
+
+	// Контакт в тексте: объявление сохраняется скрытым и попадает в очередь
+	w = doJSON(t, router, http.MethodPost, "/ads", seller, api.CreateAdRequest{Title: "Велосипед", Description: "Звоните +7 912 345-67-89", Price: 10})
+	var flagged api.AdResponse
+	json.Unmarshal(w.Body.Bytes(), &flagged)
+	if w.Code != http.StatusCreated || !flagged.Hidden {
+		t.Fatalf("Объявление с контактом должно быть скрыто: %d %s", w.Code, w.Body.String())
+	}
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 0 {
+		t.Errorf("Скрытое фильтром объявление не должно быть в ленте: %v", ids)
+	}
+	w = doJSON(t, router, http.MethodGet, "/moderation/queue", moderator, nil)
+	var queue []api.ModerationQueueItem
+	json.Unmarshal(w.Body.Bytes(), &queue)
+	if len(queue) != 1 || queue[0].AdID != flagged.ID || queue[0].Reasons[models.ReportAutomated] != 1 {
+		t.Fatalf("Очередь модерации: %s", w.Body.String())
+	}
+	w = doJSON(t, router, http.MethodGet, fmt.Sprintf("/moderation/ads/%d/reports", flagged.ID), moderator, nil)
+	var reports []api.ReportResponse
+	json.Unmarshal(w.Body.Bytes(), &reports)
+	if len(reports) != 1 || reports[0].Reporter != nil || !strings.Contains(reports[0].Comment, "телефон") {
+		t.Errorf("Жалоба фильтра: %s", w.Body.String())
+	}
+	sellerUser, _ := srv.Users.GetByUsername(context.Background(), "seller")
+	notes, _ := srv.Notifications.ListByUser(context.Background(), sellerUser.ID, false, 10, 0)
+	if len(notes) != 1 || notes[0].Type != models.NotificationAdHidden {
+		t.Errorf("Владелец должен узнать о скрытии: %+v", notes)
+	}
+	w = doJSON(t, router, http.MethodPost, fmt.Sprintf("/moderation/ads/%d/actions", flagged.ID), moderator,
+		api.ModerationRequest{Action: "dismiss", Note: "Телефон сервиса доставки"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Отклонение жалобы фильтра: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 1 || ids[0] != flagged.ID {
+		t.Errorf("После отклонения жалобы фильтра объявление должно вернуться в ленту: %v", ids)
+	}
+
+	// Дубликат своего объявления отклоняется, но правка самого объявления — нет
+	adID := createAd(t, router, seller, api.CreateAdRequest{Title: "Диван раскладной", Description: "Серый, три года", Price: 100})
+	w = doJSON(t, router, http.MethodPost, "/ads", seller, api.CreateAdRequest{Title: "Диван раскладной!", Description: "Серый, три года", Price: 90})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Дубликат: ожидали 422, получили %d", w.Code)
+	}
+	adPath := fmt.Sprintf("/ads/%d", adID)
+	if w := doJSON(t, router, http.MethodPut, adPath, seller, api.CreateAdRequest{Title: "Диван раскладной", Description: "Серый, три года", Price: 80}); w.Code != http.StatusOK {
+		t.Errorf("Правка без изменения текста: статус %d, тело %s", w.Code, w.Body.String())
+	}
+
+	// Правка проверяется теми же фильтрами; правки модератора — нет
+	if w := doJSON(t, router, http.MethodPut, adPath, seller, api.CreateAdRequest{Title: "Диван и оружие", Description: "Серый", Price: 80}); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Правка с запрещённым словом: ожидали 422, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodPut, adPath, seller, api.CreateAdRequest{Title: "Диван раскладной", Description: "Пишите seller@example.com", Price: 80})
+	var updated api.AdResponse
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || !updated.Hidden {
+		t.Errorf("Правка с контактом должна скрыть объявление: %d %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPut, adPath, moderator, api.CreateAdRequest{Title: "Диван раскладной", Description: "Пишите seller@example.com", Price: 80}); w.Code != http.StatusOK {
+		t.Errorf("Правка модератора не проверяется фильтрами: статус %d, тело %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUserStatus(t *testing.T) {
+	srv, router := newTestServer(t)
+	seller := registerAndLoginTokens(t, router, "seller")
+	buyer := registerAndLogin(t, router, "buyer")
+	registerAndLogin(t, router, "admin")
+	admin := grantRole(t, srv, router, "admin", models.RoleAdmin)
+	sellerUser, _ := srv.Users.GetByUsername(context.Background(), "seller")
+	creds := map[string]string{"username": "seller", "password": "password123"}
+
+	adID := createAd(t, router, seller.Token, api.CreateAdRequest{Title: "Велосипед", Description: "Горный велосипед", Price: 100})
+
+	// Токен отклоняется сразу после блокировки, даже если сессия не отозвана
+	srv.Users.SetStatus(context.Background(), sellerUser.ID, models.UserSuspended, nil, "Проверка")
+	if w := doJSON(t, router, http.MethodGet, "/ads", seller.Token, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Токен заблокированного: ожидали 403, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/token/refresh", "", api.RefreshRequest{RefreshToken: seller.RefreshToken}); w.Code != http.StatusForbidden {
+		t.Errorf("Обновление токена заблокированным: ожидали 403, получили %d", w.Code)
+	}
+
+	// Истёкшая блокировка снимается при входе
+	past := time.Now().Add(-time.Minute)
+	srv.Users.SetStatus(context.Background(), sellerUser.ID, models.UserSuspended, &past, "Проверка")
+	if w := doJSON(t, router, http.MethodPost, "/login", "", creds); w.Code != http.StatusOK {
+		t.Fatalf("Вход после окончания блокировки: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if u, _ := srv.Users.GetByID(context.Background(), sellerUser.ID); u.Status != models.UserActive || u.StatusUntil != nil {
+		t.Errorf("Истёкшая блокировка должна быть снята: %+v", u)
+	}
+	token := login(t, router, "seller")
+
+	statusPath := fmt.Sprintf("/admin/users/%d/status", sellerUser.ID)
+	if w := doJSON(t, router, http.MethodPut, statusPath, buyer, api.UserStatusRequest{Status: models.UserBanned, Reason: "Спам"}); w.Code != http.StatusForbidden {
+		t.Errorf("Бан от пользователя: ожидали 403, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPut, statusPath, admin, api.UserStatusRequest{Status: models.UserBanned}); w.Code != http.StatusBadRequest {
+		t.Errorf("Бан без причины: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPut, statusPath, admin, api.UserStatusRequest{Status: models.UserSuspended, Until: &past, Reason: "Спам"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Срок в прошлом: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPut, statusPath, admin, api.UserStatusRequest{Status: "deleted", Reason: "Спам"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Неизвестный статус: ожидали 400, получили %d", w.Code)
+	}
+
+	// Бан завершает сессии, запрещает вход и убирает объявления из ленты
+	w := doJSON(t, router, http.MethodPut, statusPath, admin, api.UserStatusRequest{Status: models.UserBanned, Reason: "Мошенничество"})
+	var status api.UserStatusResponse
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if w.Code != http.StatusOK || status.Status != models.UserBanned || status.Until != nil || status.Reason != "Мошенничество" {
+		t.Fatalf("Бан: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodGet, "/ads", token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Токен после бана: ожидали 401, получили %d", w.Code)
+	}
+	w = doJSON(t, router, http.MethodPost, "/login", "", creds)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "Мошенничество") {
+		t.Errorf("Вход забаненного: ожидали 403 с причиной, получили %d %s", w.Code, w.Body.String())
+	}
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 0 {
+		t.Errorf("Объявления забаненного не должны быть в ленте: %v", ids)
+	}
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/favorite", adID), buyer, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Избранное с объявлением забаненного: ожидали 404, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, fmt.Sprintf("/ads/%d/threads", adID), buyer, api.MessageRequest{Body: "Здравствуйте"}); w.Code != http.StatusNotFound {
+		t.Errorf("Сообщение забаненному продавцу: ожидали 404, получили %d", w.Code)
+	}
+
+	// Временная блокировка запрещает вход, но объявления остаются в ленте
+	until := time.Now().Add(24 * time.Hour)
+	w = doJSON(t, router, http.MethodPut, statusPath, admin, api.UserStatusRequest{Status: models.UserSuspended, Until: &until, Reason: "Остыть"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Блокировка: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPost, "/login", "", creds); w.Code != http.StatusForbidden {
+		t.Errorf("Вход заблокированного: ожидали 403, получили %d", w.Code)
+	}
+	if ids := feedIDs(t, router, buyer, "/ads"); len(ids) != 1 || ids[0] != adID {
+		t.Errorf("Объявления временно заблокированного остаются в ленте: %v", ids)
+	}
+
+	// Снятие ограничения возвращает доступ
+	if w := doJSON(t, router, http.MethodPut, statusPath, admin, api.UserStatusRequest{Status: models.UserActive}); w.Code != http.StatusOK {
+		t.Fatalf("Снятие блокировки: статус %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/ads", login(t, router, "seller"), nil); w.Code != http.StatusOK {
+		t.Errorf("Вход после снятия блокировки: статус %d", w.Code)
+	}
+	adminUser, _ := srv.Users.GetByUsername(context.Background(), "admin")
+	if w := doJSON(t, router, http.MethodPut, fmt.Sprintf("/admin/users/%d/status", adminUser.ID), admin, api.UserStatusRequest{Status: models.UserBanned, Reason: "Тест"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Бан самого себя: ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	srv, router := newTestServer(t)
+	mailer := &memoryMailer{}
+	srv.Mailer = mailer
+	srv.RequireEmailVerification = true
+	creds := map[string]string{"username": "alice", "password": "password123"}
+
+	if w := doJSON(t, router, http.MethodPost, "/register", "", creds); w.Code != http.StatusBadRequest {
+		t.Errorf("Регистрация без почты: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/register", "", api.RegisterRequest{Username: "alice", Password: "password123", Email: "not-an-address"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Неверная почта: ожидали 400, получили %d", w.Code)
+	}
+	w := doJSON(t, router, http.MethodPost, "/register", "", api.RegisterRequest{Username: "alice", Password: "password123", Email: " Alice@Example.com "})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Регистрация: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	first := mailer.lastToken(t, "alice@example.com")
+
+	// Занятая почта не раскрывается: ответ тот же, владельцу уходит предупреждение
+	taken := doJSON(t, router, http.MethodPost, "/register", "", api.RegisterRequest{Username: "alice2", Password: "password123", Email: "alice@example.com"})
+	if taken.Code != w.Code {
+		t.Errorf("Занятая почта: ожидали %d, получили %d", w.Code, taken.Code)
+	}
+	if len(mailer.sent) != 2 || mailer.sent[1].To != "alice@example.com" || strings.Contains(mailer.sent[1].Body, "?token=") {
+		t.Fatalf("Ожидали письмо о существующей учётной записи, отправлено: %+v", mailer.sent)
+	}
+	if _, err := srv.Users.GetByUsername(context.Background(), "alice2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Пользователь с занятой почтой создан: %v", err)
+	}
+
+	// До подтверждения почты вход запрещён
+	if w := doJSON(t, router, http.MethodPost, "/login", "", creds); w.Code != http.StatusForbidden {
+		t.Errorf("Вход без подтверждения: ожидали 403, получили %d", w.Code)
+	}
+
+	// Повторное письмо заменяет прежнюю ссылку; для неизвестного адреса ответ тот же
+	if w := doJSON(t, router, http.MethodPost, "/email/verify/resend", "", api.EmailRequest{Email: "alice@example.com"}); w.Code != http.StatusAccepted {
+		t.Errorf("Повторное письмо: ожидали 202, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/email/verify/resend", "", api.EmailRequest{Email: "nobody@example.com"}); w.Code != http.StatusAccepted {
+		t.Errorf("Повторное письмо на неизвестный адрес: ожидали 202, получили %d", w.Code)
+	}
+	if len(mailer.sent) != 3 {
+		t.Fatalf("Ожидали 3 письма, отправлено %d", len(mailer.sent))
+	}
+	if w := doJSON(t, router, http.MethodPost, "/email/verify", "", api.VerifyEmailRequest{Token: first}); w.Code != http.StatusBadRequest {
+		t.Errorf("Старая ссылка подтверждения: ожидали 400, получили %d", w.Code)
+	}
+	verify := mailer.lastToken(t, "alice@example.com")
+	if w := doJSON(t, router, http.MethodPost, "/email/verify", "", api.VerifyEmailRequest{Token: verify}); w.Code != http.StatusNoContent {
+		t.Fatalf("Подтверждение почты: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPost, "/email/verify", "", api.VerifyEmailRequest{Token: verify}); w.Code != http.StatusBadRequest {
+		t.Errorf("Повторное подтверждение: ожидали 400, получили %d", w.Code)
+	}
+	token := login(t, router, "alice")
+
+	// Восстановление пароля завершает все сессии
+	if w := doJSON(t, router, http.MethodPost, "/password/forgot", "", api.EmailRequest{Email: "nobody@example.com"}); w.Code != http.StatusAccepted {
+		t.Errorf("Восстановление для неизвестного адреса: ожидали 202, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/password/forgot", "", api.EmailRequest{Email: "ALICE@example.com"}); w.Code != http.StatusAccepted {
+		t.Fatalf("Восстановление пароля: статус %d", w.Code)
+	}
+	reset := mailer.lastToken(t, "alice@example.com")
+	if w := doJSON(t, router, http.MethodPost, "/email/verify", "", api.VerifyEmailRequest{Token: reset}); w.Code != http.StatusBadRequest {
+		t.Errorf("Токен восстановления не подтверждает почту: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/password/reset", "", api.ResetPasswordRequest{Token: reset, Password: "123"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Короткий пароль: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/password/reset", "", api.ResetPasswordRequest{Token: reset, Password: "newpassword"}); w.Code != http.StatusNoContent {
+		t.Fatalf("Сброс пароля: статус %d, тело %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(t, router, http.MethodPost, "/password/reset", "", api.ResetPasswordRequest{Token: reset, Password: "otherpassword"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Повторный сброс по той же ссылке: ожидали 400, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodGet, "/ads", token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Токен после сброса пароля: ожидали 401, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/login", "", creds); w.Code != http.StatusUnauthorized {
+		t.Errorf("Вход со старым паролем: ожидали 401, получили %d", w.Code)
+	}
+	if w := doJSON(t, router, http.MethodPost, "/login", "", map[string]string{"username": "alice", "password": "newpassword"}); w.Code != http.StatusOK {
+		t.Errorf("Вход с новым паролем: статус %d", w.Code)
+	}
+
+	// Просроченная ссылка не принимается
+	alice, _ := srv.Users.GetByUsername(context.Background(), "alice")
+	srv.Tokens.Create(context.Background(), &models.UserToken{
+		UserID:    alice.ID,
+		Purpose:   models.TokenPasswordReset,
+		TokenHash: services.HashToken("expired-token"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if w := doJSON(t, router, http.MethodPost, "/password/reset", "", api.ResetPasswordRequest{Token: "expired-token", Password: "newpassword2"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Просроченная ссылка: ожидали 400, получили %d", w.Code)
+	}
+
+	// Запросы писем ограничены по адресу и по IP, в том числе для неизвестных адресов
+	srv.MailLimitPerAddress = ratelimit.New(1, time.Hour)
+	srv.MailLimitPerIP = ratelimit.New(3, time.Hour)
+	for i, want := range []int{http.StatusAccepted, http.StatusTooManyRequests} {
+		if w := doJSON(t, router, http.MethodPost, "/password/forgot", "", api.EmailRequest{Email: "nobody@example.com"}); w.Code != want {
+			t.Errorf("Запрос %d на один адрес: ожидали %d, получили %d", i+1, want, w.Code)
+		}
+	}
+	for i, want := range []int{http.StatusAccepted, http.StatusTooManyRequests} {
+		email := fmt.Sprintf("other%d@example.com", i)
+		if w := doJSON(t, router, http.MethodPost, "/email/verify/resend", "", api.EmailRequest{Email: email}); w.Code != want {
+			t.Errorf("Запрос %d с одного IP: ожидали %d, получили %d", i+3, want, w.Code)
+		}
+	}
+	if w := doJSON(t, router, http.MethodPost, "/register", "", api.RegisterRequest{Username: "carol", Password: "password123", Email: "carol@example.com"}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Регистрация сверх лимита: ожидали 429, получили %d", w.Code)
+	}
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// Ограничения на длительность аукциона.
+const (
+	MinAuctionDuration = 10 * time.Minute
+	MaxAuctionDuration = 30 * 24 * time.Hour
+)
+
+// DefaultAuctionExtension — на сколько продлевается аукцион при ставке
+// в последние минуты, если AuctionExtension не задан.
+const DefaultAuctionExtension = 2 * time.Minute
+
+// bidRetries — сколько раз повторяется ставка, если аукцион параллельно изменился.
+const bidRetries = 3
+
+// AuctionRequest — условия аукциона при создании объявления.
+type AuctionRequest struct {
+	StartPrice   float64   `json:"start_price"`
+	ReservePrice *float64  `json:"reserve_price"`
+	MinIncrement float64   `json:"min_increment"`
+	EndsAt       time.Time `json:"ends_at"`
+}
+
+// AuctionResponse — состояние аукциона. Резервная цена видна только продавцу,
+// остальным — лишь признак того, достигнута ли она.
+type AuctionResponse struct {
+	StartPrice   float64              `json:"start_price"`
+	ReservePrice *float64             `json:"reserve_price,omitempty"`
+	ReserveMet   bool                 `json:"reserve_met"`
+	MinIncrement float64              `json:"min_increment"`
+	MinNextBid   float64              `json:"min_next_bid"`
+	CurrentBid   *float64             `json:"current_bid"`
+	LeaderID     *uint                `json:"leader_id"`
+	BidCount     int                  `json:"bid_count"`
+	EndsAt       time.Time            `json:"ends_at"`
+	Status       models.AuctionStatus `json:"status"`
+	WinnerID     *uint                `json:"winner_id"`
+}
+
+// BidRequest — ставка.
+type BidRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+// BidResponse — принятая ставка и состояние аукциона после неё.
+type BidResponse struct {
+	Bid     models.Bid      `json:"bid"`
+	Auction AuctionResponse `json:"auction"`
+}
+
+// AuctionOutbidPayload — уведомление бывшему лидеру о том, что его ставку перебили.
+type AuctionOutbidPayload struct {
+	AdID       uint      `json:"ad_id"`
+	Title      string    `json:"title"`
+	CurrentBid float64   `json:"current_bid"`
+	EndsAt     time.Time `json:"ends_at"`
+}
+
+func toAuctionResponse(a *models.Auction, viewerID, sellerID uint) *AuctionResponse {
+	resp := &AuctionResponse{
+		StartPrice:   a.StartPrice,
+		ReserveMet:   a.ReserveMet(),
+		MinIncrement: a.MinIncrement,
+		MinNextBid:   a.MinNextBid(),
+		CurrentBid:   a.CurrentBid,
+		LeaderID:     a.LeaderID,
+		BidCount:     a.BidCount,
+		EndsAt:       a.EndsAt,
+		Status:       a.Status,
+		WinnerID:     a.WinnerID,
+	}
+	if viewerID == sellerID {
+		resp.ReservePrice = a.ReservePrice
+	}
+	return resp
+}
+
+// newAuction проверяет условия аукциона и готовит его к сохранению.
+func newAuction(req *AuctionRequest) (*models.Auction, error) {
+	now := time.Now()
+	switch {
+	case req.StartPrice <= 0:
+		return nil, errors.New("стартовая цена должна быть больше нуля")
+	case req.MinIncrement <= 0:
+		return nil, errors.New("минимальный шаг ставки должен быть больше нуля")
+	case req.ReservePrice != nil && *req.ReservePrice < req.StartPrice:
+		return nil, errors.New("резервная цена не может быть меньше стартовой")
+	case req.EndsAt.Before(now.Add(MinAuctionDuration)) || req.EndsAt.After(now.Add(MaxAuctionDuration)):
+		return nil, fmt.Errorf("аукцион должен длиться от %v до %v", MinAuctionDuration, MaxAuctionDuration)
+	}
+	return &models.Auction{
+		StartPrice:   req.StartPrice,
+		ReservePrice: req.ReservePrice,
+		MinIncrement: req.MinIncrement,
+		EndsAt:       req.EndsAt,
+		Status:       models.AuctionOpen,
+	}, nil
+}
+
+// PlaceBidHandler принимает ставку на аукцион. Параллельные ставки не
+// теряются: при конфликте версии ставка перепроверяется на свежих данных.
+// Ставка в последние AuctionExtension продлевает аукцион, чтобы у других
+// участников было время ответить.
+func (s *Server) PlaceBidHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	var req BidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil || ad.ListingType != models.ListingAuction || ad.Status != models.AdStatusPublished || !adAvailable(ad) {
+		utils.WriteJSONError(w, http.StatusNotFound, "аукцион не найден")
+		return
+	}
+	if ad.UserID == user.ID {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя делать ставки на свой аукцион")
+		return
+	}
+
+	for range bidRetries {
+		auction, err := s.Auctions.Get(r.Context(), ad.ID)
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении аукциона")
+			return
+		}
+
+		now := time.Now()
+		switch {
+		case auction.Status != models.AuctionOpen || !now.Before(auction.EndsAt):
+			utils.WriteJSONError(w, http.StatusConflict, "аукцион завершён")
+			return
+		case auction.LeaderID != nil && *auction.LeaderID == user.ID:
+			utils.WriteJSONError(w, http.StatusConflict, "ваша ставка уже лидирует")
+			return
+		case req.Amount < auction.MinNextBid():
+			utils.WriteJSONError(w, http.StatusBadRequest, fmt.Sprintf("ставка должна быть не меньше %.2f", auction.MinNextBid()))
+			return
+		}
+
+		previousLeader := auction.LeaderID
+		auction.CurrentBid = &req.Amount
+		auction.LeaderID = &user.ID
+		auction.BidCount++
+		if extendTo := now.Add(s.auctionExtension()); auction.EndsAt.Before(extendTo) {
+			auction.EndsAt = extendTo
+		}
+
+		bid := models.Bid{AdID: ad.ID, BidderID: user.ID, Amount: req.Amount}
+		err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+			if err := s.Auctions.PlaceBid(ctx, auction, &bid); err != nil {
+				return err
+			}
+			oldPrice := ad.Price
+			ad.Price, ad.UpdatedAt = req.Amount, now
+			return s.recordAdPriceChange(ctx, ad, oldPrice)
+		})
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении ставки")
+			return
+		}
+
+		if previousLeader != nil {
+			s.notify(r.Context(), models.NotificationAuctionOutbid, AuctionOutbidPayload{
+				AdID:       ad.ID,
+				Title:      ad.Title,
+				CurrentBid: req.Amount,
+				EndsAt:     auction.EndsAt,
+			}, *previousLeader)
+		}
+		utils.WriteJSON(w, http.StatusCreated, BidResponse{Bid: bid, Auction: *toAuctionResponse(auction, user.ID, ad.UserID)})
+		return
+	}
+
+	utils.WriteJSONError(w, http.StatusConflict, "слишком много одновременных ставок, повторите запрос")
+}
+
+// ListBidsHandler возвращает ставки аукциона, новые первыми.
+func (s *Server) ListBidsHandler(w http.ResponseWriter, r *http.Request) {
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := s.Auctions.Get(r.Context(), adID); err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "аукцион не найден")
+		return
+	}
+
+	bids, err := s.Auctions.ListBids(r.Context(), adID, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении ставок")
+		return
+	}
+	if bids == nil {
+		bids = []models.Bid{}
+	}
+	utils.WriteJSON(w, http.StatusOK, bids)
+}
+
+func (s *Server) auctionExtension() time.Duration {
+	if s.AuctionExtension <= 0 {
+		return DefaultAuctionExtension
+	}
+	return s.AuctionExtension
+}
+
+// auctionBidders возвращает всех, кто делал ставки на аукционе, без повторов.
+func (s *Server) auctionBidders(ctx context.Context, adID uint) ([]uint, error) {
+	const batch = 100
+	var bidders []uint
+	seen := make(map[uint]bool)
+	for offset := 0; ; offset += batch {
+		bids, err := s.Auctions.ListBids(ctx, adID, batch, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, bid := range bids {
+			if !seen[bid.BidderID] {
+				seen[bid.BidderID] = true
+				bidders = append(bidders, bid.BidderID)
+			}
+		}
+		if len(bids) < batch {
+			return bidders, nil
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// CategoryRequest описывает входящие данные для создания или изменения раздела.
+type CategoryRequest struct {
+	Name     string `json:"name"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// CategoryNode — раздел каталога вместе с вложенными подразделами.
+type CategoryNode struct {
+	ID       uint           `json:"id"`
+	Name     string         `json:"name"`
+	ParentID *uint          `json:"parent_id"`
+	Children []CategoryNode `json:"children"`
+}
+
+// validateCategoryRequest проверяет имя раздела и существование родителя.
+// Для существующего раздела дополнительно запрещает циклы в дереве.
+func (s *Server) validateCategoryRequest(r *http.Request, req *CategoryRequest, self *models.Category) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if len(req.Name) < 2 || len(req.Name) > 100 {
+		return errors.New("название раздела должно содержать от 2 до 100 символов")
+	}
+
+	if req.ParentID == nil {
+		return nil
+	}
+	if _, err := s.Categories.GetByID(r.Context(), *req.ParentID); err != nil {
+		return errors.New("родительский раздел не найден")
+	}
+
+	if self != nil {
+		descendants, err := s.Categories.DescendantIDs(r.Context(), self.ID)
+		if err != nil {
+			return errors.New("ошибка при проверке дерева разделов")
+		}
+		if slices.Contains(descendants, *req.ParentID) {
+			return errors.New("раздел нельзя вложить в самого себя или в свой подраздел")
+		}
+	}
+	return nil
+}
+
+// ListCategoriesHandler возвращает дерево разделов каталога.
+func (s *Server) ListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.Categories.List(r.Context())
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении разделов")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, buildCategoryTree(categories, nil))
+}
+
+// buildCategoryTree собирает вложенное дерево из плоского списка разделов.
+func buildCategoryTree(categories []models.Category, parentID *uint) []CategoryNode {
+	nodes := []CategoryNode{}
+	for _, c := range categories {
+		if !sameParent(c.ParentID, parentID) {
+			continue
+		}
+		nodes = append(nodes, CategoryNode{
+			ID:       c.ID,
+			Name:     c.Name,
+			ParentID: c.ParentID,
+			Children: buildCategoryTree(categories, &c.ID),
+		})
+	}
+	return nodes
+}
+
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// CreateCategoryHandler создаёт раздел каталога. Право на изменение
+// дерева проверяет маршрутизатор.
+func (s *Server) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	if err := s.validateCategoryRequest(r, &req, nil); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	category := models.Category{Name: req.Name, ParentID: req.ParentID}
+	if err := s.Categories.Create(r.Context(), &category); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при создании раздела")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, category)
+}
+
+// UpdateCategoryHandler переименовывает или переносит раздел.
+func (s *Server) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := s.categoryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	if err := s.validateCategoryRequest(r, &req, category); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	category.Name = req.Name
+	category.ParentID = req.ParentID
+	if err := s.Categories.Update(r.Context(), category); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при обновлении раздела")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, category)
+}
+
+// DeleteCategoryHandler удаляет пустой раздел без подразделов и объявлений.
+func (s *Server) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := s.categoryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	descendants, err := s.Categories.DescendantIDs(r.Context(), category.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при проверке дерева разделов")
+		return
+	}
+	if len(descendants) > 1 {
+		utils.WriteJSONError(w, http.StatusConflict, "в разделе есть подразделы")
+		return
+	}
+
+	ads, err := s.Ads.List(r.Context(), repository.AdFilter{CategoryIDs: descendants, Limit: 1})
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при проверке объявлений раздела")
+		return
+	}
+	if len(ads) > 0 {
+		utils.WriteJSONError(w, http.StatusConflict, "в разделе есть объявления")
+		return
+	}
+
+	if err := s.Categories.Delete(r.Context(), category); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при удалении раздела")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// categoryFromPath загружает раздел по ID из пути /categories/{id}.
+func (s *Server) categoryFromPath(w http.ResponseWriter, r *http.Request) (*models.Category, bool) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/categories/")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID раздела")
+		return nil, false
+	}
+
+	category, err := s.Categories.GetByID(r.Context(), uint(id))
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "раздел не найден")
+		return nil, false
+	}
+	return category, true
+}
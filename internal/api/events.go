@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/events"
+	"github.com/WalnutBagel/go-marketplace/internal/middleware"
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+	"github.com/WalnutBagel/go-marketplace/internal/worker"
+)
+
+// EventsHeartbeat — как часто поток событий шлёт комментарий-пинг, чтобы
+// прокси не закрывали простаивающее соединение. Заодно с той же частотой
+// проверяется, не отозвана ли сессия.
+var EventsHeartbeat = 25 * time.Second
+
+// MessageEvent — данные события message.new.
+type MessageEvent struct {
+	ThreadID uint           `json:"thread_id"`
+	AdID     uint           `json:"ad_id"`
+	Message  models.Message `json:"message"`
+}
+
+// AdEvent — данные событий об изменении объявления.
+type AdEvent struct {
+	AdID     uint            `json:"ad_id"`
+	Title    string          `json:"title"`
+	Price    float64         `json:"price"`
+	OldPrice *float64        `json:"old_price,omitempty"`
+	Status   models.AdStatus `json:"status"`
+}
+
+// EventsHandler отдаёт поток событий текущего пользователя в формате
+// Server-Sent Events. Соединение держится, пока клиент не отключится
+// или не будет отозвана его сессия.
+func (s *Server) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.Events == nil {
+		utils.WriteJSONError(w, http.StatusServiceUnavailable, "поток событий недоступен")
+		return
+	}
+
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+	sessionID, _ := middleware.GetSessionID(r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "потоковая передача не поддерживается")
+		return
+	}
+
+	sub := s.Events.Subscribe(user.ID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(EventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case ev, ok := <-sub.C:
+			if !ok {
+				// Клиент не успевал читать события, пусть переподключится
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, ev.Data); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-heartbeat.C:
+			session, err := s.Sessions.GetSession(r.Context(), sessionID)
+			if err != nil || session.RevokedAt != nil {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// publish отправляет событие, если поток событий настроен. Ошибка доставки
+// не должна ломать запрос, который её вызвал, поэтому она только логируется.
+func (s *Server) publish(ctx context.Context, eventType string, data any, userIDs ...uint) {
+	if s.Events == nil {
+		return
+	}
+	if err := s.Events.Publish(ctx, eventType, data, userIDs...); err != nil {
+		log.Printf("events: не удалось отправить %s: %v", eventType, err)
+	}
+}
+
+// DeliverFavoriteUpdate отправляет подписчикам событие об изменении
+// объявления ad и записывает им уведомление favorite_updated.
+// Используется worker.FavoritesNotifier.
+func (s *Server) DeliverFavoriteUpdate(ctx context.Context, ad worker.AdEventData, userIDs []uint) {
+	eventType := events.TypeFavoriteUpdated
+	switch {
+	case ad.Status == models.AdStatusSold:
+		eventType = events.TypeAdSold
+	case ad.OldPrice != nil && *ad.OldPrice != ad.Price:
+		eventType = events.TypeAdPriceChanged
+	}
+
+	event := AdEvent{AdID: ad.ID, Title: ad.Title, Price: ad.Price, OldPrice: ad.OldPrice, Status: ad.Status}
+	s.publish(ctx, eventType, event, userIDs...)
+	s.notify(ctx, models.NotificationFavoriteUpdated, event, userIDs...)
+}
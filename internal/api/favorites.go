@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// favoriteVisibleStatuses — состояния, в которых объявление остаётся в списке
+// избранного. Черновики и архив владелец скрыл, поэтому их не показываем.
+var favoriteVisibleStatuses = []models.AdStatus{
+	models.AdStatusPublished,
+	models.AdStatusReserved,
+	models.AdStatusSold,
+	models.AdStatusExpired,
+}
+
+// AddFavoriteHandler добавляет объявление в избранное. Повторное добавление не ошибка.
+func (s *Server) AddFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil || (ad.Status != models.AdStatusPublished && ad.Status != models.AdStatusReserved) || !adAvailable(ad) {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return
+	}
+	if ad.UserID == user.ID {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя добавить в избранное своё объявление")
+		return
+	}
+
+	err = s.Favorites.Add(r.Context(), user.ID, ad.ID)
+	if errors.Is(err, repository.ErrDuplicate) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при добавлении в избранное")
+		return
+	}
+
+	payload := AdFavoritedPayload{AdID: ad.ID, Title: ad.Title}
+	if counts, err := s.Favorites.CountByAds(r.Context(), []uint{ad.ID}); err == nil {
+		payload.FavoritesCount = counts[ad.ID]
+	}
+	s.notify(r.Context(), models.NotificationAdFavorited, payload, ad.UserID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveFavoriteHandler убирает объявление из избранного. Отсутствие в избранном не ошибка.
+func (s *Server) RemoveFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	err = s.Favorites.Remove(r.Context(), user.ID, adID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при удалении из избранного")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListFavoritesHandler возвращает избранные объявления текущего пользователя,
+// недавно добавленные первыми.
+func (s *Server) ListFavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	ads, err := s.Favorites.ListAds(r.Context(), user.ID, favoriteVisibleStatuses, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении избранного")
+		return
+	}
+
+	items, err := s.feedItems(r, ads, user, "")
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении избранного")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, items)
+}
@@ -2,23 +2,30 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
-	"github.com/WalnutBagel/go-marketplace/internal/db"
+	"github.com/WalnutBagel/go-marketplace/internal/mail"
 	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
 	"github.com/WalnutBagel/go-marketplace/internal/services"
 	"github.com/WalnutBagel/go-marketplace/internal/utils"
 )
 
 // --- STRUCTS ---
 
+// RegisterRequest — данные регистрации. Email необязателен, пока не
+// включено обязательное подтверждение почты.
 type RegisterRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email"`
 }
 
 type LoginRequest struct {
@@ -26,9 +33,20 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse возвращается при входе и обновлении токенов.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
 // --- HANDLERS ---
 
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteJSONError(w, http.StatusBadRequest, "Невалидный JSON")
@@ -38,40 +56,88 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	req.Username = strings.TrimSpace(req.Username)
 	req.Password = strings.TrimSpace(req.Password)
 
+	req.Email = normalizeEmail(req.Email)
+
 	if err := validateCredentials(req.Username, req.Password); err != nil {
 		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if req.Email == "" && s.RequireEmailVerification {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Адрес почты обязателен")
+		return
+	}
+	if req.Email != "" && !mail.ValidAddress(req.Email) {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Неверный адрес почты")
+		return
+	}
 
-	var existing models.User
-	if err := db.GetDB().Where("username = ?", req.Username).First(&existing).Error; err == nil {
+	if req.Email != "" && !s.allowMailRequest(w, r, req.Email) {
+		return
+	}
+
+	if _, err := s.Users.GetByUsername(r.Context(), req.Username); err == nil {
 		utils.WriteJSONError(w, http.StatusConflict, "Пользователь с таким логином уже существует")
 		return
 	}
 
+	// Хэшируем до проверки почты, чтобы время ответа не выдавало занятый адрес
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка при хэшировании пароля")
 		return
 	}
 
+	if req.Email != "" {
+		if existing, err := s.Users.GetByEmail(r.Context(), req.Email); err == nil {
+			// Ответ тот же, что и при успехе: владелец адреса узнает о попытке из письма
+			s.sendAccountExistsEmail(existing)
+			writeRegistered(w, &models.User{Username: req.Username, Email: req.Email})
+			return
+		}
+	}
+
 	user := models.User{
 		Username: req.Username,
 		Password: string(hashedPassword),
+		Email:    req.Email,
+		Role:     models.RoleUser,
 	}
 
-	if err := db.GetDB().Create(&user).Error; err != nil {
+	if err := s.Users.Create(r.Context(), &user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			utils.WriteJSONError(w, http.StatusConflict, "Пользователь с таким логином уже существует")
+			return
+		}
 		utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка при сохранении пользователя")
 		return
 	}
 
+	if err := s.sendVerificationEmail(r.Context(), &user); err != nil {
+		// Письмо можно запросить повторно, регистрация от этого не откатывается
+		log.Printf("Ошибка отправки письма подтверждения пользователю %d: %v", user.ID, err)
+	}
+
+	writeRegistered(w, &user)
+}
+
+// writeRegistered отвечает на успешную регистрацию. Если указана почта,
+// ответ 202 без id: он не должен отличаться от ответа на занятый адрес.
+func writeRegistered(w http.ResponseWriter, user *models.User) {
+	if user.Email != "" {
+		utils.WriteJSON(w, http.StatusAccepted, map[string]any{
+			"username": user.Username,
+			"email":    user.Email,
+		})
+		return
+	}
 	utils.WriteJSON(w, http.StatusCreated, map[string]any{
 		"id":       user.ID,
 		"username": user.Username,
+		"email":    user.Email,
 	})
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteJSONError(w, http.StatusBadRequest, "Невалидный JSON")
@@ -86,8 +152,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user models.User
-	err := db.GetDB().Where("username = ?", req.Username).First(&user).Error
+	user, err := s.Users.GetByUsername(r.Context(), req.Username)
 	if err != nil {
 		utils.WriteJSONError(w, http.StatusUnauthorized, "Неверный логин или пароль")
 		return
@@ -98,17 +163,189 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := services.GenerateJWT(user.Username)
+	if !s.checkUserStatus(w, r, user) {
+		return
+	}
+	// Учётные записи без почты созданы до её появления и входят как раньше
+	if s.RequireEmailVerification && user.Email != "" && user.EmailVerifiedAt == nil {
+		utils.WriteJSONError(w, http.StatusForbidden, "Почта не подтверждена, перейдите по ссылке из письма")
+		return
+	}
+
+	sessionID, err := services.NewOpaqueToken(24)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка генерации токена")
+		return
+	}
+
+	session := models.Session{ID: sessionID, UserID: user.ID}
+	if err := s.Sessions.CreateSession(r.Context(), &session); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка создания сессии")
+		return
+	}
+
+	resp, err := s.issueTokens(r, user, session.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка генерации токена")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// RefreshHandler обменивает refresh-токен на новую пару токенов.
+// Каждый refresh-токен одноразовый: повторное предъявление уже использованного
+// токена считается кражей и отзывает всю сессию.
+func (s *Server) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Невалидный JSON")
+		return
+	}
+
+	token, err := s.Sessions.GetRefreshToken(r.Context(), services.HashToken(req.RefreshToken))
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusUnauthorized, "Недействительный refresh-токен")
+		return
+	}
+
+	session, err := s.Sessions.GetSession(r.Context(), token.SessionID)
+	if err != nil || session.RevokedAt != nil {
+		utils.WriteJSONError(w, http.StatusUnauthorized, "Сессия завершена, выполните вход заново")
+		return
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		utils.WriteJSONError(w, http.StatusUnauthorized, "Срок действия refresh-токена истёк")
+		return
+	}
+
+	consumed, err := s.Sessions.ConsumeRefreshToken(r.Context(), token.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка обновления токена")
+		return
+	}
+	if !consumed {
+		if err := s.Sessions.RevokeSession(r.Context(), session.ID); err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка отзыва сессии")
+			return
+		}
+		utils.WriteJSONError(w, http.StatusUnauthorized, "Refresh-токен уже использован, сессия отозвана")
+		return
+	}
+
+	user, err := s.Users.GetByID(r.Context(), token.UserID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusUnauthorized, "Пользователь не найден")
+		return
+	}
+	if !s.checkUserStatus(w, r, user) {
+		return
+	}
+
+	resp, err := s.issueTokens(r, user, session.ID)
 	if err != nil {
 		utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка генерации токена")
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// LogoutHandler отзывает сессию, к которой относится refresh-токен.
+func (s *Server) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Невалидный JSON")
+		return
+	}
+
+	token, err := s.Sessions.GetRefreshToken(r.Context(), services.HashToken(req.RefreshToken))
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusUnauthorized, "Недействительный refresh-токен")
+		return
+	}
+
+	if err := s.Sessions.RevokeSession(r.Context(), token.SessionID); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Ошибка отзыва сессии")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // --- HELPERS ---
 
+// checkUserStatus не даёт войти заблокированному пользователю и пишет
+// в ответ причину и срок блокировки. Истёкшая блокировка снимается.
+func (s *Server) checkUserStatus(w http.ResponseWriter, r *http.Request, user *models.User) bool {
+	now := time.Now()
+	status := user.StatusAt(now)
+	if status == models.UserActive {
+		if user.Status != models.UserActive {
+			user.Status, user.StatusUntil, user.StatusReason = models.UserActive, nil, ""
+			if err := s.Users.ClearExpiredStatus(r.Context(), user.ID, now); err != nil {
+				utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при снятии блокировки")
+				return false
+			}
+		}
+		return true
+	}
+
+	msg := "учётная запись заблокирована"
+	if user.StatusUntil != nil {
+		msg += " до " + user.StatusUntil.Format("02.01.2006 15:04")
+	}
+	if user.StatusReason != "" {
+		msg += ": " + user.StatusReason
+	}
+	utils.WriteJSONError(w, http.StatusForbidden, msg)
+	return false
+}
+
+// issueTokens выпускает access-токен и новый refresh-токен в рамках сессии.
+func (s *Server) issueTokens(r *http.Request, user *models.User, sessionID string) (*TokenResponse, error) {
+	perms := make([]string, 0, len(user.Role.Permissions()))
+	for _, p := range user.Role.Permissions() {
+		perms = append(perms, string(p))
+	}
+	access, err := services.GenerateJWT(user.Username, sessionID, string(user.Role), perms)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := services.NewOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Sessions.CreateRefreshToken(r.Context(), &models.RefreshToken{
+		SessionID: sessionID,
+		UserID:    user.ID,
+		TokenHash: services.HashToken(refresh),
+		ExpiresAt: time.Now().Add(services.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(services.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
 func validateCredentials(username, password string) error {
 	if len(username) < 3 || len(username) > 30 {
 		return fmt.Errorf("логин должен быть от 3 до 30 символов")
@@ -116,6 +353,10 @@ func validateCredentials(username, password string) error {
 	if strings.Contains(username, " ") {
 		return fmt.Errorf("логин не должен содержать пробелы")
 	}
+	return validatePassword(password)
+}
+
+func validatePassword(password string) error {
 	if len(password) < 6 {
 		return fmt.Errorf("пароль должен быть не менее 6 символов")
 	}
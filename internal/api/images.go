@@ -0,0 +1,328 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/WalnutBagel/go-marketplace/internal/imaging"
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/services"
+	"github.com/WalnutBagel/go-marketplace/internal/storage"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+const (
+	// MaxImageSize — максимальный размер одной картинки.
+	MaxImageSize = 5 << 20
+	// MaxImagesPerAd — максимальное число картинок у одного объявления.
+	MaxImagesPerAd = 10
+)
+
+// allowedImageTypes сопоставляет допустимые типы содержимого и расширения файлов.
+// Тип определяется по содержимому файла, а не по заголовкам клиента.
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ImageResponse описывает картинку объявления в ответе.
+// Адреса уменьшенных копий появляются после фоновой обработки.
+type ImageResponse struct {
+	ID           uint   `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	MediumURL    string `json:"medium_url,omitempty"`
+	Position     int    `json:"position"`
+}
+
+// ImageProcessor ставит картинку в очередь на создание уменьшенных копий.
+type ImageProcessor interface {
+	Enqueue(imageID uint)
+}
+
+// ReorderImagesRequest задаёт новый порядок картинок списком их ID.
+type ReorderImagesRequest struct {
+	Order []uint `json:"order"`
+}
+
+// imageURL возвращает публичный адрес объекта хранилища.
+func imageURL(key string) string {
+	return "/images/" + key
+}
+
+func toImageResponses(images []models.AdImage) []ImageResponse {
+	resp := make([]ImageResponse, len(images))
+	for i, img := range images {
+		resp[i] = ImageResponse{ID: img.ID, URL: imageURL(img.Key), Position: img.Position}
+		if img.ThumbKey != "" {
+			resp[i].ThumbnailURL = imageURL(img.ThumbKey)
+		}
+		if img.MediumKey != "" {
+			resp[i].MediumURL = imageURL(img.MediumKey)
+		}
+	}
+	return resp
+}
+
+// adImages загружает картинки объявления для ответа.
+func (s *Server) adImages(r *http.Request, adID uint) []ImageResponse {
+	images, err := s.Images.ListByAd(r.Context(), adID)
+	if err != nil {
+		log.Printf("Ошибка загрузки картинок объявления %d: %v", adID, err)
+	}
+	return toImageResponses(images)
+}
+
+// UploadAdImageHandler принимает картинку объявления в поле формы "image".
+func (s *Server) UploadAdImageHandler(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.loadOwnedAd(w, r, "нет прав для изменения объявления")
+	if !ok {
+		return
+	}
+
+	// Предварительная проверка избавляет от чтения файла, который всё равно
+	// не поместится; окончательно лимит проверяет Images.Append
+	existing, err := s.Images.ListByAd(r.Context(), ad.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при загрузке картинок")
+		return
+	}
+	if len(existing) >= MaxImagesPerAd {
+		writeImageLimitError(w)
+		return
+	}
+
+	// Запас в 1 МБ оставлен под служебные части multipart-формы
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImageSize+1<<20)
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.WriteJSONError(w, http.StatusRequestEntityTooLarge, "файл слишком большой")
+			return
+		}
+		utils.WriteJSONError(w, http.StatusBadRequest, "ожидается файл в поле image")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > MaxImageSize {
+		utils.WriteJSONError(w, http.StatusRequestEntityTooLarge, "файл слишком большой")
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "не удалось прочитать файл")
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		utils.WriteJSONError(w, http.StatusUnsupportedMediaType, "поддерживаются только JPEG, PNG, GIF и WebP")
+		return
+	}
+
+	// Геометка и прочие метаданные не должны попасть в публичный файл
+	data, err = imaging.StripMetadata(data, contentType)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		utils.WriteJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "не удалось обработать картинку")
+		return
+	}
+
+	name, err := services.NewOpaqueToken(16)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении файла")
+		return
+	}
+	key := fmt.Sprintf("ads/%d/%s%s", ad.ID, name, ext)
+
+	if err := s.Storage.Put(r.Context(), key, bytes.NewReader(data), contentType); err != nil {
+		log.Printf("Ошибка записи в хранилище: %v", err)
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении файла")
+		return
+	}
+
+	image := models.AdImage{
+		AdID:        ad.ID,
+		Key:         key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	if err := s.Images.Append(r.Context(), &image, MaxImagesPerAd); err != nil {
+		s.Storage.Delete(r.Context(), key)
+		if errors.Is(err, repository.ErrConflict) {
+			writeImageLimitError(w)
+			return
+		}
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении файла")
+		return
+	}
+
+	if s.Thumbnails != nil {
+		s.Thumbnails.Enqueue(image.ID)
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, toImageResponses([]models.AdImage{image})[0])
+}
+
+func writeImageLimitError(w http.ResponseWriter) {
+	utils.WriteJSONError(w, http.StatusConflict, fmt.Sprintf("у объявления может быть не больше %d картинок", MaxImagesPerAd))
+}
+
+// ReorderAdImagesHandler меняет порядок картинок объявления.
+// В запросе должны быть перечислены все картинки объявления.
+func (s *Server) ReorderAdImagesHandler(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.loadOwnedAd(w, r, "нет прав для изменения объявления")
+	if !ok {
+		return
+	}
+
+	var req ReorderImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	images, err := s.Images.ListByAd(r.Context(), ad.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при загрузке картинок")
+		return
+	}
+
+	byID := make(map[uint]models.AdImage, len(images))
+	for _, img := range images {
+		byID[img.ID] = img
+	}
+	if len(req.Order) != len(images) {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нужно перечислить все картинки объявления")
+		return
+	}
+
+	reordered := make([]models.AdImage, 0, len(images))
+	for position, id := range req.Order {
+		img, ok := byID[id]
+		if !ok {
+			utils.WriteJSONError(w, http.StatusBadRequest, "нужно перечислить все картинки объявления")
+			return
+		}
+		delete(byID, id)
+		img.Position = position
+		reordered = append(reordered, img)
+	}
+
+	for _, img := range reordered {
+		if err := s.Images.UpdatePosition(r.Context(), img.ID, img.Position); err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении порядка")
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, toImageResponses(reordered))
+}
+
+// DeleteAdImageHandler удаляет картинку объявления вместе с файлом.
+func (s *Server) DeleteAdImageHandler(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.loadOwnedAd(w, r, "нет прав для изменения объявления")
+	if !ok {
+		return
+	}
+
+	imageID, err := pathID(r, 3)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID картинки")
+		return
+	}
+
+	image, err := s.Images.GetByID(r.Context(), imageID)
+	if err != nil || image.AdID != ad.ID {
+		utils.WriteJSONError(w, http.StatusNotFound, "картинка не найдена")
+		return
+	}
+
+	if err := s.Images.Delete(r.Context(), image); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при удалении картинки")
+		return
+	}
+	for _, key := range []string{image.Key, image.ThumbKey, image.MediumKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.Storage.Delete(r.Context(), key); err != nil {
+			log.Printf("Ошибка удаления %s из хранилища: %v", key, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeImageHandler отдаёт файл из хранилища по пути /images/{key}.
+// Картинки удалённых, скрытых модерацией объявлений и объявлений
+// забаненных продавцов не отдаются, как и сами объявления в ленте.
+func (s *Server) ServeImageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/images/")
+	// Ключ картинки — ads/{adID}/{имя}
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 || parts[0] != "ads" {
+		http.NotFound(w, r)
+		return
+	}
+	adID, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	ad, err := s.Ads.GetByID(r.Context(), uint(adID))
+	if err != nil || !adAvailable(ad) {
+		http.NotFound(w, r)
+		return
+	}
+
+	obj, err := s.Storage.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка чтения %s из хранилища: %v", key, err)
+		http.Error(w, "Ошибка чтения файла", http.StatusInternalServerError)
+		return
+	}
+	defer obj.Body.Close()
+
+	if obj.ContentType != "" {
+		w.Header().Set("Content-Type", obj.ContentType)
+	}
+	if obj.Size > 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(obj.Size))
+	}
+	// Содержимое по ключу не меняется, но объявление могут скрыть, поэтому
+	// кэш живёт час, а не вечно
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if r.Method == http.MethodHead {
+		return
+	}
+	io.Copy(w, obj.Body)
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/WalnutBagel/go-marketplace/internal/events"
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// MaxMessageLength — максимальная длина сообщения в символах.
+const MaxMessageLength = 2000
+
+// MessageRequest — тело нового сообщения, в том числе первого в переписке.
+type MessageRequest struct {
+	Body string `json:"body"`
+}
+
+// ThreadResponse — переписка глазами текущего пользователя.
+type ThreadResponse struct {
+	ID            uint         `json:"id"`
+	AdID          uint         `json:"ad_id"`
+	Buyer         UserResponse `json:"buyer"`
+	Seller        UserResponse `json:"seller"`
+	UnreadCount   int64        `json:"unread_count"`
+	LastMessageAt time.Time    `json:"last_message_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+func toThreadResponse(t *models.Thread, unread int64) ThreadResponse {
+	return ThreadResponse{
+		ID:            t.ID,
+		AdID:          t.AdID,
+		Buyer:         toUserResponse(&t.Buyer),
+		Seller:        toUserResponse(&t.Seller),
+		UnreadCount:   unread,
+		LastMessageAt: t.LastMessageAt,
+		CreatedAt:     t.CreatedAt,
+	}
+}
+
+// decodeMessage читает и проверяет тело сообщения. При ошибке ответ уже записан.
+func decodeMessage(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req MessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный формат запроса")
+		return "", false
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" || utf8.RuneCountInString(body) > MaxMessageLength {
+		utils.WriteJSONError(w, http.StatusBadRequest, "сообщение должно содержать от 1 до 2000 символов")
+		return "", false
+	}
+	return body, true
+}
+
+// StartThreadHandler начинает переписку с продавцом по объявлению и отправляет
+// первое сообщение. Если переписка уже есть, сообщение добавляется в неё.
+func (s *Server) StartThreadHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	body, ok := decodeMessage(w, r)
+	if !ok {
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil || (ad.Status != models.AdStatusPublished && ad.Status != models.AdStatusReserved) || !adAvailable(ad) {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return
+	}
+	if ad.UserID == user.ID {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя написать самому себе")
+		return
+	}
+
+	thread, created, err := s.findOrCreateThread(r, ad, user)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при создании переписки")
+		return
+	}
+
+	message := &models.Message{ThreadID: thread.ID, SenderID: user.ID, Body: body, CreatedAt: time.Now()}
+	if err := s.Threads.AddMessage(r.Context(), message); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при отправке сообщения")
+		return
+	}
+	thread.LastMessageAt = message.CreatedAt
+	s.publishMessage(r, thread, message)
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	utils.WriteJSON(w, status, toThreadResponse(thread, 0))
+}
+
+// findOrCreateThread возвращает переписку покупателя по объявлению, создавая её
+// при необходимости. Параллельное создание разрешается уникальным индексом.
+func (s *Server) findOrCreateThread(r *http.Request, ad *models.Ad, buyer *models.User) (*models.Thread, bool, error) {
+	thread, err := s.Threads.FindThread(r.Context(), ad.ID, buyer.ID)
+	if err == nil {
+		return thread, false, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	thread = &models.Thread{
+		AdID:          ad.ID,
+		BuyerID:       buyer.ID,
+		SellerID:      ad.UserID,
+		LastMessageAt: now,
+		CreatedAt:     now,
+	}
+	err = s.Threads.CreateThread(r.Context(), thread)
+	if errors.Is(err, repository.ErrDuplicate) {
+		thread, err = s.Threads.FindThread(r.Context(), ad.ID, buyer.ID)
+		return thread, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	thread.Buyer = *buyer
+	thread.Seller = ad.User
+	return thread, true, nil
+}
+
+// ListThreadsHandler возвращает переписки текущего пользователя с числом непрочитанных.
+func (s *Server) ListThreadsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	summaries, err := s.Threads.ListThreads(r.Context(), user.ID, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении переписок")
+		return
+	}
+
+	resp := make([]ThreadResponse, len(summaries))
+	for i := range summaries {
+		resp[i] = toThreadResponse(&summaries[i].Thread, summaries[i].Unread)
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// ListMessagesHandler возвращает сообщения переписки в порядке отправки.
+// Параметр after позволяет дочитать только новые сообщения.
+func (s *Server) ListMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	thread, _, ok := s.loadThread(w, r)
+	if !ok {
+		return
+	}
+
+	var afterID uint
+	if a := r.URL.Query().Get("after"); a != "" {
+		val, err := strconv.ParseUint(a, 10, 64)
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, "невалидный параметр after")
+			return
+		}
+		afterID = uint(val)
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		val, err := strconv.Atoi(l)
+		if err != nil || val <= 0 || val > 100 {
+			utils.WriteJSONError(w, http.StatusBadRequest, "невалидный параметр limit")
+			return
+		}
+		limit = val
+	}
+
+	messages, err := s.Threads.ListMessages(r.Context(), thread.ID, afterID, limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении сообщений")
+		return
+	}
+	if messages == nil {
+		messages = []models.Message{}
+	}
+	utils.WriteJSON(w, http.StatusOK, messages)
+}
+
+// PostMessageHandler добавляет сообщение в переписку.
+func (s *Server) PostMessageHandler(w http.ResponseWriter, r *http.Request) {
+	thread, user, ok := s.loadThread(w, r)
+	if !ok {
+		return
+	}
+
+	body, ok := decodeMessage(w, r)
+	if !ok {
+		return
+	}
+
+	message := &models.Message{ThreadID: thread.ID, SenderID: user.ID, Body: body, CreatedAt: time.Now()}
+	if err := s.Threads.AddMessage(r.Context(), message); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при отправке сообщения")
+		return
+	}
+	s.publishMessage(r, thread, message)
+	utils.WriteJSON(w, http.StatusCreated, message)
+}
+
+// publishMessage рассылает новое сообщение обоим участникам переписки,
+// чтобы оно появилось и на других устройствах отправителя.
+func (s *Server) publishMessage(r *http.Request, thread *models.Thread, message *models.Message) {
+	s.publish(r.Context(), events.TypeMessageNew, MessageEvent{
+		ThreadID: thread.ID,
+		AdID:     thread.AdID,
+		Message:  *message,
+	}, thread.BuyerID, thread.SellerID)
+}
+
+// MarkThreadReadHandler отмечает все сообщения переписки прочитанными.
+func (s *Server) MarkThreadReadHandler(w http.ResponseWriter, r *http.Request) {
+	thread, user, ok := s.loadThread(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.Threads.MarkRead(r.Context(), thread.ID, user.ID); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при обновлении переписки")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadThread загружает переписку из пути /threads/{id}/... и проверяет, что
+// текущий пользователь — её участник. При отказе ответ уже записан.
+func (s *Server) loadThread(w http.ResponseWriter, r *http.Request) (*models.Thread, *models.User, bool) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	threadID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID переписки")
+		return nil, nil, false
+	}
+
+	thread, err := s.Threads.GetThread(r.Context(), threadID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "переписка не найдена")
+		return nil, nil, false
+	}
+	if !thread.HasParticipant(user.ID) {
+		utils.WriteJSONError(w, http.StatusForbidden, "нет доступа к переписке")
+		return nil, nil, false
+	}
+	return thread, user, true
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// ModerationActionResponse — запись журнала модерации.
+type ModerationActionResponse struct {
+	ID           uint         `json:"id"`
+	Moderator    UserResponse `json:"moderator"`
+	Action       string       `json:"action"`
+	AdID         uint         `json:"ad_id"`
+	TargetUserID uint         `json:"target_user_id"`
+	Note         string       `json:"note"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// recordModeration записывает действие модератора над объявлением в журнал.
+// Вызывается в той же транзакции, что и само действие.
+func (s *Server) recordModeration(ctx context.Context, moderator *models.User, action string, ad *models.Ad, note string) (*models.ModerationAction, error) {
+	record := &models.ModerationAction{
+		ModeratorID:  moderator.ID,
+		Action:       action,
+		AdID:         ad.ID,
+		TargetUserID: ad.UserID,
+		Note:         note,
+	}
+	if err := s.Moderation.Record(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func toModerationActionResponse(a *models.ModerationAction) ModerationActionResponse {
+	return ModerationActionResponse{
+		ID:           a.ID,
+		Moderator:    toUserResponse(&a.Moderator),
+		Action:       a.Action,
+		AdID:         a.AdID,
+		TargetUserID: a.TargetUserID,
+		Note:         a.Note,
+		CreatedAt:    a.CreatedAt,
+	}
+}
+
+// ListModerationActionsHandler возвращает журнал модерации, новые записи
+// первыми. Параметр ad_id оставляет действия над одним объявлением.
+func (s *Server) ListModerationActionsHandler(w http.ResponseWriter, r *http.Request) {
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	var adID uint
+	if v := r.URL.Query().Get("ad_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, "невалидный параметр ad_id")
+			return
+		}
+		adID = uint(id)
+	}
+
+	actions, err := s.Moderation.List(r.Context(), adID, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении журнала модерации")
+		return
+	}
+
+	resp := make([]ModerationActionResponse, len(actions))
+	for i := range actions {
+		resp[i] = toModerationActionResponse(&actions[i])
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/events"
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// NotificationResponse — уведомление пользователя. Формат Payload зависит от Type.
+type NotificationResponse struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// UnreadCountResponse — число непрочитанных уведомлений.
+type UnreadCountResponse struct {
+	Unread int64 `json:"unread"`
+}
+
+// AdFavoritedPayload — уведомление продавцу о том, что его объявление добавили в избранное.
+type AdFavoritedPayload struct {
+	AdID           uint   `json:"ad_id"`
+	Title          string `json:"title"`
+	FavoritesCount int64  `json:"favorites_count"`
+}
+
+// AdModeratedPayload — уведомление владельцу о действии модератора
+// с его объявлением. Note — пояснение модератора.
+type AdModeratedPayload struct {
+	AdID  uint   `json:"ad_id"`
+	Title string `json:"title"`
+	Note  string `json:"note,omitempty"`
+}
+
+func toNotificationResponse(n *models.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:        n.ID,
+		Type:      n.Type,
+		Payload:   json.RawMessage(n.Payload),
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+// ListNotificationsHandler возвращает уведомления текущего пользователя, новые первыми.
+// С параметром unread=true — только непрочитанные.
+func (s *Server) ListNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	var unreadOnly bool
+	if u := r.URL.Query().Get("unread"); u != "" {
+		val, err := strconv.ParseBool(u)
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, "невалидный параметр unread")
+			return
+		}
+		unreadOnly = val
+	}
+
+	notifications, err := s.Notifications.ListByUser(r.Context(), user.ID, unreadOnly, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении уведомлений")
+		return
+	}
+
+	resp := make([]NotificationResponse, len(notifications))
+	for i := range notifications {
+		resp[i] = toNotificationResponse(&notifications[i])
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// UnreadCountHandler возвращает число непрочитанных уведомлений.
+func (s *Server) UnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := s.Notifications.CountUnread(r.Context(), user.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении уведомлений")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, UnreadCountResponse{Unread: n})
+}
+
+// MarkNotificationReadHandler отмечает прочитанным уведомление из пути
+// /me/notifications/{id}/read. Повторная отметка не ошибка.
+func (s *Server) MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := pathID(r, 2)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID уведомления")
+		return
+	}
+
+	err = s.Notifications.MarkRead(r.Context(), user.ID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteJSONError(w, http.StatusNotFound, "уведомление не найдено")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при обновлении уведомления")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllNotificationsReadHandler отмечает прочитанными все уведомления пользователя.
+func (s *Server) MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := s.Notifications.MarkAllRead(r.Context(), user.ID); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при обновлении уведомлений")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notify записывает уведомление каждому из пользователей и сразу отправляет
+// его в поток событий. Как и publish, ошибки только логируются: уведомление
+// не должно ломать действие, которое его вызвало.
+func (s *Server) notify(ctx context.Context, notificationType string, payload any, userIDs ...uint) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("notifications: не удалось сериализовать %s: %v", notificationType, err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		n := &models.Notification{UserID: userID, Type: notificationType, Payload: string(raw)}
+		if err := s.Notifications.Create(ctx, n); err != nil {
+			log.Printf("notifications: не удалось создать %s для пользователя %d: %v", notificationType, userID, err)
+			continue
+		}
+		s.publish(ctx, events.TypeNotificationNew, toNotificationResponse(n), userID)
+	}
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+	"github.com/WalnutBagel/go-marketplace/internal/worker"
+)
+
+// OfferRequest — сумма предложения или встречного предложения.
+type OfferRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+// OfferResponse — предложение цены.
+type OfferResponse struct {
+	ID         uint               `json:"id"`
+	AdID       uint               `json:"ad_id"`
+	Buyer      UserResponse       `json:"buyer"`
+	SellerID   uint               `json:"seller_id"`
+	Amount     float64            `json:"amount"`
+	ProposedBy uint               `json:"proposed_by"`
+	Status     models.OfferStatus `json:"status"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// OfferPayload — уведомление об изменении торга.
+type OfferPayload struct {
+	OfferID uint    `json:"offer_id"`
+	AdID    uint    `json:"ad_id"`
+	Title   string  `json:"title"`
+	Amount  float64 `json:"amount"`
+}
+
+func toOfferResponse(o *models.Offer) OfferResponse {
+	return OfferResponse{
+		ID:         o.ID,
+		AdID:       o.AdID,
+		Buyer:      toUserResponse(&o.Buyer),
+		SellerID:   o.SellerID,
+		Amount:     o.Amount,
+		ProposedBy: o.ProposedBy,
+		Status:     o.Status,
+		ExpiresAt:  o.ExpiresAt,
+		CreatedAt:  o.CreatedAt,
+		UpdatedAt:  o.UpdatedAt,
+	}
+}
+
+func toOfferResponses(offers []models.Offer) []OfferResponse {
+	resp := make([]OfferResponse, len(offers))
+	for i := range offers {
+		resp[i] = toOfferResponse(&offers[i])
+	}
+	return resp
+}
+
+// decodeOffer читает и проверяет сумму из тела запроса. При ошибке ответ уже записан.
+func decodeOffer(w http.ResponseWriter, r *http.Request) (float64, bool) {
+	var req OfferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return 0, false
+	}
+	if req.Amount <= 0 {
+		utils.WriteJSONError(w, http.StatusBadRequest, "сумма должна быть больше нуля")
+		return 0, false
+	}
+	return req.Amount, true
+}
+
+// CreateOfferHandler создаёт предложение цены покупателя на опубликованное объявление.
+func (s *Server) CreateOfferHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	amount, ok := decodeOffer(w, r)
+	if !ok {
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil || ad.Status != models.AdStatusPublished || !adAvailable(ad) {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return
+	}
+	if ad.UserID == user.ID {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя предложить цену за своё объявление")
+		return
+	}
+	if ad.ListingType == models.ListingAuction {
+		utils.WriteJSONError(w, http.StatusBadRequest, "на аукционе цену определяют ставки")
+		return
+	}
+
+	offer := models.Offer{
+		AdID:       ad.ID,
+		BuyerID:    user.ID,
+		Buyer:      *user,
+		SellerID:   ad.UserID,
+		Amount:     amount,
+		ProposedBy: user.ID,
+		Status:     models.OfferPending,
+		ExpiresAt:  s.newOfferExpiry(),
+	}
+	err = s.Offers.Create(r.Context(), &offer)
+	if errors.Is(err, repository.ErrDuplicate) {
+		utils.WriteJSONError(w, http.StatusConflict, "у вас уже есть открытое предложение на это объявление")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при создании предложения")
+		return
+	}
+
+	s.notify(r.Context(), models.NotificationOfferReceived, offerPayload(&offer, ad), ad.UserID)
+	utils.WriteJSON(w, http.StatusCreated, toOfferResponse(&offer))
+}
+
+// ListAdOffersHandler возвращает предложения на объявление: продавцу — все,
+// покупателю — только его собственные.
+func (s *Server) ListAdOffersHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil || (ad.UserID != user.ID && !adAvailable(ad)) {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return
+	}
+
+	var buyerID *uint
+	if ad.UserID != user.ID {
+		buyerID = &user.ID
+	}
+	offers, err := s.Offers.ListByAd(r.Context(), ad.ID, buyerID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении предложений")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, toOfferResponses(offers))
+}
+
+// ListMyOffersHandler возвращает предложения, сделанные текущим пользователем, новые первыми.
+func (s *Server) ListMyOffersHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	offers, err := s.Offers.ListByBuyer(r.Context(), user.ID, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении предложений")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, toOfferResponses(offers))
+}
+
+// OfferActionHandler обрабатывает /offers/{id}/{action}: accept, reject
+// и counter — ответ другой стороны торга, withdraw — отзыв предложения покупателем.
+func (s *Server) OfferActionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID предложения")
+		return
+	}
+
+	offer, err := s.Offers.GetByID(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !offer.HasParticipant(user.ID)) {
+		utils.WriteJSONError(w, http.StatusNotFound, "предложение не найдено")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении предложения")
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), offer.AdID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return
+	}
+
+	action := strings.Trim(r.URL.Path, "/")
+	action = action[strings.LastIndex(action, "/")+1:]
+
+	if action == "withdraw" {
+		if offer.BuyerID != user.ID || offer.Status != models.OfferPending {
+			utils.WriteJSONError(w, http.StatusConflict, "отозвать можно только своё открытое предложение")
+			return
+		}
+		offer.Status = models.OfferWithdrawn
+		if !s.saveOffer(w, r, offer) {
+			return
+		}
+		s.notify(r.Context(), models.NotificationOfferWithdrawn, offerPayload(offer, ad), offer.SellerID)
+		utils.WriteJSON(w, http.StatusOK, toOfferResponse(offer))
+		return
+	}
+
+	if !offer.AwaitsResponseFrom(user.ID, time.Now()) {
+		utils.WriteJSONError(w, http.StatusConflict, "предложение не ждёт вашего ответа")
+		return
+	}
+	// По скрытому объявлению торг можно только прекратить
+	if action != "reject" && !adAvailable(ad) {
+		utils.WriteJSONError(w, http.StatusConflict, "объявление недоступно")
+		return
+	}
+	other := offer.ProposedBy
+
+	switch action {
+	case "accept":
+		s.acceptOffer(w, r, offer, ad, other)
+
+	case "reject":
+		offer.Status = models.OfferRejected
+		if !s.saveOffer(w, r, offer) {
+			return
+		}
+		s.notify(r.Context(), models.NotificationOfferRejected, offerPayload(offer, ad), other)
+		utils.WriteJSON(w, http.StatusOK, toOfferResponse(offer))
+
+	case "counter":
+		amount, ok := decodeOffer(w, r)
+		if !ok {
+			return
+		}
+		if ad.Status != models.AdStatusPublished {
+			utils.WriteJSONError(w, http.StatusConflict, "объявление больше не продаётся")
+			return
+		}
+		offer.Amount = amount
+		offer.ProposedBy = user.ID
+		offer.ExpiresAt = s.newOfferExpiry()
+		if !s.saveOffer(w, r, offer) {
+			return
+		}
+		s.notify(r.Context(), models.NotificationOfferCountered, offerPayload(offer, ad), other)
+		utils.WriteJSON(w, http.StatusOK, toOfferResponse(offer))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// acceptOffer в одной транзакции принимает предложение, резервирует
+// объявление и отклоняет остальные открытые предложения на него. Если
+// объявление успели снять или принять другое предложение, ничего не меняется.
+func (s *Server) acceptOffer(w http.ResponseWriter, r *http.Request, offer *models.Offer, ad *models.Ad, other uint) {
+	var rejected []models.Offer
+	err := s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := s.Ads.UpdateStatus(ctx, ad.ID, models.AdStatusPublished, models.AdStatusReserved, ad.ExpiresAt); err != nil {
+			return err
+		}
+		if err := s.Ads.SetBuyer(ctx, ad.ID, &offer.BuyerID); err != nil {
+			return err
+		}
+		offer.Status = models.OfferAccepted
+		if err := s.Offers.Update(ctx, offer); err != nil {
+			return err
+		}
+
+		var err error
+		if rejected, err = s.Offers.RejectPending(ctx, ad.ID, offer.ID); err != nil {
+			return err
+		}
+
+		reserved := *ad
+		reserved.Status, reserved.BuyerID, reserved.UpdatedAt = models.AdStatusReserved, &offer.BuyerID, time.Now()
+		return s.recordAdEvent(ctx, worker.WebhookAdUpdated, &reserved)
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		utils.WriteJSONError(w, http.StatusConflict, "объявление или предложение изменилось, обновите данные")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при принятии предложения")
+		return
+	}
+
+	ad.Status, ad.BuyerID = models.AdStatusReserved, &offer.BuyerID
+	s.notify(r.Context(), models.NotificationOfferAccepted, offerPayload(offer, ad), other)
+	for i := range rejected {
+		s.notify(r.Context(), models.NotificationOfferRejected, offerPayload(&rejected[i], ad), rejected[i].BuyerID)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, toOfferResponse(offer))
+}
+
+// saveOffer сохраняет предложение с проверкой версии. При ошибке ответ уже записан.
+func (s *Server) saveOffer(w http.ResponseWriter, r *http.Request, offer *models.Offer) bool {
+	err := s.Offers.Update(r.Context(), offer)
+	if errors.Is(err, repository.ErrConflict) {
+		utils.WriteJSONError(w, http.StatusConflict, "предложение изменилось, обновите данные")
+		return false
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении предложения")
+		return false
+	}
+	return true
+}
+
+func offerPayload(offer *models.Offer, ad *models.Ad) OfferPayload {
+	return OfferPayload{OfferID: offer.ID, AdID: ad.ID, Title: ad.Title, Amount: offer.Amount}
+}
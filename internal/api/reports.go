@@ -0,0 +1,359 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// ReportRequest — жалоба на объявление.
+type ReportRequest struct {
+	Reason  models.ReportReason `json:"reason"`
+	Comment string              `json:"comment"`
+}
+
+// ReportResponse — жалоба на объявление.
+type ReportResponse struct {
+	ID        uint                `json:"id"`
+	AdID      uint                `json:"ad_id"`
+	Reporter  *UserResponse       `json:"reporter"` // null — жалоба фильтра содержимого
+	Reason    models.ReportReason `json:"reason"`
+	Comment   string              `json:"comment"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// ModerationQueueItem — объявление с открытыми жалобами.
+type ModerationQueueItem struct {
+	AdID           uint                          `json:"ad_id"`
+	Title          string                        `json:"title"`
+	Status         models.AdStatus               `json:"status"`
+	Hidden         bool                          `json:"hidden"`
+	HiddenReason   models.HiddenReason           `json:"hidden_reason,omitempty"`
+	Owner          UserResponse                  `json:"owner"`
+	Reports        int64                         `json:"reports"`
+	Reasons        map[models.ReportReason]int64 `json:"reasons"`
+	LastReportedAt time.Time                     `json:"last_reported_at"`
+}
+
+// ModerationRequest — решение модератора по объявлению. Note обязательна;
+// SuspendDays нужна только для action=suspend.
+type ModerationRequest struct {
+	Action      string `json:"action"`
+	Note        string `json:"note"`
+	SuspendDays int    `json:"suspend_days"`
+}
+
+// moderationActions сопоставляет действие из запроса и запись журнала.
+var moderationActions = map[string]string{
+	"dismiss": models.ModerationReportsDismissed,
+	"hide":    models.ModerationAdHidden,
+	"remove":  models.ModerationAdRemoved,
+	"warn":    models.ModerationUserWarned,
+	"suspend": models.ModerationUserSuspended,
+}
+
+func toReportResponse(rp *models.Report) ReportResponse {
+	resp := ReportResponse{
+		ID:        rp.ID,
+		AdID:      rp.AdID,
+		Reason:    rp.Reason,
+		Comment:   rp.Comment,
+		CreatedAt: rp.CreatedAt,
+	}
+	if rp.Reporter != nil {
+		reporter := toUserResponse(rp.Reporter)
+		resp.Reporter = &reporter
+	}
+	return resp
+}
+
+func (s *Server) reportHideThreshold() int {
+	if s.ReportHideThreshold <= 0 {
+		return DefaultReportHideThreshold
+	}
+	return s.ReportHideThreshold
+}
+
+// CreateReportHandler принимает жалобу на чужое объявление. Набрав
+// ReportHideThreshold жалоб, объявление скрывается из ленты до решения модератора.
+func (s *Server) CreateReportHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if !req.Reason.Valid() {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неизвестная причина жалобы")
+		return
+	}
+	if utf8.RuneCountInString(req.Comment) > 500 {
+		utils.WriteJSONError(w, http.StatusBadRequest, "комментарий к жалобе слишком длинный")
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return
+	}
+	if ad.UserID == user.ID {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя пожаловаться на своё объявление")
+		return
+	}
+
+	report := &models.Report{
+		AdID:       ad.ID,
+		ReporterID: &user.ID,
+		Reason:     req.Reason,
+		Comment:    req.Comment,
+		Status:     models.ReportOpen,
+	}
+	hidden := false
+	err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := s.Reports.Create(ctx, report); err != nil {
+			return err
+		}
+		if ad.Hidden {
+			return nil
+		}
+		open, err := s.Reports.CountOpen(ctx, ad.ID)
+		if err != nil {
+			return err
+		}
+		if open < int64(s.reportHideThreshold()) {
+			return nil
+		}
+		hidden = true
+		return s.Ads.SetHidden(ctx, ad.ID, models.HiddenByReports)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		utils.WriteJSONError(w, http.StatusConflict, "ваша жалоба на это объявление уже рассматривается")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении жалобы")
+		return
+	}
+
+	if hidden {
+		s.notify(r.Context(), models.NotificationAdHidden, AdModeratedPayload{AdID: ad.ID, Title: ad.Title}, ad.UserID)
+	}
+
+	report.Reporter = user
+	utils.WriteJSON(w, http.StatusCreated, toReportResponse(report))
+}
+
+// ModerationQueueHandler возвращает объявления с открытыми жалобами:
+// сначала те, на которые жалуются чаще.
+func (s *Server) ModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	summaries, err := s.Reports.Queue(r.Context(), limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении очереди модерации")
+		return
+	}
+
+	items := make([]ModerationQueueItem, 0, len(summaries))
+	for _, sum := range summaries {
+		ad, err := s.Ads.GetByID(r.Context(), sum.AdID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении очереди модерации")
+			return
+		}
+		items = append(items, ModerationQueueItem{
+			AdID:           ad.ID,
+			Title:          ad.Title,
+			Status:         ad.Status,
+			Hidden:         ad.Hidden,
+			HiddenReason:   ad.HiddenReason,
+			Owner:          toUserResponse(&ad.User),
+			Reports:        sum.Reports,
+			Reasons:        sum.Reasons,
+			LastReportedAt: sum.LastReportedAt,
+		})
+	}
+	utils.WriteJSON(w, http.StatusOK, items)
+}
+
+// ListAdReportsHandler возвращает открытые жалобы на объявление.
+func (s *Server) ListAdReportsHandler(w http.ResponseWriter, r *http.Request) {
+	adID, err := pathID(r, 2)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	reports, err := s.Reports.ListOpen(r.Context(), adID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении жалоб")
+		return
+	}
+
+	resp := make([]ReportResponse, len(reports))
+	for i := range reports {
+		resp[i] = toReportResponse(&reports[i])
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// ModerateAdHandler применяет решение модератора к объявлению и закрывает
+// открытые жалобы на него. Решение записывается в журнал вместе с пояснением.
+func (s *Server) ModerateAdHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 2)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	var req ModerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+	action, ok := moderationActions[req.Action]
+	if !ok {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неизвестное действие: "+req.Action)
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" || utf8.RuneCountInString(req.Note) > 1000 {
+		utils.WriteJSONError(w, http.StatusBadRequest, "пояснение обязательно и не длиннее 1000 символов")
+		return
+	}
+	if action == models.ModerationUserSuspended && (req.SuspendDays < 1 || req.SuspendDays > 365) {
+		utils.WriteJSONError(w, http.StatusBadRequest, "срок блокировки — от 1 до 365 дней")
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return
+	}
+	if action == models.ModerationUserSuspended && ad.User.Can(models.PermModerateAds) {
+		utils.WriteJSONError(w, http.StatusConflict, "нельзя заблокировать модератора")
+		return
+	}
+
+	var record *models.ModerationAction
+	var bidders []uint
+	err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		var err error
+		if bidders, err = s.applyModeration(ctx, action, ad, &req); err != nil {
+			return err
+		}
+		if _, err := s.Reports.Resolve(ctx, ad.ID, action, time.Now()); err != nil {
+			return err
+		}
+		record, err = s.recordModeration(ctx, moderator, action, ad, req.Note)
+		return err
+	})
+	if errors.Is(err, errUserBanned) {
+		utils.WriteJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		utils.WriteJSONError(w, http.StatusConflict, "в аукционе появились новые ставки, повторите запрос")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при применении решения")
+		return
+	}
+
+	payload := AdModeratedPayload{AdID: ad.ID, Title: ad.Title, Note: req.Note}
+	switch action {
+	case models.ModerationAdHidden:
+		s.notify(r.Context(), models.NotificationAdHidden, payload, ad.UserID)
+	case models.ModerationAdRemoved:
+		s.notify(r.Context(), models.NotificationAdRemoved, payload, ad.UserID)
+	case models.ModerationUserWarned:
+		s.notify(r.Context(), models.NotificationModerationWarn, payload, ad.UserID)
+	}
+	s.notifyBidders(r.Context(), ad, bidders)
+
+	record.Moderator = *moderator
+	utils.WriteJSON(w, http.StatusOK, toModerationActionResponse(record))
+}
+
+// errUserBanned — владельца объявления нельзя заблокировать на срок: он уже забанен.
+var errUserBanned = errors.New("владелец объявления забанен, временная блокировка не нужна")
+
+// applyModeration выполняет само действие модератора внутри транзакции.
+// Если вместе с объявлением сняты торги, возвращает их участников.
+func (s *Server) applyModeration(ctx context.Context, action string, ad *models.Ad, req *ModerationRequest) ([]uint, error) {
+	switch action {
+	case models.ModerationReportsDismissed:
+		// Жалобы не подтвердились — автоматически скрытое объявление возвращается
+		// в ленту. Скрытое модератором остаётся скрытым.
+		if ad.Hidden && ad.HiddenReason.Automatic() {
+			return nil, s.Ads.SetHidden(ctx, ad.ID, "")
+		}
+	case models.ModerationAdHidden:
+		return nil, s.Ads.SetHidden(ctx, ad.ID, models.HiddenByModerator)
+	case models.ModerationAdRemoved:
+		return s.deleteAd(ctx, ad, true)
+	case models.ModerationUserSuspended:
+		return nil, s.suspendOwner(ctx, ad.UserID, req.SuspendDays, req.Note)
+	}
+	return nil, nil
+}
+
+// suspendOwner блокирует владельца объявления на days дней. Бан не
+// заменяется временной блокировкой, а действующая блокировка, которая
+// закончится позже или бессрочна, не сокращается.
+func (s *Server) suspendOwner(ctx context.Context, userID uint, days int, reason string) error {
+	owner, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	until := now.AddDate(0, 0, days)
+	end := &until
+	switch owner.StatusAt(now) {
+	case models.UserBanned:
+		return errUserBanned
+	case models.UserSuspended:
+		if owner.StatusUntil == nil || owner.StatusUntil.After(until) {
+			end = owner.StatusUntil
+		}
+	}
+
+	if err := s.Users.SetStatus(ctx, userID, models.UserSuspended, end, reason); err != nil {
+		return err
+	}
+	return s.Sessions.RevokeUserSessions(ctx, userID)
+}
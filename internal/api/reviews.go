@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// maxReviewText — предельная длина текста отзыва в символах.
+const maxReviewText = 1000
+
+// ReviewRequest — оценка и текст отзыва.
+type ReviewRequest struct {
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+// ReviewResponse — отзыв о пользователе.
+type ReviewResponse struct {
+	ID        uint         `json:"id"`
+	AdID      uint         `json:"ad_id"`
+	Author    UserResponse `json:"author"`
+	Rating    int          `json:"rating"`
+	Text      string       `json:"text"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// ReviewPayload — уведомление о новом отзыве.
+type ReviewPayload struct {
+	ReviewID uint   `json:"review_id"`
+	AdID     uint   `json:"ad_id"`
+	Title    string `json:"title"`
+	Rating   int    `json:"rating"`
+}
+
+func toReviewResponse(rv *models.Review) ReviewResponse {
+	return ReviewResponse{
+		ID:        rv.ID,
+		AdID:      rv.AdID,
+		Author:    toUserResponse(&rv.Author),
+		Rating:    rv.Rating,
+		Text:      rv.Text,
+		CreatedAt: rv.CreatedAt,
+	}
+}
+
+// CreateReviewHandler оставляет отзыв о второй стороне сделки. Отзыв
+// доступен продавцу и покупателю проданного объявления, по одному от каждого.
+func (s *Server) CreateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	adID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID объявления")
+		return
+	}
+
+	var req ReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Rating < 1 || req.Rating > 5 {
+		utils.WriteJSONError(w, http.StatusBadRequest, "оценка должна быть от 1 до 5")
+		return
+	}
+	if utf8.RuneCountInString(req.Text) > maxReviewText {
+		utils.WriteJSONError(w, http.StatusBadRequest, "текст отзыва слишком длинный")
+		return
+	}
+
+	ad, err := s.Ads.GetByID(r.Context(), adID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "объявление не найдено")
+		return
+	}
+	if ad.Status != models.AdStatusSold || ad.BuyerID == nil {
+		utils.WriteJSONError(w, http.StatusConflict, "отзыв можно оставить только после завершённой сделки")
+		return
+	}
+
+	var targetID uint
+	switch user.ID {
+	case ad.UserID:
+		targetID = *ad.BuyerID
+	case *ad.BuyerID:
+		targetID = ad.UserID
+	default:
+		utils.WriteJSONError(w, http.StatusForbidden, "отзыв могут оставить только участники сделки")
+		return
+	}
+
+	review := &models.Review{
+		AdID:     ad.ID,
+		AuthorID: user.ID,
+		TargetID: targetID,
+		Rating:   req.Rating,
+		Text:     req.Text,
+	}
+	err = s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := s.Reviews.Create(ctx, review); err != nil {
+			return err
+		}
+		return s.Users.AddRating(ctx, targetID, req.Rating)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		utils.WriteJSONError(w, http.StatusConflict, "вы уже оставили отзыв по этой сделке")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении отзыва")
+		return
+	}
+
+	review.Author = *user
+	s.notify(r.Context(), models.NotificationReviewReceived, ReviewPayload{
+		ReviewID: review.ID,
+		AdID:     ad.ID,
+		Title:    ad.Title,
+		Rating:   review.Rating,
+	}, targetID)
+
+	utils.WriteJSON(w, http.StatusCreated, toReviewResponse(review))
+}
+
+// GetUserHandler возвращает публичный профиль пользователя с рейтингом.
+func (s *Server) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID пользователя")
+		return
+	}
+
+	user, err := s.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "пользователь не найден")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, toUserResponse(user))
+}
+
+// ListUserReviewsHandler возвращает отзывы о пользователе, новые первыми.
+func (s *Server) ListUserReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, 1)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID пользователя")
+		return
+	}
+
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := s.Users.GetByID(r.Context(), userID); err != nil {
+		utils.WriteJSONError(w, http.StatusNotFound, "пользователь не найден")
+		return
+	}
+
+	reviews, err := s.Reviews.ListByTarget(r.Context(), userID, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении отзывов")
+		return
+	}
+
+	resp := make([]ReviewResponse, len(reviews))
+	for i := range reviews {
+		resp[i] = toReviewResponse(&reviews[i])
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/screening"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// screenAd проверяет текст объявления фильтрами содержимого. Отклонённое
+// объявление получает 422 с общими причинами без подробностей; при отказе
+// ответ уже записан.
+func (s *Server) screenAd(w http.ResponseWriter, r *http.Request, content *screening.Content) (screening.Decision, bool) {
+	if s.Screener == nil {
+		return screening.Decision{Verdict: screening.Allow}, true
+	}
+
+	decision, err := s.Screener.Check(r.Context(), content)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при проверке объявления")
+		return decision, false
+	}
+	if decision.Verdict == screening.Reject {
+		utils.WriteJSONError(w, http.StatusUnprocessableEntity, "объявление отклонено: "+screeningReasons(decision))
+		return decision, false
+	}
+	return decision, true
+}
+
+// flagAd скрывает объявление и ставит его в очередь модерации с причинами,
+// которые назвали фильтры. Вызывается в транзакции сохранения объявления.
+func (s *Server) flagAd(ctx context.Context, ad *models.Ad, decision screening.Decision) error {
+	if !ad.Hidden {
+		if err := s.Ads.SetHidden(ctx, ad.ID, models.HiddenByScreening); err != nil {
+			return err
+		}
+		ad.Hidden, ad.HiddenReason = true, models.HiddenByScreening
+	}
+	return s.Reports.Create(ctx, &models.Report{
+		AdID:    ad.ID,
+		Reason:  models.ReportAutomated,
+		Comment: flagComment(decision),
+		Status:  models.ReportOpen,
+	})
+}
+
+// screeningReasons — причины решения фильтров, которые можно показать автору.
+func screeningReasons(decision screening.Decision) string {
+	return strings.Join(decision.Reasons, "; ")
+}
+
+// flagComment — подробности решения фильтров для модератора.
+func flagComment(decision screening.Decision) string {
+	comment := strings.Join(decision.Details, "; ")
+	if r := []rune(comment); len(r) > 500 {
+		comment = string(r[:500])
+	}
+	return comment
+}
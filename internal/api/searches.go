@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+)
+
+// MaxSavedSearches — сколько поисков может сохранить один пользователь.
+const MaxSavedSearches = 20
+
+// SavedSearchRequest — параметры сохраняемого поиска, те же, что у GET /ads.
+type SavedSearchRequest struct {
+	Name       string   `json:"name"`
+	Query      string   `json:"q"`
+	MinPrice   *float64 `json:"min_price"`
+	MaxPrice   *float64 `json:"max_price"`
+	CategoryID *uint    `json:"category_id"`
+}
+
+// CreateSavedSearchHandler сохраняет поиск текущего пользователя.
+func (s *Server) CreateSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Name == "" {
+		req.Name = req.Query
+	}
+
+	switch {
+	case req.Query == "" && req.MinPrice == nil && req.MaxPrice == nil && req.CategoryID == nil:
+		utils.WriteJSONError(w, http.StatusBadRequest, "укажите хотя бы одно условие поиска")
+		return
+	case req.Name == "" || utf8.RuneCountInString(req.Name) > 100:
+		utils.WriteJSONError(w, http.StatusBadRequest, "название должно содержать от 1 до 100 символов")
+		return
+	case utf8.RuneCountInString(req.Query) > 200:
+		utils.WriteJSONError(w, http.StatusBadRequest, "слишком длинный поисковый запрос")
+		return
+	case req.MinPrice != nil && req.MaxPrice != nil && *req.MinPrice > *req.MaxPrice:
+		utils.WriteJSONError(w, http.StatusBadRequest, "min_price не может быть больше max_price")
+		return
+	}
+
+	if req.CategoryID != nil {
+		if _, err := s.Categories.GetByID(r.Context(), *req.CategoryID); err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, "раздел не найден")
+			return
+		}
+	}
+
+	n, err := s.SavedSearches.CountByUser(r.Context(), user.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении поиска")
+		return
+	}
+	if n >= MaxSavedSearches {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя сохранить больше 20 поисков")
+		return
+	}
+
+	search := &models.SavedSearch{
+		UserID:     user.ID,
+		Name:       req.Name,
+		Query:      req.Query,
+		MinPrice:   req.MinPrice,
+		MaxPrice:   req.MaxPrice,
+		CategoryID: req.CategoryID,
+	}
+	if err := s.SavedSearches.Create(r.Context(), search); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при сохранении поиска")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, search)
+}
+
+// ListSavedSearchesHandler возвращает сохранённые поиски текущего пользователя.
+func (s *Server) ListSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	searches, err := s.SavedSearches.ListByUser(r.Context(), user.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении поисков")
+		return
+	}
+	if searches == nil {
+		searches = []models.SavedSearch{}
+	}
+	utils.WriteJSON(w, http.StatusOK, searches)
+}
+
+// DeleteSavedSearchHandler удаляет сохранённый поиск из пути /me/searches/{id}.
+func (s *Server) DeleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	searchID, err := pathID(r, 2)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID поиска")
+		return
+	}
+
+	// Чужой поиск неотличим от несуществующего
+	search, err := s.SavedSearches.GetByID(r.Context(), searchID)
+	if err != nil || search.UserID != user.ID {
+		utils.WriteJSONError(w, http.StatusNotFound, "поиск не найден")
+		return
+	}
+
+	if err := s.SavedSearches.Delete(r.Context(), search); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при удалении поиска")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
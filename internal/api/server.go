@@ -0,0 +1,102 @@
+package api
+
+import (
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/events"
+	"github.com/WalnutBagel/go-marketplace/internal/mail"
+	"github.com/WalnutBagel/go-marketplace/internal/ratelimit"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/screening"
+	"github.com/WalnutBagel/go-marketplace/internal/storage"
+)
+
+// Server хранит зависимости HTTP-обработчиков.
+// Обработчики не обращаются к базе напрямую, а работают через репозитории,
+// поэтому в тестах их можно заменить реализациями в памяти.
+type Server struct {
+	Users         repository.UserRepository
+	Ads           repository.AdRepository
+	Sessions      repository.SessionRepository
+	Tokens        repository.UserTokenRepository
+	Categories    repository.CategoryRepository
+	Images        repository.AdImageRepository
+	Favorites     repository.FavoriteRepository
+	Threads       repository.ThreadRepository
+	SavedSearches repository.SavedSearchRepository
+	Notifications repository.NotificationRepository
+	Webhooks      repository.WebhookRepository
+	Offers        repository.OfferRepository
+	Auctions      repository.AuctionRepository
+	Reviews       repository.ReviewRepository
+	Moderation    repository.ModerationRepository
+	Reports       repository.ReportRepository
+	Outbox        repository.OutboxRepository
+	Tx            repository.Transactor
+	Storage       storage.Storage
+	Thumbnails    ImageProcessor          // необязателен: без него уменьшенные копии не создаются
+	Events        *events.Hub             // необязателен: без него события в реальном времени не рассылаются
+	Screener      screening.ContentFilter // необязателен: без него текст объявлений не проверяется
+	Mailer        MailSender              // необязателен: без него письма не отправляются
+
+	// AdLifetime — срок показа опубликованного объявления. По умолчанию DefaultAdLifetime.
+	AdLifetime time.Duration
+	// OfferLifetime — сколько предложение цены ждёт ответа. По умолчанию DefaultOfferLifetime.
+	OfferLifetime time.Duration
+	// AuctionExtension — окно против «снайперских» ставок: ставка в последние
+	// AuctionExtension продлевает аукцион до now+AuctionExtension.
+	// По умолчанию DefaultAuctionExtension.
+	AuctionExtension time.Duration
+	// ReportHideThreshold — после стольких жалоб разных пользователей
+	// объявление скрывается из ленты до решения модератора.
+	// По умолчанию DefaultReportHideThreshold.
+	ReportHideThreshold int
+	// AppURL — адрес сайта для ссылок в письмах. По умолчанию DefaultAppURL.
+	AppURL string
+	// RequireEmailVerification запрещает вход, пока пользователь не
+	// подтвердил почту, и делает почту обязательной при регистрации.
+	RequireEmailVerification bool
+	// AllowPrivateWebhooks разрешает вебхуки на локальные и внутренние
+	// адреса. Только для тестов и локальной разработки.
+	AllowPrivateWebhooks bool
+	// MailLimitPerAddress и MailLimitPerIP ограничивают запросы писем
+	// восстановления пароля и повторного подтверждения. nil — без ограничения.
+	MailLimitPerAddress *ratelimit.Limiter
+	MailLimitPerIP      *ratelimit.Limiter
+}
+
+// MailSender ставит письма в очередь отправки, не дожидаясь почтового сервера.
+type MailSender interface {
+	Enqueue(msg mail.Message)
+}
+
+// DefaultAdLifetime — срок показа объявления, если AdLifetime не задан.
+const DefaultAdLifetime = 30 * 24 * time.Hour
+
+// DefaultOfferLifetime — срок ответа на предложение цены, если OfferLifetime не задан.
+const DefaultOfferLifetime = 48 * time.Hour
+
+// DefaultAppURL — адрес сайта для ссылок в письмах, если AppURL не задан.
+const DefaultAppURL = "http://localhost:8080"
+
+// DefaultReportHideThreshold — порог автоматического скрытия, если ReportHideThreshold не задан.
+const DefaultReportHideThreshold = 5
+
+// newExpiry возвращает срок окончания показа для объявления, публикуемого сейчас.
+func (s *Server) newExpiry() *time.Time {
+	lifetime := s.AdLifetime
+	if lifetime <= 0 {
+		lifetime = DefaultAdLifetime
+	}
+	expiresAt := time.Now().Add(lifetime)
+	return &expiresAt
+}
+
+// newOfferExpiry возвращает срок ответа на предложение цены, сделанное сейчас.
+func (s *Server) newOfferExpiry() time.Time {
+	lifetime := s.OfferLifetime
+	if lifetime <= 0 {
+		lifetime = DefaultOfferLifetime
+	}
+	return time.Now().Add(lifetime)
+}
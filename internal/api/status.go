@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+	"github.com/WalnutBagel/go-marketplace/internal/worker"
+)
+
+// adActions сопоставляет действие из пути /ads/{id}/{action} и целевое состояние.
+var adActions = map[string]models.AdStatus{
+	"publish":   models.AdStatusPublished,
+	"unpublish": models.AdStatusDraft,
+	"reserve":   models.AdStatusReserved,
+	"mark-sold": models.AdStatusSold,
+	"archive":   models.AdStatusArchived,
+}
+
+// IsAdAction сообщает, что сегмент пути — действие над состоянием объявления.
+func IsAdAction(action string) bool {
+	_, ok := adActions[action]
+	return ok
+}
+
+// AdStatusResponse возвращается после смены состояния объявления.
+type AdStatusResponse struct {
+	ID        uint            `json:"id"`
+	Status    models.AdStatus `json:"status"`
+	ExpiresAt *time.Time      `json:"expires_at"`
+	BuyerID   *uint           `json:"buyer_id,omitempty"`
+}
+
+// AdActionHandler переводит объявление в новое состояние, если переход разрешён.
+func (s *Server) AdActionHandler(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	target, ok := adActions[segments[len(segments)-1]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	ad, ok := s.loadOwnedAd(w, r, "нет прав для изменения объявления")
+	if !ok {
+		return
+	}
+
+	if !s.auctionAllowsStatusChange(w, r, ad, target) {
+		return
+	}
+
+	if !ad.Status.CanTransitionTo(target) {
+		utils.WriteJSONError(w, http.StatusConflict, "переход из состояния "+string(ad.Status)+" в "+string(target)+" невозможен")
+		return
+	}
+
+	// Каждая публикация начинает срок показа заново
+	expiresAt := ad.ExpiresAt
+	if target == models.AdStatusPublished {
+		expiresAt = s.newExpiry()
+	}
+
+	// Возврат в продажу снимает резерв за покупателем, а при продаже
+	// покупателя можно указать явно
+	buyerID := ad.BuyerID
+	switch target {
+	case models.AdStatusPublished, models.AdStatusDraft:
+		buyerID = nil
+	case models.AdStatusSold:
+		if buyerID, ok = s.soldBuyer(w, r, ad); !ok {
+			return
+		}
+	}
+
+	s.changeAdStatus(w, r, ad, target, expiresAt, buyerID)
+}
+
+// MarkSoldRequest — необязательное тело запроса mark-sold.
+type MarkSoldRequest struct {
+	BuyerID *uint `json:"buyer_id"`
+}
+
+// soldBuyer определяет покупателя проданного объявления: из тела запроса
+// или уже закреплённого за объявлением. Указать можно только того, кто
+// переписывался с продавцом по объявлению. При ошибке ответ уже записан.
+func (s *Server) soldBuyer(w http.ResponseWriter, r *http.Request, ad *models.Ad) (*uint, bool) {
+	var req MarkSoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный формат запроса")
+		return nil, false
+	}
+	if req.BuyerID == nil || (ad.BuyerID != nil && *ad.BuyerID == *req.BuyerID) {
+		return ad.BuyerID, true
+	}
+
+	if *req.BuyerID == ad.UserID {
+		utils.WriteJSONError(w, http.StatusBadRequest, "нельзя указать себя покупателем")
+		return nil, false
+	}
+	_, err := s.Threads.FindThread(r.Context(), ad.ID, *req.BuyerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.WriteJSONError(w, http.StatusBadRequest, "покупатель должен быть участником переписки по объявлению")
+		return nil, false
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при проверке покупателя")
+		return nil, false
+	}
+	return req.BuyerID, true
+}
+
+// RenewAdHandler продлевает показ опубликованного или истёкшего объявления.
+func (s *Server) RenewAdHandler(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.loadOwnedAd(w, r, "нет прав для изменения объявления")
+	if !ok {
+		return
+	}
+
+	if !s.auctionAllowsStatusChange(w, r, ad, models.AdStatusPublished) {
+		return
+	}
+
+	if !ad.Status.Renewable() {
+		utils.WriteJSONError(w, http.StatusConflict, "продлить можно только опубликованное или истёкшее объявление")
+		return
+	}
+
+	s.changeAdStatus(w, r, ad, models.AdStatusPublished, s.newExpiry(), ad.BuyerID)
+}
+
+// auctionAllowsStatusChange не даёт вручную менять состояние аукциона,
+// пока идут торги, и публиковать его повторно после завершения. Итог торгов
+// выставляет AuctionCloser. При запрете ответ уже записан.
+func (s *Server) auctionAllowsStatusChange(w http.ResponseWriter, r *http.Request, ad *models.Ad, target models.AdStatus) bool {
+	if ad.ListingType != models.ListingAuction {
+		return true
+	}
+
+	auction, err := s.Auctions.Get(r.Context(), ad.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении аукциона")
+		return false
+	}
+	if auction.Status == models.AuctionOpen {
+		utils.WriteJSONError(w, http.StatusConflict, "состояние аукциона нельзя менять, пока идут торги")
+		return false
+	}
+	if target == models.AdStatusPublished {
+		utils.WriteJSONError(w, http.StatusConflict, "завершённый аукцион нельзя опубликовать повторно")
+		return false
+	}
+	return true
+}
+
+// changeAdStatus атомарно меняет состояние, срок показа и покупателя
+// и пишет ответ. Если резерв снимается с покупателя, принятое им
+// предложение отменяется в той же транзакции, а покупатель получает
+// уведомление offer_cancelled.
+func (s *Server) changeAdStatus(w http.ResponseWriter, r *http.Request, ad *models.Ad, target models.AdStatus, expiresAt *time.Time, buyerID *uint) {
+	var cancelled []models.Offer
+	err := s.Tx.Transaction(r.Context(), func(ctx context.Context) error {
+		if err := s.Ads.UpdateStatus(ctx, ad.ID, ad.Status, target, expiresAt); err != nil {
+			return err
+		}
+		if !sameID(ad.BuyerID, buyerID) {
+			if err := s.Ads.SetBuyer(ctx, ad.ID, buyerID); err != nil {
+				return err
+			}
+			if ad.BuyerID != nil {
+				var err error
+				if cancelled, err = s.Offers.CancelAccepted(ctx, ad.ID); err != nil {
+					return err
+				}
+			}
+		}
+		updated := *ad
+		updated.Status, updated.ExpiresAt, updated.BuyerID, updated.UpdatedAt = target, expiresAt, buyerID, time.Now()
+		return s.recordAdEvent(ctx, worker.WebhookAdUpdated, &updated)
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		utils.WriteJSONError(w, http.StatusConflict, "состояние объявления изменилось, повторите запрос")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при смене состояния объявления")
+		return
+	}
+
+	for i := range cancelled {
+		s.notify(r.Context(), models.NotificationOfferCancelled, offerPayload(&cancelled[i], ad), cancelled[i].BuyerID)
+	}
+	utils.WriteJSON(w, http.StatusOK, AdStatusResponse{ID: ad.ID, Status: target, ExpiresAt: expiresAt, BuyerID: buyerID})
+}
+
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/middleware"
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/services"
+	"github.com/WalnutBagel/go-marketplace/internal/utils"
+	"github.com/WalnutBagel/go-marketplace/internal/worker"
+)
+
+// MaxWebhooks — сколько вебхуков может зарегистрировать один пользователь.
+const MaxWebhooks = 10
+
+// WebhookRequest — параметры регистрации вебхука. Без Events вебхук
+// подписывается на все события.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	AllAds bool     `json:"all_ads"`
+}
+
+// WebhookResponse — зарегистрированный вебхук. Secret отдаётся только при создании.
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	AllAds    bool      `json:"all_ads"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toWebhookResponse(webhook *models.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    webhook.EventList(),
+		AllAds:    webhook.AllAds,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+// CreateWebhookHandler регистрирует вебхук текущего пользователя.
+// Вебхук на все объявления (all_ads) может завести только администратор.
+func (s *Server) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "невалидный JSON")
+		return
+	}
+
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(req.URL) > 500 {
+		utils.WriteJSONError(w, http.StatusBadRequest, "укажите абсолютный http(s) адрес не длиннее 500 символов")
+		return
+	}
+	if !s.AllowPrivateWebhooks {
+		if err := worker.CheckWebhookHost(r.Context(), u.Hostname()); err != nil {
+			if errors.Is(err, worker.ErrWebhookAddress) {
+				utils.WriteJSONError(w, http.StatusBadRequest, "вебхук нельзя направить на локальный или внутренний адрес")
+				return
+			}
+			utils.WriteJSONError(w, http.StatusBadRequest, "не удалось определить адрес вебхука")
+			return
+		}
+	}
+
+	if len(req.Events) == 0 {
+		req.Events = worker.WebhookEvents
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(worker.WebhookEvents, event) {
+			utils.WriteJSONError(w, http.StatusBadRequest, "неизвестное событие: "+event)
+			return
+		}
+	}
+	req.Events = slices.Compact(slices.Sorted(slices.Values(req.Events)))
+
+	if req.AllAds && !middleware.HasPermission(r, models.PermWebhooksAllAds) {
+		utils.WriteJSONError(w, http.StatusForbidden, "подписка на все объявления доступна только администратору")
+		return
+	}
+
+	existing, err := s.Webhooks.ListByUser(r.Context(), user.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при создании вебхука")
+		return
+	}
+	if len(existing) >= MaxWebhooks {
+		utils.WriteJSONError(w, http.StatusConflict, "достигнут лимит вебхуков")
+		return
+	}
+
+	secret, err := services.NewOpaqueToken(32)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при создании вебхука")
+		return
+	}
+
+	webhook := models.Webhook{
+		UserID: user.ID,
+		URL:    req.URL,
+		Events: strings.Join(req.Events, ","),
+		Secret: secret,
+		AllAds: req.AllAds,
+	}
+	if err := s.Webhooks.Create(r.Context(), &webhook); err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при создании вебхука")
+		return
+	}
+
+	resp := toWebhookResponse(&webhook)
+	resp.Secret = webhook.Secret
+	utils.WriteJSON(w, http.StatusCreated, resp)
+}
+
+// ListWebhooksHandler возвращает вебхуки текущего пользователя.
+func (s *Server) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	webhooks, err := s.Webhooks.ListByUser(r.Context(), user.ID)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении вебхуков")
+		return
+	}
+
+	resp := make([]WebhookResponse, len(webhooks))
+	for i := range webhooks {
+		resp[i] = toWebhookResponse(&webhooks[i])
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// DeleteWebhookHandler удаляет вебхук вместе с журналом доставок.
+func (s *Server) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := s.loadOwnedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.Webhooks.Delete(r.Context(), webhook); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при удалении вебхука")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveriesHandler возвращает журнал доставок вебхука, новые первыми.
+func (s *Server) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := s.loadOwnedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := s.Webhooks.ListDeliveries(r.Context(), webhook.ID, limit, (page-1)*limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении журнала доставок")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	utils.WriteJSON(w, http.StatusOK, deliveries)
+}
+
+// loadOwnedWebhook загружает вебхук из пути /me/webhooks/{id}[/...].
+// Чужой вебхук выглядит как несуществующий. При ошибке ответ уже записан.
+func (s *Server) loadOwnedWebhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := pathID(r, 2)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "неверный ID вебхука")
+		return nil, false
+	}
+
+	webhook, err := s.Webhooks.GetByID(r.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && webhook.UserID != user.ID) {
+		utils.WriteJSONError(w, http.StatusNotFound, "вебхук не найден")
+		return nil, false
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "ошибка при получении вебхука")
+		return nil, false
+	}
+	return webhook, true
+}
+
+// recordAdEvent записывает событие об объявлении в outbox. Вызывайте внутри
+// s.Tx.Transaction вместе с изменением объявления: тогда событие не потеряется,
+// даже если процесс упадёт сразу после коммита.
+func (s *Server) recordAdEvent(ctx context.Context, eventType string, ad *models.Ad) error {
+	event, err := worker.NewAdEvent(eventType, ad)
+	if err != nil {
+		return err
+	}
+	return s.Outbox.Add(ctx, event)
+}
+
+// recordAdPriceChange записывает в outbox событие ad.updated с прежней
+// ценой oldPrice, чтобы подписчики избранного узнали об изменении цены.
+func (s *Server) recordAdPriceChange(ctx context.Context, ad *models.Ad, oldPrice float64) error {
+	event, err := worker.NewAdPriceEvent(ad, oldPrice)
+	if err != nil {
+		return err
+	}
+	return s.Outbox.Add(ctx, event)
+}
@@ -31,7 +31,7 @@ func ConnectWithRetry() (*gorm.DB, error) {
 			dbHost, dbUser, dbPassword, dbName, dbPort, sslMode)
 
 		for attempts := 1; attempts <= 10; attempts++ {
-			dbInstance, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+			dbInstance, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 			if err == nil {
 				sqlDB, err := dbInstance.DB()
 				if err == nil && sqlDB.Ping() == nil {
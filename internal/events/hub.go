@@ -0,0 +1,167 @@
+// Package events доставляет события пользователям в реальном времени.
+//
+// Hub хранит подписки клиентов, подключённых к этому экземпляру сервиса,
+// а рассылка идёт через Backend. LocalBackend работает внутри процесса;
+// чтобы события доходили до клиентов на других экземплярах, достаточно
+// реализовать Backend поверх общего брокера (Redis, Postgres NOTIFY и т.п.).
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Типы событий.
+const (
+	TypeMessageNew      = "message.new"
+	TypeAdPriceChanged  = "ad.price_changed"
+	TypeAdSold          = "ad.sold"
+	TypeFavoriteUpdated = "favorite.updated"
+	TypeNotificationNew = "notification.new"
+)
+
+// Event — событие для клиента. Data уже сериализовано, чтобы событие
+// можно было без изменений передать между экземплярами.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Envelope — событие вместе с получателями, в таком виде оно проходит через Backend.
+type Envelope struct {
+	UserIDs []uint `json:"user_ids"`
+	Event   Event  `json:"event"`
+}
+
+// Backend рассылает конверты всем экземплярам сервиса, включая текущий.
+type Backend interface {
+	Publish(ctx context.Context, env Envelope) error
+	// Subscribe регистрирует обработчик конвертов, опубликованных любым экземпляром.
+	Subscribe(handler func(Envelope))
+}
+
+// SubscriptionBuffer — сколько событий может ждать отправки одному клиенту.
+// Клиент, который не успевает их забирать, отключается и должен переподключиться.
+const SubscriptionBuffer = 32
+
+// Subscription — поток событий одного подключения.
+// Канал C закрывается при Close или если клиент не успевает читать события.
+type Subscription struct {
+	C <-chan Event
+
+	c      chan Event
+	userID uint
+	hub    *Hub
+}
+
+// Close отписывает подключение. Повторный вызов безопасен.
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub хранит подписки подключённых к экземпляру клиентов.
+type Hub struct {
+	backend Backend
+
+	mu   sync.Mutex
+	subs map[uint]map[*Subscription]struct{}
+}
+
+func NewHub(backend Backend) *Hub {
+	h := &Hub{backend: backend, subs: make(map[uint]map[*Subscription]struct{})}
+	backend.Subscribe(h.deliver)
+	return h
+}
+
+// Publish отправляет событие указанным пользователям на всех экземплярах.
+func (h *Hub) Publish(ctx context.Context, eventType string, data any, userIDs ...uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return h.backend.Publish(ctx, Envelope{UserIDs: userIDs, Event: Event{Type: eventType, Data: raw}})
+}
+
+// Subscribe открывает поток событий пользователя.
+func (h *Hub) Subscribe(userID uint) *Subscription {
+	c := make(chan Event, SubscriptionBuffer)
+	sub := &Subscription{C: c, c: c, userID: userID, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+// deliver раздаёт событие локальным подпискам получателей.
+func (h *Hub) deliver(env Envelope) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	seen := make(map[uint]bool, len(env.UserIDs))
+	for _, userID := range env.UserIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		for sub := range h.subs[userID] {
+			select {
+			case sub.c <- env.Event:
+			default:
+				// Медленный клиент не должен задерживать остальных
+				h.removeLocked(sub)
+			}
+		}
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(sub)
+}
+
+func (h *Hub) removeLocked(sub *Subscription) {
+	userSubs := h.subs[sub.userID]
+	if _, ok := userSubs[sub]; !ok {
+		return
+	}
+	delete(userSubs, sub)
+	if len(userSubs) == 0 {
+		delete(h.subs, sub.userID)
+	}
+	close(sub.c)
+}
+
+// LocalBackend доставляет события только внутри текущего процесса.
+type LocalBackend struct {
+	mu       sync.RWMutex
+	handlers []func(Envelope)
+}
+
+func NewLocalBackend() *LocalBackend {
+	return &LocalBackend{}
+}
+
+func (b *LocalBackend) Publish(_ context.Context, env Envelope) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(env)
+	}
+	return nil
+}
+
+func (b *LocalBackend) Subscribe(handler func(Envelope)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestHubDeliversToRecipients(t *testing.T) {
+	hub := NewHub(NewLocalBackend())
+
+	alice1 := hub.Subscribe(1)
+	alice2 := hub.Subscribe(1)
+	bob := hub.Subscribe(2)
+	defer alice1.Close()
+	defer alice2.Close()
+	defer bob.Close()
+
+	if err := hub.Publish(context.Background(), TypeMessageNew, map[string]int{"id": 7}, 1, 1); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	for _, sub := range []*Subscription{alice1, alice2} {
+		select {
+		case ev := <-sub.C:
+			if ev.Type != TypeMessageNew || string(ev.Data) != `{"id":7}` {
+				t.Errorf("Неожиданное событие: %+v", ev)
+			}
+		default:
+			t.Fatal("Событие не доставлено на одно из подключений")
+		}
+		// Повтор получателя в списке не дублирует событие
+		select {
+		case ev := <-sub.C:
+			t.Errorf("Лишнее событие: %+v", ev)
+		default:
+		}
+	}
+
+	select {
+	case ev := <-bob.C:
+		t.Errorf("Событие пришло не тому пользователю: %+v", ev)
+	default:
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub(NewLocalBackend())
+	slow := hub.Subscribe(1)
+
+	for i := 0; i <= SubscriptionBuffer; i++ {
+		hub.Publish(context.Background(), TypeAdSold, i, 1)
+	}
+
+	n := 0
+	for range slow.C {
+		n++
+	}
+	if n != SubscriptionBuffer {
+		t.Errorf("Ожидали %d событий до отключения, получили %d", SubscriptionBuffer, n)
+	}
+
+	// Закрытие уже отключённой подписки безопасно
+	slow.Close()
+}
+
+// relayBackend имитирует брокер между двумя экземплярами сервиса.
+type relayBackend struct {
+	handlers *[]func(Envelope)
+}
+
+func (b relayBackend) Publish(_ context.Context, env Envelope) error {
+	// Конверт проходит через сериализацию, как через внешний брокер
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	for _, h := range *b.handlers {
+		var received Envelope
+		json.Unmarshal(raw, &received)
+		h(received)
+	}
+	return nil
+}
+
+func (b relayBackend) Subscribe(handler func(Envelope)) {
+	*b.handlers = append(*b.handlers, handler)
+}
+
+func TestHubFansOutAcrossInstances(t *testing.T) {
+	var handlers []func(Envelope)
+	first := NewHub(relayBackend{&handlers})
+	second := NewHub(relayBackend{&handlers})
+
+	sub := second.Subscribe(5)
+	defer sub.Close()
+
+	first.Publish(context.Background(), TypeAdPriceChanged, map[string]float64{"price": 10}, 5)
+
+	select {
+	case ev := <-sub.C:
+		if ev.Type != TypeAdPriceChanged {
+			t.Errorf("Неожиданное событие: %+v", ev)
+		}
+	default:
+		t.Fatal("Событие не дошло до другого экземпляра")
+	}
+}
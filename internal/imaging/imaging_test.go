@@ -0,0 +1,142 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withExif вставляет в JPEG сегмент APP1 с тегом Orientation.
+func withExif(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1) // одна запись в IFD0
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStripJPEG(t *testing.T) {
+	src := withExif(t, encodeJPEG(t, 8, 4), 1)
+	if jpegOrientation(src) != 1 {
+		t.Fatal("EXIF не распознан")
+	}
+
+	out, err := StripMetadata(src, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("Exif")) {
+		t.Fatal("EXIF остался в файле")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("Файл после очистки не декодируется: %v", err)
+	}
+}
+
+func TestStripJPEGAppliesOrientation(t *testing.T) {
+	// Orientation 6: камера повёрнута на 90°, картинка 8×4 должна стать 4×8
+	out, err := StripMetadata(withExif(t, encodeJPEG(t, 8, 4), 6), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("Exif")) {
+		t.Fatal("EXIF остался в файле")
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 4 || cfg.Height != 8 {
+		t.Fatalf("Ожидали 4×8, получили %d×%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	src := buf.Bytes()
+
+	// Вставляем текстовый чанк перед IEND
+	text := []byte("Comment\x00secret")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, text...)
+	chunk = append(chunk, 0, 0, 0, 0)
+	iend := len(src) - 12
+	src = append(append(append([]byte{}, src[:iend]...), chunk...), src[iend:]...)
+
+	out, err := StripMetadata(src, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("secret")) {
+		t.Fatal("Текстовый чанк остался в файле")
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("Файл после очистки не декодируется: %v", err)
+	}
+}
+
+func TestFit(t *testing.T) {
+	cases := []struct {
+		w, h, wantW, wantH int
+	}{
+		{1600, 800, 200, 100},
+		{800, 1600, 100, 200},
+		{100, 50, 100, 50}, // маленькие картинки не увеличиваются
+	}
+	for _, c := range cases {
+		b := Fit(image.NewRGBA(image.Rect(0, 0, c.w, c.h)), 200, 200).Bounds()
+		if b.Dx() != c.wantW || b.Dy() != c.wantH {
+			t.Errorf("%d×%d: ожидали %d×%d, получили %d×%d", c.w, c.h, c.wantW, c.wantH, b.Dx(), b.Dy())
+		}
+	}
+}
+
+func TestRejectsOversizedImage(t *testing.T) {
+	// Заголовок PNG с размером 100000×100000: пиксельные данные не нужны,
+	// размер должен отклоняться до декодирования
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	if _, err := StripMetadata(data, "image/png"); err != ErrTooLarge {
+		t.Fatalf("Ожидали ErrTooLarge, получили %v", err)
+	}
+	if _, err := decode(data); err != ErrTooLarge {
+		t.Fatalf("Ожидали ErrTooLarge при обработке, получили %v", err)
+	}
+	if _, err := StripMetadata(encodeJPEG(t, 8, 4), "image/jpeg"); err != nil {
+		t.Fatalf("Обычная картинка должна проходить: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif" // декодеры для image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"sync"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+	"github.com/WalnutBagel/go-marketplace/internal/storage"
+)
+
+// Размеры уменьшенных копий. Картинка вписывается в квадрат со стороной указанной длины.
+const (
+	ThumbnailSize = 200
+	MediumSize    = 800
+)
+
+// MaxPixels — наибольшая площадь картинки. Файл в несколько килобайт может
+// объявить размер в десятки тысяч пикселей по каждой стороне и при
+// декодировании занять гигабайты памяти, поэтому размер проверяется по
+// заголовку до декодирования.
+const MaxPixels = 40_000_000
+
+// ErrTooLarge — размер картинки превышает MaxPixels.
+var ErrTooLarge = fmt.Errorf("картинка больше %d мегапикселей", MaxPixels/1_000_000)
+
+// checkDimensions читает из заголовка размер картинки и отклоняет
+// слишком большие.
+func checkDimensions(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return errMalformed
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// decode декодирует картинку, предварительно проверив её размер.
+func decode(data []byte) (image.Image, error) {
+	if err := checkDimensions(data); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Processor в фоне генерирует уменьшенные копии загруженных картинок.
+type Processor struct {
+	storage storage.Storage
+	images  repository.AdImageRepository
+	queue   chan uint
+}
+
+func NewProcessor(st storage.Storage, images repository.AdImageRepository, queueSize int) *Processor {
+	return &Processor{storage: st, images: images, queue: make(chan uint, queueSize)}
+}
+
+// Enqueue ставит картинку в очередь. Если очередь переполнена, задача
+// отбрасывается: объявление останется с оригиналом без уменьшенных копий.
+func (p *Processor) Enqueue(imageID uint) {
+	select {
+	case p.queue <- imageID:
+	default:
+		log.Printf("Очередь обработки картинок переполнена, картинка %d пропущена", imageID)
+	}
+}
+
+// Run запускает workers обработчиков и блокируется до отмены ctx.
+func (p *Processor) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-p.queue:
+					if err := p.Process(ctx, id); err != nil {
+						log.Printf("Ошибка обработки картинки %d: %v", id, err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Process синхронно создаёт уменьшенные копии картинки и сохраняет их ключи.
+func (p *Processor) Process(ctx context.Context, imageID uint) error {
+	img, err := p.images.GetByID(ctx, imageID)
+	if err != nil {
+		return err
+	}
+
+	obj, err := p.storage.Get(ctx, img.Key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return err
+	}
+	src, err := decode(data)
+	if err != nil {
+		return err
+	}
+
+	thumbKey, err := p.storeVariant(ctx, img.Key, "thumb", Fit(src, ThumbnailSize, ThumbnailSize))
+	if err != nil {
+		return err
+	}
+	mediumKey, err := p.storeVariant(ctx, img.Key, "medium", Fit(src, MediumSize, MediumSize))
+	if err != nil {
+		return err
+	}
+
+	return p.images.UpdateVariants(ctx, img.ID, thumbKey, mediumKey)
+}
+
+// storeVariant кодирует вариант в JPEG и сохраняет рядом с оригиналом:
+// ads/1/abc.png → ads/1/abc_thumb.jpg.
+func (p *Processor) storeVariant(ctx context.Context, key, suffix string, img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return "", err
+	}
+
+	variantKey := strings.TrimSuffix(key, path.Ext(key)) + "_" + suffix + ".jpg"
+	if err := p.storage.Put(ctx, variantKey, &buf, "image/jpeg"); err != nil {
+		return "", err
+	}
+	return variantKey, nil
+}
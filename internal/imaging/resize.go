@@ -0,0 +1,32 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Fit уменьшает картинку так, чтобы она вписалась в maxW×maxH с сохранением пропорций.
+// Картинки меньше заданного размера не увеличиваются. Прозрачные области
+// заливаются белым, поскольку варианты сохраняются в JPEG.
+func Fit(src image.Image, maxW, maxH int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w > maxW || h > maxH {
+		if w*maxH > h*maxW {
+			h = max(1, h*maxW/w)
+			w = maxW
+		} else {
+			w = max(1, w*maxH/h)
+			h = maxH
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
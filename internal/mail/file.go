@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer складывает письма в каталог файлами .eml вместо отправки.
+// Подходит для локальной разработки: ссылку из письма можно открыть вручную.
+type FileMailer struct {
+	dir  string
+	from string
+	seq  atomic.Uint64
+}
+
+// NewFileMailer создаёт каталог dir, если его нет.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if !ValidAddress(msg.To) {
+		return errors.New("неверный адрес получателя")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102-150405"), m.seq.Add(1))
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, compose(m.from, msg, now), 0o644); err != nil {
+		return err
+	}
+	log.Printf("Письмо %q для %s сохранено в %s", msg.Subject, msg.To, path)
+	return nil
+}
@@ -0,0 +1,142 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidAddress(t *testing.T) {
+	cases := map[string]bool{
+		"user@example.com":               true,
+		"":                               false,
+		"user":                           false,
+		"User <user@example.com>":        false,
+		"user@example.com\r\nBcc: x@y.z": false,
+	}
+	for addr, want := range cases {
+		if got := ValidAddress(addr); got != want {
+			t.Errorf("ValidAddress(%q) = %v, ожидали %v", addr, got, want)
+		}
+	}
+}
+
+func TestCompose(t *testing.T) {
+	raw := string(compose("noreply@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Сброс пароля",
+		Body:    "Строка 1\nСтрока 2",
+	}, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))
+
+	for _, want := range []string{
+		"From: noreply@example.com\r\n",
+		"To: user@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Date: Wed, 01 Jan 2025 12:00:00 +0000\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nСтрока 1\r\nСтрока 2",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("В письме нет %q:\n%s", want, raw)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "noreply@example.com")
+	if err != nil {
+		t.Fatalf("NewFileMailer: %v", err)
+	}
+
+	for range 2 {
+		if err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Тест", Body: "Привет"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if err := m.Send(context.Background(), Message{To: "not-an-address", Subject: "Тест"}); err == nil {
+		t.Error("Неверный адрес должен давать ошибку")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 2 {
+		t.Fatalf("Ожидали 2 письма, нашли %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "To: user@example.com") || !strings.HasSuffix(string(data), "Привет") {
+		t.Errorf("Содержимое письма: %s", data)
+	}
+}
+
+type recordingMailer struct {
+	sent chan Message
+}
+
+func (m recordingMailer) Send(_ context.Context, msg Message) error {
+	m.sent <- msg
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	m := recordingMailer{sent: make(chan Message, 1)}
+	q := NewQueue(m, 1)
+	q.Enqueue(Message{To: "user@example.com", Subject: "Первое"})
+	// Очередь на одно письмо уже занята: второе отбрасывается, а не блокирует вызывающего
+	q.Enqueue(Message{To: "user@example.com", Subject: "Второе"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx, 1)
+		close(done)
+	}()
+
+	select {
+	case msg := <-m.sent:
+		if msg.Subject != "Первое" {
+			t.Errorf("Отправлено не то письмо: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Письмо из очереди не отправлено")
+	}
+	cancel()
+	<-done
+	select {
+	case msg := <-m.sent:
+		t.Errorf("Лишнее письмо: %+v", msg)
+	default:
+	}
+}
+
+func TestSMTPMailerTimeout(t *testing.T) {
+	// Сервер принимает соединение, но не отвечает приветствием
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: addr.Port, From: "noreply@example.com", Timeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	if err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Тест"}); err == nil {
+		t.Error("Молчащий сервер должен давать ошибку")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Отправка должна прерваться по таймауту, прошло %v", elapsed)
+	}
+}
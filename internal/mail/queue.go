@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Queue отправляет письма в фоне, чтобы время ответа обработчика не
+// зависело от почтового сервера и от того, ушло ли письмо вообще.
+type Queue struct {
+	mailer Mailer
+	queue  chan Message
+}
+
+func NewQueue(mailer Mailer, queueSize int) *Queue {
+	return &Queue{mailer: mailer, queue: make(chan Message, queueSize)}
+}
+
+// Enqueue ставит письмо в очередь. Если очередь переполнена, письмо
+// отбрасывается: пользователь может запросить его повторно.
+func (q *Queue) Enqueue(msg Message) {
+	select {
+	case q.queue <- msg:
+	default:
+		log.Printf("Очередь писем переполнена, письмо %q пропущено", msg.Subject)
+	}
+}
+
+// Run запускает workers отправителей и блокируется до отмены ctx.
+func (q *Queue) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-q.queue:
+					if err := q.mailer.Send(ctx, msg); err != nil {
+						log.Printf("Ошибка отправки письма %q: %v", msg.Subject, err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
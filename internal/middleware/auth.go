@@ -3,38 +3,97 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/WalnutBagel/go-marketplace/internal/models"
 	"github.com/WalnutBagel/go-marketplace/internal/services"
 )
 
 type contextKey string
 
-const userKey contextKey = "username"
-
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Требуется авторизация", http.StatusUnauthorized)
-			return
-		}
-
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Неверный формат заголовка Authorization", http.StatusUnauthorized)
-			return
-		}
-
-		username, err := services.ParseJWT(parts[1])
-		if err != nil {
-			http.Error(w, "Недействительный токен: "+err.Error(), http.StatusUnauthorized)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), userKey, username)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+const (
+	userKey        contextKey = "username"
+	sessionKey     contextKey = "session_id"
+	permissionsKey contextKey = "permissions"
+)
+
+// SessionStore позволяет middleware проверить, не отозвана ли сессия токена.
+type SessionStore interface {
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+}
+
+// UserStore позволяет middleware проверить, не заблокирован ли владелец токена.
+type UserStore interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// AuthMiddleware пропускает запросы с действующим access-токеном. Токен
+// отклоняется, если его сессия отозвана или пользователь заблокирован.
+func AuthMiddleware(sessions SessionStore, users UserStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				http.Error(w, "Требуется авторизация", http.StatusUnauthorized)
+				return
+			}
+
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				http.Error(w, "Неверный формат заголовка Authorization", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := services.ParseJWT(parts[1])
+			if err != nil {
+				http.Error(w, "Недействительный токен: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			session, err := sessions.GetSession(r.Context(), claims.SessionID)
+			if err != nil || session.RevokedAt != nil {
+				http.Error(w, "Сессия завершена, выполните вход заново", http.StatusUnauthorized)
+				return
+			}
+
+			user, err := users.GetByID(r.Context(), session.UserID)
+			if err != nil {
+				http.Error(w, "Пользователь не найден", http.StatusUnauthorized)
+				return
+			}
+			if user.StatusAt(time.Now()) != models.UserActive {
+				http.Error(w, "Учётная запись заблокирована", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userKey, claims.Username)
+			ctx = context.WithValue(ctx, sessionKey, claims.SessionID)
+			ctx = context.WithValue(ctx, permissionsKey, claims.Permissions)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission пропускает запрос, только если в токене есть право
+// permission. Ставится после AuthMiddleware.
+func RequirePermission(permission models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasPermission(r, permission) {
+				http.Error(w, "Недостаточно прав", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasPermission сообщает, есть ли право permission в токене запроса.
+func HasPermission(r *http.Request, permission models.Permission) bool {
+	permissions, _ := r.Context().Value(permissionsKey).([]string)
+	return slices.Contains(permissions, string(permission))
 }
 
 // Получить имя пользователя из контекста
@@ -42,3 +101,9 @@ func GetUsername(r *http.Request) (string, bool) {
 	username, ok := r.Context().Value(userKey).(string)
 	return username, ok
 }
+
+// Получить идентификатор сессии из контекста
+func GetSessionID(r *http.Request) (string, bool) {
+	sessionID, ok := r.Context().Value(sessionKey).(string)
+	return sessionID, ok
+}
@@ -6,15 +6,53 @@ import (
 	"gorm.io/gorm"
 )
 
+// ListingType — способ продажи: по фиксированной цене или с аукциона.
+type ListingType string
+
+const (
+	ListingFixed   ListingType = "fixed"
+	ListingAuction ListingType = "auction"
+)
+
+// Ad — объявление. У аукциона Price — текущая цена: стартовая, пока нет ставок,
+// затем последняя ставка; условия торгов хранятся в Auction.
 type Ad struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Title       string         `gorm:"size:100;not null" json:"title"`
-	Description string         `gorm:"size:1000;not null" json:"description"`
-	ImageURL    string         `gorm:"size:255" json:"image_url"`
-	Price       float64        `gorm:"not null" json:"price"`
-	UserID      uint           `gorm:"not null" json:"user_id"`
-	User        User           `gorm:"foreignKey:UserID" json:"user"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	Title       string      `gorm:"size:100;not null" json:"title"`
+	Description string      `gorm:"size:1000;not null" json:"description"`
+	ImageURL    string      `gorm:"size:255" json:"image_url"`
+	Price       float64     `gorm:"not null" json:"price"`
+	CategoryID  *uint       `gorm:"index" json:"category_id"`
+	ListingType ListingType `gorm:"size:20;not null;default:fixed" json:"listing_type"`
+	Status      AdStatus    `gorm:"size:20;not null;default:published;index" json:"status"`
+	ExpiresAt   *time.Time  `gorm:"index" json:"expires_at"`
+	UserID      uint        `gorm:"not null" json:"user_id"`
+	// BuyerID — покупатель, за которым объявление зарезервировано или которому продано.
+	BuyerID *uint `gorm:"index" json:"buyer_id"`
+	// Hidden — объявление скрыто модерацией и не показывается в ленте,
+	// HiddenReason — кто его скрыл. Меняются только через AdRepository.SetHidden.
+	Hidden       bool           `gorm:"not null;default:false;index" json:"hidden"`
+	HiddenReason HiddenReason   `gorm:"size:20;not null;default:''" json:"hidden_reason,omitempty"`
+	User         User           `gorm:"foreignKey:UserID" json:"user"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// HiddenReason — почему объявление скрыто из ленты.
+type HiddenReason string
+
+const (
+	// HiddenByReports — набралось достаточно открытых жалоб.
+	HiddenByReports HiddenReason = "reports"
+	// HiddenByScreening — сработал фильтр содержимого.
+	HiddenByScreening HiddenReason = "screening"
+	// HiddenByModerator — объявление скрыл модератор.
+	HiddenByModerator HiddenReason = "moderator"
+)
+
+// Automatic сообщает, что объявление скрыто автоматически, а не решением
+// модератора. Такое скрытие снимается, когда модератор отклоняет жалобы.
+func (r HiddenReason) Automatic() bool {
+	return r == HiddenByReports || r == HiddenByScreening
 }
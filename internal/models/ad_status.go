@@ -0,0 +1,59 @@
+package models
+
+// AdStatus — состояние объявления в его жизненном цикле.
+type AdStatus string
+
+const (
+	AdStatusDraft     AdStatus = "draft"
+	AdStatusPublished AdStatus = "published"
+	AdStatusReserved  AdStatus = "reserved"
+	AdStatusSold      AdStatus = "sold"
+	AdStatusArchived  AdStatus = "archived"
+	// AdStatusExpired выставляется только фоновой задачей по истечении expires_at.
+	AdStatusExpired AdStatus = "expired"
+)
+
+// adTransitions перечисляет допустимые переходы между состояниями.
+var adTransitions = map[AdStatus][]AdStatus{
+	AdStatusDraft:     {AdStatusPublished, AdStatusArchived},
+	AdStatusPublished: {AdStatusDraft, AdStatusReserved, AdStatusSold, AdStatusArchived, AdStatusExpired},
+	AdStatusReserved:  {AdStatusPublished, AdStatusSold, AdStatusArchived},
+	AdStatusSold:      {AdStatusArchived},
+	AdStatusArchived:  {AdStatusPublished},
+	AdStatusExpired:   {AdStatusPublished, AdStatusArchived},
+}
+
+// Valid сообщает, является ли значение известным состоянием.
+func (s AdStatus) Valid() bool {
+	_, ok := adTransitions[s]
+	return ok
+}
+
+// CanTransitionTo сообщает, разрешён ли переход из s в next.
+func (s AdStatus) CanTransitionTo(next AdStatus) bool {
+	for _, allowed := range adTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// EditableStatuses — состояния, в которых можно менять содержимое объявления.
+// Проданные и архивные объявления сохраняются как есть.
+var EditableStatuses = []AdStatus{AdStatusDraft, AdStatusPublished, AdStatusReserved, AdStatusExpired}
+
+// Editable сообщает, можно ли менять содержимое объявления в этом состоянии.
+func (s AdStatus) Editable() bool {
+	for _, editable := range EditableStatuses {
+		if s == editable {
+			return true
+		}
+	}
+	return false
+}
+
+// Renewable сообщает, можно ли продлить срок показа объявления.
+func (s AdStatus) Renewable() bool {
+	return s == AdStatusPublished || s == AdStatusExpired
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// AuctionStatus — состояние торгов.
+type AuctionStatus string
+
+const (
+	AuctionOpen   AuctionStatus = "open"
+	AuctionClosed AuctionStatus = "closed"
+)
+
+// Auction — условия и ход торгов по объявлению с ListingType auction.
+// Ставка принимается, только если Version не изменилась с момента чтения:
+// так параллельные ставки не перезаписывают друг друга.
+type Auction struct {
+	AdID         uint          `gorm:"primaryKey;autoIncrement:false" json:"ad_id"`
+	StartPrice   float64       `gorm:"not null" json:"start_price"`
+	ReservePrice *float64      `json:"-"` // скрыта от покупателей, видна только продавцу
+	MinIncrement float64       `gorm:"not null" json:"min_increment"`
+	EndsAt       time.Time     `gorm:"not null;index:idx_auctions_due,priority:2" json:"ends_at"`
+	Status       AuctionStatus `gorm:"size:20;not null;index:idx_auctions_due,priority:1" json:"status"`
+	CurrentBid   *float64      `json:"current_bid"`
+	LeaderID     *uint         `json:"leader_id"`
+	BidCount     int           `gorm:"not null;default:0" json:"bid_count"`
+	WinnerID     *uint         `json:"winner_id"`
+	Version      int           `gorm:"not null;default:0" json:"-"`
+	ClosedAt     *time.Time    `json:"closed_at"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// MinNextBid возвращает наименьшую ставку, которую примут сейчас.
+func (a *Auction) MinNextBid() float64 {
+	if a.CurrentBid == nil {
+		return a.StartPrice
+	}
+	return *a.CurrentBid + a.MinIncrement
+}
+
+// ReserveMet сообщает, достигла ли текущая ставка резервной цены.
+func (a *Auction) ReserveMet() bool {
+	if a.CurrentBid == nil {
+		return false
+	}
+	return a.ReservePrice == nil || *a.CurrentBid >= *a.ReservePrice
+}
+
+// Bid — ставка на аукционе.
+type Bid struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AdID      uint      `gorm:"not null;index" json:"ad_id"`
+	BidderID  uint      `gorm:"not null;index" json:"bidder_id"`
+	Amount    float64   `gorm:"not null" json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Favorite — объявление в избранном пользователя.
+type Favorite struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	AdID      uint      `gorm:"primaryKey;index" json:"ad_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AdImage — картинка объявления в хранилище. Position задаёт порядок показа.
+// ThumbKey и MediumKey заполняются фоновой обработкой и пусты, пока она не завершилась.
+type AdImage struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	AdID        uint      `gorm:"not null;index" json:"ad_id"`
+	Key         string    `gorm:"size:255;not null;uniqueIndex" json:"-"`
+	ThumbKey    string    `gorm:"size:255" json:"-"`
+	MediumKey   string    `gorm:"size:255" json:"-"`
+	ContentType string    `gorm:"size:50;not null" json:"content_type"`
+	Size        int64     `gorm:"not null" json:"size"`
+	Position    int       `gorm:"not null" json:"position"`
+	CreatedAt   time.Time `json:"created_at"`
+}
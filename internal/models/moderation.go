@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Действия модераторов.
+const (
+	ModerationAdEdited         = "ad_edited"
+	ModerationAdRemoved        = "ad_removed"
+	ModerationAdHidden         = "ad_hidden"
+	ModerationReportsDismissed = "reports_dismissed"
+	ModerationUserWarned       = "user_warned"
+	ModerationUserSuspended    = "user_suspended"
+)
+
+// ModerationAction — запись журнала модерации: кто, что и с чьим
+// объявлением сделал. AdID не ссылается на объявление внешним ключом,
+// чтобы запись переживала удаление объявления.
+type ModerationAction struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ModeratorID  uint      `gorm:"not null;index" json:"moderator_id"`
+	Moderator    User      `json:"-"`
+	Action       string    `gorm:"size:50;not null" json:"action"`
+	AdID         uint      `gorm:"not null;index" json:"ad_id"`
+	TargetUserID uint      `gorm:"not null;index" json:"target_user_id"`
+	Note         string    `gorm:"size:1000" json:"note"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
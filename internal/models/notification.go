@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Типы уведомлений.
+const (
+	NotificationSavedSearchMatch = "saved_search_match"
+	NotificationAdFavorited      = "ad_favorited"
+	NotificationFavoriteUpdated  = "favorite_updated"
+	NotificationAdRemoved        = "ad_removed"
+	NotificationAdEdited         = "ad_edited"
+	NotificationAdHidden         = "ad_hidden"
+	NotificationModerationWarn   = "moderation_warning"
+	NotificationOfferReceived    = "offer_received"
+	NotificationOfferCountered   = "offer_countered"
+	NotificationOfferAccepted    = "offer_accepted"
+	NotificationOfferRejected    = "offer_rejected"
+	NotificationOfferWithdrawn   = "offer_withdrawn"
+	NotificationOfferCancelled   = "offer_cancelled"
+	NotificationAuctionOutbid    = "auction_outbid"
+	NotificationAuctionWon       = "auction_won"
+	NotificationAuctionEnded     = "auction_ended"
+	NotificationReviewReceived   = "review_received"
+)
+
+// Notification — уведомление пользователя. Payload — JSON, формат которого
+// зависит от Type. DedupKey, если задан, не даёт создать пользователю
+// два уведомления об одном и том же.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index;uniqueIndex:idx_notifications_user_dedup" json:"user_id"`
+	Type      string     `gorm:"size:50;not null" json:"type"`
+	Payload   string     `gorm:"type:jsonb;not null" json:"payload"`
+	DedupKey  *string    `gorm:"size:100;uniqueIndex:idx_notifications_user_dedup" json:"-"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// OfferStatus — состояние предложения цены.
+type OfferStatus string
+
+const (
+	OfferPending   OfferStatus = "pending"
+	OfferAccepted  OfferStatus = "accepted"
+	OfferRejected  OfferStatus = "rejected"
+	OfferWithdrawn OfferStatus = "withdrawn"
+	// OfferCancelled — продавец снял резерв по принятому предложению.
+	OfferCancelled OfferStatus = "cancelled"
+	// OfferExpired выставляется фоновой задачей, если на предложение не ответили вовремя.
+	OfferExpired OfferStatus = "expired"
+)
+
+// Offer — торг покупателя с продавцом за объявление. Стороны по очереди
+// называют сумму: Amount — последнее предложение, ProposedBy — кто его сделал.
+// Ответить (принять, отклонить или предложить свою сумму) может только другая сторона.
+// У покупателя не больше одного открытого предложения на объявление.
+type Offer struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	AdID       uint        `gorm:"not null;index;uniqueIndex:idx_offers_open,where:status = 'pending'" json:"ad_id"`
+	BuyerID    uint        `gorm:"not null;index;uniqueIndex:idx_offers_open,where:status = 'pending'" json:"buyer_id"`
+	Buyer      User        `gorm:"foreignKey:BuyerID" json:"-"`
+	SellerID   uint        `gorm:"not null;index" json:"seller_id"`
+	Amount     float64     `gorm:"not null" json:"amount"`
+	ProposedBy uint        `gorm:"not null" json:"proposed_by"`
+	Status     OfferStatus `gorm:"size:20;not null;index" json:"status"`
+	ExpiresAt  time.Time   `gorm:"not null;index" json:"expires_at"`
+	Version    int         `gorm:"not null;default:0" json:"-"` // для оптимистичной блокировки
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// HasParticipant сообщает, участвует ли пользователь в торге.
+func (o *Offer) HasParticipant(userID uint) bool {
+	return o.BuyerID == userID || o.SellerID == userID
+}
+
+// AwaitsResponseFrom сообщает, что открытое предложение ждёт ответа от пользователя.
+func (o *Offer) AwaitsResponseFrom(userID uint, now time.Time) bool {
+	return o.Status == OfferPending && o.HasParticipant(userID) && o.ProposedBy != userID && now.Before(o.ExpiresAt)
+}
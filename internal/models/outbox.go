@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// OutboxEvent — событие, записанное в той же транзакции, что и изменение,
+// которое его вызвало. Фоновый диспетчер публикует его и отмечает PublishedAt;
+// если процесс упадёт раньше, событие будет опубликовано после перезапуска.
+type OutboxEvent struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Type          string     `gorm:"size:50;not null" json:"type"`
+	AggregateID   uint       `gorm:"not null" json:"aggregate_id"` // ID объявления
+	UserID        uint       `gorm:"not null" json:"user_id"`      // владелец объявления
+	Payload       string     `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index:idx_outbox_pending,where:published_at IS NULL" json:"next_attempt_at"`
+	LastError     string     `gorm:"size:500" json:"last_error"`
+	PublishedAt   *time.Time `gorm:"index" json:"published_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// ReportReason — причина жалобы на объявление.
+type ReportReason string
+
+const (
+	ReportScam       ReportReason = "scam"
+	ReportProhibited ReportReason = "prohibited"
+	ReportSpam       ReportReason = "spam"
+	ReportOffensive  ReportReason = "offensive"
+	ReportOther      ReportReason = "other"
+	// ReportAutomated — объявление отправил на модерацию фильтр содержимого.
+	// Пользователь такую причину указать не может.
+	ReportAutomated ReportReason = "automated"
+)
+
+// Valid сообщает, что пользователь может указать такую причину.
+func (r ReportReason) Valid() bool {
+	switch r {
+	case ReportScam, ReportProhibited, ReportSpam, ReportOffensive, ReportOther:
+		return true
+	}
+	return false
+}
+
+// ReportStatus — состояние жалобы.
+type ReportStatus string
+
+const (
+	ReportOpen     ReportStatus = "open"
+	ReportResolved ReportStatus = "resolved"
+)
+
+// ReportResolutionAdDeleted закрывает жалобы на объявление, удалённое без
+// решения модератора по жалобам.
+const ReportResolutionAdDeleted = "ad_deleted"
+
+// Report — жалоба на объявление от пользователя или, без ReporterID,
+// от фильтра содержимого. Пока жалоба пользователя на объявление открыта,
+// вторую он подать не может; после решения модератора — снова может.
+// Resolution — действие модератора, которым жалоба закрыта.
+type Report struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	AdID       uint         `gorm:"not null;uniqueIndex:idx_reports_ad_reporter,where:status = 'open'" json:"ad_id"`
+	ReporterID *uint        `gorm:"uniqueIndex:idx_reports_ad_reporter,where:status = 'open'" json:"reporter_id"`
+	Reporter   *User        `json:"-"`
+	Reason     ReportReason `gorm:"size:20;not null" json:"reason"`
+	Comment    string       `gorm:"size:500" json:"comment"`
+	Status     ReportStatus `gorm:"size:20;not null;default:open;index" json:"status"`
+	Resolution string       `gorm:"size:50" json:"resolution,omitempty"`
+	ResolvedAt *time.Time   `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
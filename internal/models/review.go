@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Review — оценка и отзыв участника сделки о другой стороне. После продажи
+// объявления покупатель и продавец могут оставить друг другу по одному отзыву.
+type Review struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AdID      uint      `gorm:"not null;uniqueIndex:idx_reviews_ad_author" json:"ad_id"`
+	AuthorID  uint      `gorm:"not null;uniqueIndex:idx_reviews_ad_author" json:"author_id"`
+	Author    User      `gorm:"foreignKey:AuthorID" json:"-"`
+	TargetID  uint      `gorm:"not null;index" json:"target_id"`
+	Rating    int       `gorm:"not null" json:"rating"`
+	Text      string    `gorm:"size:1000" json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "slices"
+
+// Role — роль пользователя. От роли зависят его права.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Permission — право на действие, которое обычному пользователю недоступно.
+type Permission string
+
+const (
+	// PermModerateAds — изменять и удалять чужие объявления.
+	PermModerateAds Permission = "ads:moderate"
+	// PermManageCategories — изменять дерево разделов каталога.
+	PermManageCategories Permission = "categories:manage"
+	// PermWebhooksAllAds — подписывать вебхуки на события обо всех объявлениях.
+	PermWebhooksAllAds Permission = "webhooks:all_ads"
+	// PermManageUsers — назначать роли пользователям.
+	PermManageUsers Permission = "users:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser:      {},
+	RoleModerator: {PermModerateAds},
+	RoleAdmin:     {PermModerateAds, PermManageCategories, PermWebhooksAllAds, PermManageUsers},
+}
+
+// Valid сообщает, что роль известна.
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions возвращает права роли. У неизвестной роли прав нет.
+func (r Role) Permissions() []Permission {
+	return slices.Clone(rolePermissions[r])
+}
+
+// RolesWith возвращает роли, у которых есть право p.
+func RolesWith(p Permission) []Role {
+	var roles []Role
+	for role, perms := range rolePermissions {
+		if slices.Contains(perms, p) {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+// Can сообщает, есть ли у роли право p.
+func (r Role) Can(p Permission) bool {
+	return slices.Contains(rolePermissions[r], p)
+}
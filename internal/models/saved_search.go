@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SavedSearch — сохранённые параметры поиска по ленте. Когда публикуется
+// подходящее объявление, владелец поиска получает уведомление.
+type SavedSearch struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	Name       string    `gorm:"size:100;not null" json:"name"`
+	Query      string    `gorm:"size:200" json:"q"`
+	MinPrice   *float64  `json:"min_price"`
+	MaxPrice   *float64  `json:"max_price"`
+	CategoryID *uint     `json:"category_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
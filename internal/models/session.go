@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Session объединяет цепочку refresh-токенов, выданных при одном входе.
+// Отзыв сессии делает недействительными все её access- и refresh-токены.
+type Session struct {
+	ID        string     `gorm:"primaryKey;size:64" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RefreshToken хранит хэш непрозрачного refresh-токена.
+// Токен одноразовый: при обновлении он помечается использованным и заменяется новым.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	SessionID string     `gorm:"size:64;not null;index" json:"session_id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
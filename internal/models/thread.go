@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Thread — переписка покупателя с продавцом по объявлению.
+// На пару объявление–покупатель приходится одна переписка.
+// Прочитанность хранится как ID последнего прочитанного сообщения каждого участника.
+type Thread struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	AdID             uint      `gorm:"not null;uniqueIndex:idx_threads_ad_buyer" json:"ad_id"`
+	BuyerID          uint      `gorm:"not null;uniqueIndex:idx_threads_ad_buyer;index" json:"buyer_id"`
+	Buyer            User      `gorm:"foreignKey:BuyerID" json:"buyer"`
+	SellerID         uint      `gorm:"not null;index" json:"seller_id"`
+	Seller           User      `gorm:"foreignKey:SellerID" json:"seller"`
+	BuyerLastReadID  uint      `gorm:"not null;default:0" json:"-"`
+	SellerLastReadID uint      `gorm:"not null;default:0" json:"-"`
+	LastMessageAt    time.Time `gorm:"index" json:"last_message_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// HasParticipant сообщает, участвует ли пользователь в переписке.
+func (t *Thread) HasParticipant(userID uint) bool {
+	return t.BuyerID == userID || t.SellerID == userID
+}
+
+// Message — сообщение в переписке.
+type Message struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ThreadID  uint      `gorm:"not null;index" json:"thread_id"`
+	SenderID  uint      `gorm:"not null" json:"sender_id"`
+	Body      string    `gorm:"size:2000;not null" json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
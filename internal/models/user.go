@@ -1,10 +1,66 @@
 package models
 
-import "time"
+import (
+	"math"
+	"time"
+)
+
+// UserStatus — состояние учётной записи.
+type UserStatus string
+
+const (
+	UserActive    UserStatus = "active"
+	UserSuspended UserStatus = "suspended"
+	// UserBanned, в отличие от UserSuspended, ещё и скрывает объявления
+	// пользователя из ленты.
+	UserBanned UserStatus = "banned"
+)
+
+// Valid сообщает, известен ли статус.
+func (s UserStatus) Valid() bool {
+	switch s {
+	case UserActive, UserSuspended, UserBanned:
+		return true
+	}
+	return false
+}
 
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Username  string    `gorm:"uniqueIndex;not null" json:"username"`
-	Password  string    `gorm:"not null" json:"-"` // скрыт в JSON
-	CreatedAt time.Time `json:"created_at"`
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Username string `gorm:"uniqueIndex;not null" json:"username"`
+	Password string `gorm:"not null" json:"-"` // скрыт в JSON
+	Role     Role   `gorm:"size:20;not null;default:user" json:"-"`
+	// Email необязателен у старых учётных записей; пустые адреса не участвуют в уникальности.
+	Email           string     `gorm:"size:255;not null;default:'';uniqueIndex:idx_users_email,where:email <> ''" json:"-"`
+	EmailVerifiedAt *time.Time `json:"-"`
+	// Status, StatusUntil и StatusReason описывают ограничение учётной записи.
+	Status       UserStatus `gorm:"size:20;not null;default:active" json:"-"`
+	StatusUntil  *time.Time `json:"-"`
+	StatusReason string     `gorm:"size:1000" json:"-"`
+	// Сумма оценок и число отзывов; меняются только через UserRepository.AddRating.
+	RatingSum   int64     `gorm:"not null;default:0" json:"-"`
+	ReviewCount int64     `gorm:"not null;default:0" json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Can сообщает, есть ли у пользователя право p.
+func (u *User) Can(p Permission) bool {
+	return u.Role.Can(p)
+}
+
+// StatusAt возвращает статус, действующий в момент now: ограничение
+// с истёкшим StatusUntil снимается само.
+func (u *User) StatusAt(now time.Time) UserStatus {
+	if u.Status == "" || u.StatusUntil != nil && !u.StatusUntil.After(now) {
+		return UserActive
+	}
+	return u.Status
+}
+
+// Rating возвращает среднюю оценку пользователя или 0, если отзывов нет.
+func (u *User) Rating() float64 {
+	if u.ReviewCount == 0 {
+		return 0
+	}
+	return math.Round(float64(u.RatingSum)/float64(u.ReviewCount)*100) / 100
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TokenPurpose — назначение одноразового токена из письма.
+type TokenPurpose string
+
+const (
+	TokenEmailVerification TokenPurpose = "email_verification"
+	TokenPasswordReset     TokenPurpose = "password_reset"
+)
+
+// UserToken хранит хэш одноразового токена, отправленного пользователю
+// по почте. Токен действует до ExpiresAt и только один раз.
+type UserToken struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	UserID    uint         `gorm:"not null;index" json:"user_id"`
+	Purpose   TokenPurpose `gorm:"size:30;not null" json:"purpose"`
+	TokenHash string       `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time    `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time   `json:"used_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Webhook — адрес, на который отправляются события об объявлениях.
+// Обычный пользователь получает события только о своих объявлениях,
+// вебхук администратора с AllAds — обо всех.
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	URL       string    `gorm:"size:500;not null" json:"url"`
+	Events    string    `gorm:"size:255;not null" json:"-"` // через запятую
+	Secret    string    `gorm:"size:100;not null" json:"-"`
+	AllAds    bool      `gorm:"not null;default:false" json:"all_ads"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventList возвращает список событий вебхука.
+func (w *Webhook) EventList() []string {
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribed сообщает, подписан ли вебхук на событие.
+func (w *Webhook) Subscribed(event string) bool {
+	return slices.Contains(w.EventList(), event)
+}
+
+// Состояния доставки вебхука.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery — одна доставка события на вебхук вместе с историей попыток.
+// Тело запроса фиксируется при создании, поэтому повторы отправляют то же самое.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	WebhookID      uint       `gorm:"not null;index" json:"webhook_id"`
+	Event          string     `gorm:"size:50;not null" json:"event"`
+	Payload        string     `gorm:"type:jsonb;not null" json:"-"`
+	Status         string     `gorm:"size:20;not null;index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	LastError      string     `gorm:"size:500" json:"last_error"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,60 @@
+// Package ratelimit ограничивает частоту действий по ключу: адресу почты,
+// IP-адресу клиента.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter разрешает не больше limit действий на ключ за окно window
+// (фиксированные окна). Состояние хранится в памяти процесса, поэтому при
+// нескольких экземплярах сервиса лимит действует на каждый отдельно.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]bucket
+	calls   int
+}
+
+type bucket struct {
+	count   int
+	resetAt time.Time
+}
+
+// pruneEvery — через сколько вызовов Allow удаляются истёкшие окна.
+const pruneEvery = 1000
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window, now: time.Now, buckets: make(map[string]bucket)}
+}
+
+// Allow учитывает действие с ключом key и сообщает, укладывается ли оно в лимит.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls%pruneEvery == 0 {
+		for k, b := range l.buckets {
+			if !now.Before(b.resetAt) {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	b := l.buckets[key]
+	if !now.Before(b.resetAt) {
+		b = bucket{resetAt: now.Add(l.window)}
+	}
+	if b.count >= l.limit {
+		return false
+	}
+	b.count++
+	l.buckets[key] = b
+	return true
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Hour)
+	l.now = func() time.Time { return now }
+
+	if !l.Allow("a") || !l.Allow("a") {
+		t.Fatal("Первые два действия должны пройти")
+	}
+	if l.Allow("a") {
+		t.Error("Третье действие в окне должно быть отклонено")
+	}
+	if !l.Allow("b") {
+		t.Error("Лимит считается отдельно для каждого ключа")
+	}
+
+	now = now.Add(time.Hour)
+	if !l.Allow("a") {
+		t.Error("В новом окне лимит обнуляется")
+	}
+}
+
+func TestLimiterPrunesExpiredKeys(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("old")
+	now = now.Add(time.Minute)
+	for range pruneEvery {
+		l.Allow("new")
+	}
+	if _, ok := l.buckets["old"]; ok {
+		t.Error("Истёкшие окна должны удаляться")
+	}
+}
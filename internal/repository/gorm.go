@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// translateError приводит ошибки GORM к ошибкам пакета.
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
+
+type txKey struct{}
+
+// GormTransactor открывает транзакции Postgres. Репозитории GORM, получившие
+// контекст из Transaction, выполняют запросы внутри неё.
+type GormTransactor struct {
+	db *gorm.DB
+}
+
+func NewGormTransactor(db *gorm.DB) *GormTransactor {
+	return &GormTransactor{db: db}
+}
+
+func (t *GormTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn возвращает соединение для запроса: открытую транзакцию из ctx,
+// если она есть, иначе db.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// GormUserRepository хранит пользователей в Postgres через GORM.
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+func (r *GormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return translateError(conn(ctx, r.db).Create(user).Error)
+}
+
+func (r *GormUserRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := conn(ctx, r.db).First(&user, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := conn(ctx, r.db).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if email == "" {
+		return nil, ErrNotFound
+	}
+	var user models.User
+	if err := conn(ctx, r.db).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// Пользователь меняется только точечными методами: сохранение устаревшей
+// копии целиком затёрло бы параллельную смену роли, статуса или рейтинга.
+
+func (r *GormUserRepository) SetPassword(ctx context.Context, userID uint, hash string) error {
+	return r.update(ctx, userID, map[string]any{"password": hash})
+}
+
+func (r *GormUserRepository) MarkEmailVerified(ctx context.Context, userID uint, at time.Time) error {
+	err := conn(ctx, r.db).
+		Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", userID).
+		Update("email_verified_at", at).Error
+	return translateError(err)
+}
+
+func (r *GormUserRepository) SetRole(ctx context.Context, userID uint, role models.Role) error {
+	return r.update(ctx, userID, map[string]any{"role": role})
+}
+
+func (r *GormUserRepository) SetStatus(ctx context.Context, userID uint, status models.UserStatus, until *time.Time, reason string) error {
+	return r.update(ctx, userID, map[string]any{"status": status, "status_until": until, "status_reason": reason})
+}
+
+func (r *GormUserRepository) ClearExpiredStatus(ctx context.Context, userID uint, now time.Time) error {
+	err := conn(ctx, r.db).
+		Model(&models.User{}).
+		Where("id = ? AND status <> ? AND status_until <= ?", userID, models.UserActive, now).
+		Updates(map[string]any{"status": models.UserActive, "status_until": nil, "status_reason": ""}).Error
+	return translateError(err)
+}
+
+func (r *GormUserRepository) update(ctx context.Context, userID uint, columns map[string]any) error {
+	res := conn(ctx, r.db).Model(&models.User{}).Where("id = ?", userID).Updates(columns)
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormUserRepository) AddRating(ctx context.Context, userID uint, rating int) error {
+	res := conn(ctx, r.db).
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]any{
+			"rating_sum":   gorm.Expr("rating_sum + ?", rating),
+			"review_count": gorm.Expr("review_count + 1"),
+		})
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GormAdRepository хранит объявления в Postgres через GORM.
+type GormAdRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAdRepository(db *gorm.DB) *GormAdRepository {
+	return &GormAdRepository{db: db}
+}
+
+func (r *GormAdRepository) Create(ctx context.Context, ad *models.Ad) error {
+	return translateError(conn(ctx, r.db).Create(ad).Error)
+}
+
+func (r *GormAdRepository) GetByID(ctx context.Context, id uint) (*models.Ad, error) {
+	var ad models.Ad
+	if err := conn(ctx, r.db).Preload("User").First(&ad, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &ad, nil
+}
+
+func (r *GormAdRepository) Update(ctx context.Context, ad *models.Ad) error {
+	ad.UpdatedAt = time.Now()
+	// Обновляются только поля содержимого: параллельная смена состояния,
+	// покупателя или скрытия не затирается устаревшей копией. Цену аукциона
+	// ведут ставки, поэтому она сохраняется только у фиксированной цены
+	columns := []string{"title", "description", "image_url", "category_id", "updated_at"}
+	if ad.ListingType != models.ListingAuction {
+		columns = append(columns, "price")
+	}
+	res := conn(ctx, r.db).
+		Model(&models.Ad{}).
+		Where("id = ? AND status IN ?", ad.ID, models.EditableStatuses).
+		Select(columns).
+		Updates(ad)
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		if _, err := r.GetByID(ctx, ad.ID); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *GormAdRepository) Delete(ctx context.Context, ad *models.Ad) error {
+	return translateError(conn(ctx, r.db).Delete(ad).Error)
+}
+
+func (r *GormAdRepository) UpdateStatus(ctx context.Context, id uint, from, to models.AdStatus, expiresAt *time.Time) error {
+	updates := map[string]any{"status": to, "updated_at": time.Now()}
+	if expiresAt != nil {
+		updates["expires_at"] = *expiresAt
+	}
+
+	res := conn(ctx, r.db).
+		Model(&models.Ad{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(updates)
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *GormAdRepository) SetBuyer(ctx context.Context, id uint, buyerID *uint) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.Ad{}).
+		Where("id = ?", id).
+		Update("buyer_id", buyerID).Error)
+}
+
+func (r *GormAdRepository) SetHidden(ctx context.Context, id uint, reason models.HiddenReason) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.Ad{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"hidden": reason != "", "hidden_reason": reason}).Error)
+}
+
+func (r *GormAdRepository) ExpirePublished(ctx context.Context, now time.Time) (int64, error) {
+	res := conn(ctx, r.db).
+		Model(&models.Ad{}).
+		Where("status = ? AND expires_at <= ?", models.AdStatusPublished, now).
+		Updates(map[string]any{"status": models.AdStatusExpired, "updated_at": now})
+	return res.RowsAffected, translateError(res.Error)
+}
+
+func (r *GormAdRepository) List(ctx context.Context, filter AdFilter) ([]models.Ad, error) {
+	query := applyAdFilter(conn(ctx, r.db).Preload("User"), filter)
+
+	if filter.After != nil && filter.SortField != "relevance" {
+		// Сравнение кортежей совпадает с порядком ORDER BY поле, id.
+		op := ">"
+		if filter.Order == "DESC" {
+			op = "<"
+		}
+		query = query.Where("("+filter.SortField+", id) "+op+" (?, ?)", filter.After.Value(filter.SortField), filter.After.ID)
+	}
+
+	if filter.SortField == "relevance" {
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "ts_rank(search_vector, websearch_to_tsquery('" + searchConfig + "', ?)) " + filter.Order + ", id " + filter.Order,
+			Vars: []any{filter.Query},
+		}})
+	} else {
+		query = query.Order(filter.SortField + " " + filter.Order + ", id " + filter.Order)
+	}
+
+	var ads []models.Ad
+	err := query.
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&ads).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return ads, nil
+}
+
+func (r *GormAdRepository) Count(ctx context.Context, filter AdFilter) (int64, error) {
+	var total int64
+	err := applyAdFilter(conn(ctx, r.db).Model(&models.Ad{}), filter).Count(&total).Error
+	return total, translateError(err)
+}
+
+// applyAdFilter добавляет к запросу условия фильтра без сортировки и пагинации.
+func applyAdFilter(query *gorm.DB, filter AdFilter) *gorm.DB {
+	if len(filter.IDs) > 0 {
+		query = query.Where("id IN ?", filter.IDs)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("category_id IN ?", filter.CategoryIDs)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if !filter.ActiveAt.IsZero() {
+		query = query.Where("expires_at IS NULL OR expires_at > ?", filter.ActiveAt)
+	}
+	if filter.VisibleOnly {
+		query = query.Where("NOT hidden").
+			Where("user_id NOT IN (SELECT id FROM users WHERE status = ? AND (status_until IS NULL OR status_until > ?))",
+				models.UserBanned, time.Now())
+	}
+	if filter.Query != "" {
+		query = query.Where("search_vector @@ websearch_to_tsquery('"+searchConfig+"', ?)", filter.Query)
+	}
+	return query
+}
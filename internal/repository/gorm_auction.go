@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormAuctionRepository хранит аукционы и ставки в Postgres через GORM.
+type GormAuctionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuctionRepository(db *gorm.DB) *GormAuctionRepository {
+	return &GormAuctionRepository{db: db}
+}
+
+func (r *GormAuctionRepository) Create(ctx context.Context, auction *models.Auction) error {
+	return translateError(conn(ctx, r.db).Create(auction).Error)
+}
+
+func (r *GormAuctionRepository) Get(ctx context.Context, adID uint) (*models.Auction, error) {
+	var auction models.Auction
+	if err := conn(ctx, r.db).First(&auction, "ad_id = ?", adID).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &auction, nil
+}
+
+func (r *GormAuctionRepository) ListByAds(ctx context.Context, adIDs []uint) (map[uint]models.Auction, error) {
+	result := make(map[uint]models.Auction)
+	if len(adIDs) == 0 {
+		return result, nil
+	}
+
+	var auctions []models.Auction
+	if err := conn(ctx, r.db).Where("ad_id IN ?", adIDs).Find(&auctions).Error; err != nil {
+		return nil, translateError(err)
+	}
+	for _, a := range auctions {
+		result[a.AdID] = a
+	}
+	return result, nil
+}
+
+func (r *GormAuctionRepository) PlaceBid(ctx context.Context, auction *models.Auction, bid *models.Bid) error {
+	return translateError(conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Auction{}).
+			Where("ad_id = ? AND version = ? AND status = ?", auction.AdID, auction.Version, models.AuctionOpen).
+			Updates(map[string]any{
+				"current_bid": auction.CurrentBid,
+				"leader_id":   auction.LeaderID,
+				"bid_count":   auction.BidCount,
+				"ends_at":     auction.EndsAt,
+				"version":     auction.Version + 1,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrConflict
+		}
+
+		if err := tx.Create(bid).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Ad{}).
+			Where("id = ?", auction.AdID).
+			Updates(map[string]any{"price": bid.Amount, "updated_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+
+		auction.Version++
+		return nil
+	}))
+}
+
+func (r *GormAuctionRepository) ListBids(ctx context.Context, adID uint, limit, offset int) ([]models.Bid, error) {
+	var bids []models.Bid
+	err := conn(ctx, r.db).
+		Where("ad_id = ?", adID).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&bids).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return bids, nil
+}
+
+func (r *GormAuctionRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.Auction, error) {
+	var auctions []models.Auction
+	err := conn(ctx, r.db).
+		Where("status = ? AND ends_at <= ?", models.AuctionOpen, now).
+		Order("ends_at").
+		Limit(limit).
+		Find(&auctions).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return auctions, nil
+}
+
+func (r *GormAuctionRepository) Close(ctx context.Context, auction *models.Auction) error {
+	res := conn(ctx, r.db).
+		Model(&models.Auction{}).
+		Where("ad_id = ? AND version = ? AND status = ?", auction.AdID, auction.Version, models.AuctionOpen).
+		Updates(map[string]any{
+			"status":    models.AuctionClosed,
+			"winner_id": auction.WinnerID,
+			"closed_at": auction.ClosedAt,
+			"version":   auction.Version + 1,
+		})
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrConflict
+	}
+	auction.Status = models.AuctionClosed
+	auction.Version++
+	return nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormCategoryRepository хранит разделы каталога в Postgres через GORM.
+type GormCategoryRepository struct {
+	db *gorm.DB
+}
+
+func NewGormCategoryRepository(db *gorm.DB) *GormCategoryRepository {
+	return &GormCategoryRepository{db: db}
+}
+
+func (r *GormCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	return translateError(conn(ctx, r.db).Create(category).Error)
+}
+
+func (r *GormCategoryRepository) GetByID(ctx context.Context, id uint) (*models.Category, error) {
+	var category models.Category
+	if err := conn(ctx, r.db).First(&category, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &category, nil
+}
+
+func (r *GormCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	return translateError(conn(ctx, r.db).Save(category).Error)
+}
+
+func (r *GormCategoryRepository) Delete(ctx context.Context, category *models.Category) error {
+	return translateError(conn(ctx, r.db).Delete(category).Error)
+}
+
+func (r *GormCategoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	if err := conn(ctx, r.db).Order("name ASC").Find(&categories).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return categories, nil
+}
+
+func (r *GormCategoryRepository) DescendantIDs(ctx context.Context, id uint) ([]uint, error) {
+	var ids []uint
+	err := conn(ctx, r.db).Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION ALL
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT id FROM tree`, id).Scan(&ids).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if len(ids) == 0 {
+		return nil, ErrNotFound
+	}
+	return ids, nil
+}
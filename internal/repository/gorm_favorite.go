@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormFavoriteRepository хранит избранное в Postgres через GORM.
+type GormFavoriteRepository struct {
+	db *gorm.DB
+}
+
+func NewGormFavoriteRepository(db *gorm.DB) *GormFavoriteRepository {
+	return &GormFavoriteRepository{db: db}
+}
+
+func (r *GormFavoriteRepository) Add(ctx context.Context, userID, adID uint) error {
+	return translateError(conn(ctx, r.db).Create(&models.Favorite{UserID: userID, AdID: adID}).Error)
+}
+
+func (r *GormFavoriteRepository) Remove(ctx context.Context, userID, adID uint) error {
+	res := conn(ctx, r.db).Delete(&models.Favorite{}, "user_id = ? AND ad_id = ?", userID, adID)
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormFavoriteRepository) ListAds(ctx context.Context, userID uint, statuses []models.AdStatus, limit, offset int) ([]models.Ad, error) {
+	query := conn(ctx, r.db).
+		Preload("User").
+		Joins("JOIN favorites ON favorites.ad_id = ads.id AND favorites.user_id = ?", userID)
+	if len(statuses) > 0 {
+		query = query.Where("ads.status IN ?", statuses)
+	}
+
+	var ads []models.Ad
+	err := query.
+		Order("favorites.created_at DESC, ads.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&ads).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return ads, nil
+}
+
+func (r *GormFavoriteRepository) FavoritedAdIDs(ctx context.Context, userID uint, adIDs []uint) (map[uint]bool, error) {
+	result := make(map[uint]bool)
+	if len(adIDs) == 0 {
+		return result, nil
+	}
+
+	var ids []uint
+	err := conn(ctx, r.db).
+		Model(&models.Favorite{}).
+		Where("user_id = ? AND ad_id IN ?", userID, adIDs).
+		Pluck("ad_id", &ids).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
+
+func (r *GormFavoriteRepository) CountByAds(ctx context.Context, adIDs []uint) (map[uint]int64, error) {
+	result := make(map[uint]int64)
+	if len(adIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		AdID  uint
+		Count int64
+	}
+	err := conn(ctx, r.db).
+		Model(&models.Favorite{}).
+		Select("ad_id, COUNT(*) AS count").
+		Where("ad_id IN ?", adIDs).
+		Group("ad_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	for _, row := range rows {
+		result[row.AdID] = row.Count
+	}
+	return result, nil
+}
+
+func (r *GormFavoriteRepository) UserIDsByAd(ctx context.Context, adID uint) ([]uint, error) {
+	var ids []uint
+	err := conn(ctx, r.db).
+		Model(&models.Favorite{}).
+		Where("ad_id = ?", adID).
+		Order("user_id").
+		Pluck("user_id", &ids).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return ids, nil
+}
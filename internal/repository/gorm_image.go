@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormAdImageRepository хранит метаданные картинок в Postgres через GORM.
+type GormAdImageRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAdImageRepository(db *gorm.DB) *GormAdImageRepository {
+	return &GormAdImageRepository{db: db}
+}
+
+func (r *GormAdImageRepository) Append(ctx context.Context, image *models.AdImage, limit int) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// Блокировка строки объявления выстраивает параллельные загрузки
+		// в очередь: каждая видит картинки, сохранённые предыдущими
+		if err := tx.Exec("SELECT id FROM ads WHERE id = ? FOR UPDATE", image.AdID).Error; err != nil {
+			return err
+		}
+
+		var stats struct {
+			Count int64
+			Next  int
+		}
+		err := tx.Model(&models.AdImage{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(position) + 1, 0) AS next").
+			Where("ad_id = ?", image.AdID).
+			Scan(&stats).Error
+		if err != nil {
+			return err
+		}
+		if stats.Count >= int64(limit) {
+			return ErrConflict
+		}
+
+		image.Position = stats.Next
+		return tx.Create(image).Error
+	})
+	return translateError(err)
+}
+
+func (r *GormAdImageRepository) GetByID(ctx context.Context, id uint) (*models.AdImage, error) {
+	var image models.AdImage
+	if err := conn(ctx, r.db).First(&image, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &image, nil
+}
+
+// UpdatePosition и UpdateVariants меняют только свои столбцы, чтобы смена
+// порядка и фоновая обработка не затирали результаты друг друга.
+func (r *GormAdImageRepository) UpdatePosition(ctx context.Context, id uint, position int) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.AdImage{}).
+		Where("id = ?", id).
+		Update("position", position).Error)
+}
+
+func (r *GormAdImageRepository) UpdateVariants(ctx context.Context, id uint, thumbKey, mediumKey string) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.AdImage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"thumb_key": thumbKey, "medium_key": mediumKey}).Error)
+}
+
+func (r *GormAdImageRepository) Delete(ctx context.Context, image *models.AdImage) error {
+	return translateError(conn(ctx, r.db).Delete(image).Error)
+}
+
+func (r *GormAdImageRepository) ListByAd(ctx context.Context, adID uint) ([]models.AdImage, error) {
+	return r.ListByAds(ctx, []uint{adID})
+}
+
+func (r *GormAdImageRepository) ListByAds(ctx context.Context, adIDs []uint) ([]models.AdImage, error) {
+	var images []models.AdImage
+	if len(adIDs) == 0 {
+		return images, nil
+	}
+	err := conn(ctx, r.db).
+		Where("ad_id IN ?", adIDs).
+		Order("ad_id, position, id").
+		Find(&images).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return images, nil
+}
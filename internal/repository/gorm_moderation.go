@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormModerationRepository хранит журнал модерации в Postgres через GORM.
+type GormModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewGormModerationRepository(db *gorm.DB) *GormModerationRepository {
+	return &GormModerationRepository{db: db}
+}
+
+func (r *GormModerationRepository) Record(ctx context.Context, action *models.ModerationAction) error {
+	return translateError(conn(ctx, r.db).Omit("Moderator").Create(action).Error)
+}
+
+func (r *GormModerationRepository) List(ctx context.Context, adID uint, limit, offset int) ([]models.ModerationAction, error) {
+	query := conn(ctx, r.db).Preload("Moderator")
+	if adID != 0 {
+		query = query.Where("ad_id = ?", adID)
+	}
+
+	var actions []models.ModerationAction
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&actions).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return actions, nil
+}
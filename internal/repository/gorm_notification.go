@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormNotificationRepository хранит уведомления в Postgres через GORM.
+type GormNotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewGormNotificationRepository(db *gorm.DB) *GormNotificationRepository {
+	return &GormNotificationRepository{db: db}
+}
+
+func (r *GormNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	return translateError(conn(ctx, r.db).Create(notification).Error)
+}
+
+func (r *GormNotificationRepository) ListByUser(ctx context.Context, userID uint, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	query := conn(ctx, r.db).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []models.Notification
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return notifications, nil
+}
+
+func (r *GormNotificationRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	var n int64
+	err := conn(ctx, r.db).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&n).Error
+	return n, translateError(err)
+}
+
+func (r *GormNotificationRepository) MarkRead(ctx context.Context, userID, id uint) error {
+	res := conn(ctx, r.db).
+		Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", time.Now())
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 1 {
+		return nil
+	}
+
+	// Ничего не изменилось: уведомление уже прочитано или не принадлежит пользователю
+	var n int64
+	err := conn(ctx, r.db).
+		Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Count(&n).Error
+	if err != nil {
+		return translateError(err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormNotificationRepository) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	res := conn(ctx, r.db).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return res.RowsAffected, translateError(res.Error)
+}
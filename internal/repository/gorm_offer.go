@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormOfferRepository хранит предложения цены в Postgres через GORM.
+type GormOfferRepository struct {
+	db *gorm.DB
+}
+
+func NewGormOfferRepository(db *gorm.DB) *GormOfferRepository {
+	return &GormOfferRepository{db: db}
+}
+
+func (r *GormOfferRepository) Create(ctx context.Context, offer *models.Offer) error {
+	return translateError(conn(ctx, r.db).Omit("Buyer").Create(offer).Error)
+}
+
+func (r *GormOfferRepository) GetByID(ctx context.Context, id uint) (*models.Offer, error) {
+	var offer models.Offer
+	if err := conn(ctx, r.db).Preload("Buyer").First(&offer, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &offer, nil
+}
+
+func (r *GormOfferRepository) ListByAd(ctx context.Context, adID uint, buyerID *uint) ([]models.Offer, error) {
+	query := conn(ctx, r.db).Preload("Buyer").Where("ad_id = ?", adID)
+	if buyerID != nil {
+		query = query.Where("buyer_id = ?", *buyerID)
+	}
+
+	var offers []models.Offer
+	if err := query.Order("created_at DESC, id DESC").Find(&offers).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return offers, nil
+}
+
+func (r *GormOfferRepository) ListByBuyer(ctx context.Context, buyerID uint, limit, offset int) ([]models.Offer, error) {
+	var offers []models.Offer
+	err := conn(ctx, r.db).
+		Preload("Buyer").
+		Where("buyer_id = ?", buyerID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&offers).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return offers, nil
+}
+
+func (r *GormOfferRepository) Update(ctx context.Context, offer *models.Offer) error {
+	now := time.Now()
+	res := conn(ctx, r.db).
+		Model(&models.Offer{}).
+		Where("id = ? AND version = ?", offer.ID, offer.Version).
+		Updates(map[string]any{
+			"amount":      offer.Amount,
+			"proposed_by": offer.ProposedBy,
+			"status":      offer.Status,
+			"expires_at":  offer.ExpiresAt,
+			"version":     offer.Version + 1,
+			"updated_at":  now,
+		})
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrConflict
+	}
+	offer.Version++
+	offer.UpdatedAt = now
+	return nil
+}
+
+func (r *GormOfferRepository) RejectPending(ctx context.Context, adID, exceptID uint) ([]models.Offer, error) {
+	var offers []models.Offer
+	err := conn(ctx, r.db).Raw(`
+		UPDATE offers SET status = ?, version = version + 1, updated_at = ?
+		WHERE ad_id = ? AND id <> ? AND status = ?
+		RETURNING *`,
+		models.OfferRejected, time.Now(), adID, exceptID, models.OfferPending,
+	).Scan(&offers).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return offers, nil
+}
+
+func (r *GormOfferRepository) CancelAccepted(ctx context.Context, adID uint) ([]models.Offer, error) {
+	var offers []models.Offer
+	err := conn(ctx, r.db).Raw(`
+		UPDATE offers SET status = ?, version = version + 1, updated_at = ?
+		WHERE ad_id = ? AND status = ?
+		RETURNING *`,
+		models.OfferCancelled, time.Now(), adID, models.OfferAccepted,
+	).Scan(&offers).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return offers, nil
+}
+
+func (r *GormOfferRepository) ExpirePending(ctx context.Context, now time.Time) (int64, error) {
+	res := conn(ctx, r.db).
+		Model(&models.Offer{}).
+		Where("status = ? AND expires_at <= ?", models.OfferPending, now).
+		Updates(map[string]any{"status": models.OfferExpired, "version": gorm.Expr("version + 1"), "updated_at": now})
+	return res.RowsAffected, translateError(res.Error)
+}
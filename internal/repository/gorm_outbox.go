@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormOutboxRepository хранит исходящие события в Postgres через GORM.
+type GormOutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewGormOutboxRepository(db *gorm.DB) *GormOutboxRepository {
+	return &GormOutboxRepository{db: db}
+}
+
+func (r *GormOutboxRepository) Add(ctx context.Context, event *models.OutboxEvent) error {
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	return translateError(conn(ctx, r.db).Create(event).Error)
+}
+
+func (r *GormOutboxRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := conn(ctx, r.db).Raw(`
+		UPDATE outbox_events SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE published_at IS NULL AND next_attempt_at <= ?
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), now, limit,
+	).Scan(&events).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	// RETURNING не гарантирует порядок строк
+	sortOutbox(events)
+	return events, nil
+}
+
+func (r *GormOutboxRepository) MarkPublished(ctx context.Context, id uint, at time.Time) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"published_at": at, "last_error": ""}).Error)
+}
+
+func (r *GormOutboxRepository) MarkFailed(ctx context.Context, id uint, attempts int, next time.Time, lastError string) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"attempts": attempts, "next_attempt_at": next, "last_error": lastError}).Error)
+}
+
+func (r *GormOutboxRepository) PurgePublished(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.db).
+		Where("published_at < ?", before).
+		Delete(&models.OutboxEvent{})
+	return res.RowsAffected, translateError(res.Error)
+}
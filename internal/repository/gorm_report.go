@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormReportRepository хранит жалобы в Postgres через GORM.
+type GormReportRepository struct {
+	db *gorm.DB
+}
+
+func NewGormReportRepository(db *gorm.DB) *GormReportRepository {
+	return &GormReportRepository{db: db}
+}
+
+func (r *GormReportRepository) Create(ctx context.Context, report *models.Report) error {
+	return translateError(conn(ctx, r.db).Omit("Reporter").Create(report).Error)
+}
+
+func (r *GormReportRepository) CountOpen(ctx context.Context, adID uint) (int64, error) {
+	var count int64
+	err := conn(ctx, r.db).
+		Model(&models.Report{}).
+		Where("ad_id = ? AND status = ? AND reporter_id IS NOT NULL", adID, models.ReportOpen).
+		Count(&count).Error
+	return count, translateError(err)
+}
+
+func (r *GormReportRepository) ListOpen(ctx context.Context, adID uint) ([]models.Report, error) {
+	var reports []models.Report
+	err := conn(ctx, r.db).
+		Preload("Reporter").
+		Where("ad_id = ? AND status = ?", adID, models.ReportOpen).
+		Order("created_at, id").
+		Find(&reports).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return reports, nil
+}
+
+func (r *GormReportRepository) Queue(ctx context.Context, limit, offset int) ([]ReportSummary, error) {
+	var rows []struct {
+		AdID           uint
+		Reports        int64
+		LastReportedAt time.Time
+	}
+	err := conn(ctx, r.db).
+		Model(&models.Report{}).
+		Select("ad_id, COUNT(*) AS reports, MAX(created_at) AS last_reported_at").
+		Where("status = ?", models.ReportOpen).
+		Group("ad_id").
+		Order("reports DESC, last_reported_at DESC, ad_id").
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	summaries := make([]ReportSummary, len(rows))
+	if len(rows) == 0 {
+		return summaries, nil
+	}
+	index := make(map[uint]int, len(rows))
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		summaries[i] = ReportSummary{
+			AdID:           row.AdID,
+			Reports:        row.Reports,
+			Reasons:        map[models.ReportReason]int64{},
+			LastReportedAt: row.LastReportedAt,
+		}
+		index[row.AdID] = i
+		ids[i] = row.AdID
+	}
+
+	var reasons []struct {
+		AdID   uint
+		Reason models.ReportReason
+		Count  int64
+	}
+	err = conn(ctx, r.db).
+		Model(&models.Report{}).
+		Select("ad_id, reason, COUNT(*) AS count").
+		Where("status = ? AND ad_id IN ?", models.ReportOpen, ids).
+		Group("ad_id, reason").
+		Scan(&reasons).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	for _, row := range reasons {
+		summaries[index[row.AdID]].Reasons[row.Reason] = row.Count
+	}
+	return summaries, nil
+}
+
+func (r *GormReportRepository) Resolve(ctx context.Context, adID uint, resolution string, at time.Time) (int64, error) {
+	res := conn(ctx, r.db).
+		Model(&models.Report{}).
+		Where("ad_id = ? AND status = ?", adID, models.ReportOpen).
+		Updates(map[string]any{"status": models.ReportResolved, "resolution": resolution, "resolved_at": at})
+	return res.RowsAffected, translateError(res.Error)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormReviewRepository хранит отзывы в Postgres через GORM.
+type GormReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewGormReviewRepository(db *gorm.DB) *GormReviewRepository {
+	return &GormReviewRepository{db: db}
+}
+
+func (r *GormReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	return translateError(conn(ctx, r.db).Omit("Author").Create(review).Error)
+}
+
+func (r *GormReviewRepository) ListByTarget(ctx context.Context, userID uint, limit, offset int) ([]models.Review, error) {
+	var reviews []models.Review
+	err := conn(ctx, r.db).
+		Preload("Author").
+		Where("target_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormSavedSearchRepository хранит сохранённые поиски в Postgres через GORM.
+type GormSavedSearchRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSavedSearchRepository(db *gorm.DB) *GormSavedSearchRepository {
+	return &GormSavedSearchRepository{db: db}
+}
+
+func (r *GormSavedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	return translateError(conn(ctx, r.db).Create(search).Error)
+}
+
+func (r *GormSavedSearchRepository) GetByID(ctx context.Context, id uint) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	if err := conn(ctx, r.db).First(&search, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &search, nil
+}
+
+func (r *GormSavedSearchRepository) Delete(ctx context.Context, search *models.SavedSearch) error {
+	return translateError(conn(ctx, r.db).Delete(search).Error)
+}
+
+func (r *GormSavedSearchRepository) ListByUser(ctx context.Context, userID uint) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := conn(ctx, r.db).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Find(&searches).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return searches, nil
+}
+
+func (r *GormSavedSearchRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	var n int64
+	err := conn(ctx, r.db).Model(&models.SavedSearch{}).Where("user_id = ?", userID).Count(&n).Error
+	return n, translateError(err)
+}
+
+func (r *GormSavedSearchRepository) ListCandidates(ctx context.Context, price float64, excludeUserID uint) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := conn(ctx, r.db).
+		Where("user_id <> ?", excludeUserID).
+		Where("min_price IS NULL OR min_price <= ?", price).
+		Where("max_price IS NULL OR max_price >= ?", price).
+		Order("id").
+		Find(&searches).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return searches, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// searchConfig — конфигурация текстового поиска Postgres. Для русского текста
+// применяется русский стеммер, латиница обрабатывается английским.
+const searchConfig = "russian"
+
+// MigrateAdSearch добавляет к таблице ads поисковый вектор и GIN-индекс.
+// Вектор — генерируемый столбец, поэтому Postgres сам пересчитывает его
+// при любом изменении заголовка или описания. Заголовок весит больше описания.
+func MigrateAdSearch(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			ALTER TABLE ads ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('` + searchConfig + `', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('` + searchConfig + `', coalesce(description, '')), 'B')
+			) STORED`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_ads_search_vector ON ads USING GIN (search_vector)`).Error
+	})
+}
+
+func (r *GormAdRepository) Highlight(ctx context.Context, query string, adIDs []uint) (map[uint]AdHighlight, error) {
+	result := make(map[uint]AdHighlight, len(adIDs))
+	if query == "" || len(adIDs) == 0 {
+		return result, nil
+	}
+
+	const (
+		markers            = "StartSel=" + highlightStart + ", StopSel=" + highlightStop
+		titleOptions       = markers + ", HighlightAll=true"
+		descriptionOptions = markers + ", MaxWords=35, MinWords=15, MaxFragments=2"
+	)
+	var rows []struct {
+		ID          uint
+		Title       string
+		Description string
+	}
+	err := conn(ctx, r.db).Raw(`
+		SELECT id,
+			ts_headline('`+searchConfig+`', title, q, ?) AS title,
+			ts_headline('`+searchConfig+`', description, q, ?) AS description
+		FROM ads, websearch_to_tsquery('`+searchConfig+`', ?) AS q
+		WHERE id IN ?`, titleOptions, descriptionOptions, query, adIDs).Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	for _, row := range rows {
+		result[row.ID] = AdHighlight{Title: row.Title, Description: row.Description}
+	}
+	return result, nil
+}
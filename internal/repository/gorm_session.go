@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormSessionRepository хранит сессии и refresh-токены в Postgres через GORM.
+type GormSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSessionRepository(db *gorm.DB) *GormSessionRepository {
+	return &GormSessionRepository{db: db}
+}
+
+func (r *GormSessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	return translateError(conn(ctx, r.db).Create(session).Error)
+}
+
+func (r *GormSessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := conn(ctx, r.db).First(&session, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &session, nil
+}
+
+func (r *GormSessionRepository) RevokeSession(ctx context.Context, id string) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error)
+}
+
+func (r *GormSessionRepository) RevokeUserSessions(ctx context.Context, userID uint) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error)
+}
+
+func (r *GormSessionRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return translateError(conn(ctx, r.db).Create(token).Error)
+}
+
+func (r *GormSessionRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := conn(ctx, r.db).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
+
+func (r *GormSessionRepository) ConsumeRefreshToken(ctx context.Context, id uint) (bool, error) {
+	res := conn(ctx, r.db).
+		Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, translateError(res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
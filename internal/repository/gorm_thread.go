@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormThreadRepository хранит переписки и сообщения в Postgres через GORM.
+type GormThreadRepository struct {
+	db *gorm.DB
+}
+
+func NewGormThreadRepository(db *gorm.DB) *GormThreadRepository {
+	return &GormThreadRepository{db: db}
+}
+
+func (r *GormThreadRepository) CreateThread(ctx context.Context, thread *models.Thread) error {
+	return translateError(conn(ctx, r.db).Omit("Buyer", "Seller").Create(thread).Error)
+}
+
+func (r *GormThreadRepository) GetThread(ctx context.Context, id uint) (*models.Thread, error) {
+	var thread models.Thread
+	err := conn(ctx, r.db).
+		Preload("Buyer").
+		Preload("Seller").
+		First(&thread, id).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &thread, nil
+}
+
+func (r *GormThreadRepository) FindThread(ctx context.Context, adID, buyerID uint) (*models.Thread, error) {
+	var thread models.Thread
+	err := conn(ctx, r.db).
+		Preload("Buyer").
+		Preload("Seller").
+		Where("ad_id = ? AND buyer_id = ?", adID, buyerID).
+		First(&thread).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &thread, nil
+}
+
+func (r *GormThreadRepository) ListThreads(ctx context.Context, userID uint, limit, offset int) ([]ThreadSummary, error) {
+	var threads []models.Thread
+	err := conn(ctx, r.db).
+		Preload("Buyer").
+		Preload("Seller").
+		Where("buyer_id = ? OR seller_id = ?", userID, userID).
+		Order("last_message_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&threads).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	summaries := make([]ThreadSummary, len(threads))
+	if len(threads) == 0 {
+		return summaries, nil
+	}
+
+	ids := make([]uint, len(threads))
+	for i, t := range threads {
+		ids[i] = t.ID
+	}
+
+	// Непрочитанные — чужие сообщения после последнего прочитанного
+	// со стороны пользователя в этой переписке.
+	var counts []struct {
+		ThreadID uint
+		Unread   int64
+	}
+	err = conn(ctx, r.db).
+		Table("messages").
+		Select("messages.thread_id, COUNT(*) AS unread").
+		Joins("JOIN threads ON threads.id = messages.thread_id").
+		Where("messages.thread_id IN ? AND messages.sender_id <> ?", ids, userID).
+		Where("messages.id > CASE WHEN threads.buyer_id = ? THEN threads.buyer_last_read_id ELSE threads.seller_last_read_id END", userID).
+		Group("messages.thread_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	unread := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		unread[c.ThreadID] = c.Unread
+	}
+
+	for i, t := range threads {
+		summaries[i] = ThreadSummary{Thread: t, Unread: unread[t.ID]}
+	}
+	return summaries, nil
+}
+
+func (r *GormThreadRepository) AddMessage(ctx context.Context, message *models.Message) error {
+	return translateError(conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Thread{}).
+			Where("id = ?", message.ThreadID).
+			Update("last_message_at", message.CreatedAt).Error
+	}))
+}
+
+func (r *GormThreadRepository) ListMessages(ctx context.Context, threadID, afterID uint, limit int) ([]models.Message, error) {
+	var messages []models.Message
+	err := conn(ctx, r.db).
+		Where("thread_id = ? AND id > ?", threadID, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&messages).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return messages, nil
+}
+
+func (r *GormThreadRepository) MarkRead(ctx context.Context, threadID, userID uint) error {
+	latest := r.db.Table("messages").Select("COALESCE(MAX(id), 0)").Where("thread_id = ?", threadID)
+
+	return translateError(conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Thread{}).
+			Where("id = ? AND buyer_id = ?", threadID, userID).
+			Update("buyer_last_read_id", latest).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Thread{}).
+			Where("id = ? AND seller_id = ?", threadID, userID).
+			Update("seller_last_read_id", latest).Error
+	}))
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormUserTokenRepository хранит одноразовые токены из писем в Postgres через GORM.
+type GormUserTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserTokenRepository(db *gorm.DB) *GormUserTokenRepository {
+	return &GormUserTokenRepository{db: db}
+}
+
+func (r *GormUserTokenRepository) Create(ctx context.Context, token *models.UserToken) error {
+	return translateError(conn(ctx, r.db).Create(token).Error)
+}
+
+func (r *GormUserTokenRepository) Get(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := conn(ctx, r.db).
+		Where("purpose = ? AND token_hash = ?", purpose, tokenHash).
+		First(&token).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
+
+func (r *GormUserTokenRepository) Consume(ctx context.Context, id uint) (bool, error) {
+	res := conn(ctx, r.db).
+		Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, translateError(res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *GormUserTokenRepository) InvalidateUser(ctx context.Context, userID uint, purpose models.TokenPurpose) error {
+	return translateError(conn(ctx, r.db).
+		Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error)
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// GormWebhookRepository хранит вебхуки и журнал доставок в Postgres через GORM.
+type GormWebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewGormWebhookRepository(db *gorm.DB) *GormWebhookRepository {
+	return &GormWebhookRepository{db: db}
+}
+
+func (r *GormWebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	return translateError(conn(ctx, r.db).Create(webhook).Error)
+}
+
+func (r *GormWebhookRepository) GetByID(ctx context.Context, id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := conn(ctx, r.db).First(&webhook, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &webhook, nil
+}
+
+func (r *GormWebhookRepository) Delete(ctx context.Context, webhook *models.Webhook) error {
+	return translateError(conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(webhook).Error
+	}))
+}
+
+func (r *GormWebhookRepository) ListByUser(ctx context.Context, userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := conn(ctx, r.db).
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return webhooks, nil
+}
+
+func (r *GormWebhookRepository) ListSubscribed(ctx context.Context, event string, adOwnerID uint) ([]models.Webhook, error) {
+	// Права на all_ads проверяются на момент события: после снятия роли
+	// или блокировки владельца вебхук перестаёт получать чужие объявления
+	allowed := conn(ctx, r.db).
+		Model(&models.User{}).
+		Select("id").
+		Where("role IN ?", models.RolesWith(models.PermWebhooksAllAds)).
+		Where("status IN ? OR status_until <= ?", []models.UserStatus{models.UserActive, ""}, time.Now())
+
+	var webhooks []models.Webhook
+	err := conn(ctx, r.db).
+		Where("user_id = ? OR (all_ads AND user_id IN (?))", adOwnerID, allowed).
+		Where("',' || events || ',' LIKE ?", "%,"+event+",%").
+		Order("id").
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return webhooks, nil
+}
+
+func (r *GormWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return translateError(conn(ctx, r.db).Create(delivery).Error)
+}
+
+func (r *GormWebhookRepository) ListDeliveries(ctx context.Context, webhookID uint, limit, offset int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := conn(ctx, r.db).
+		Where("webhook_id = ?", webhookID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return deliveries, nil
+}
+
+func (r *GormWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	// SKIP LOCKED позволяет нескольким экземплярам разбирать очередь,
+	// не блокируя друг друга и не беря одни и те же доставки.
+	var deliveries []models.WebhookDelivery
+	err := conn(ctx, r.db).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), models.DeliveryPending, now, limit,
+	).Scan(&deliveries).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return deliveries, nil
+}
+
+func (r *GormWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return translateError(conn(ctx, r.db).
+		Model(delivery).
+		Select("status", "attempts", "next_attempt_at", "last_attempt_at", "response_status", "last_error").
+		Updates(delivery).Error)
+}
@@ -0,0 +1,493 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryUserRepository хранит пользователей в памяти процесса.
+// Используется в тестах и для локальной разработки без Postgres.
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	users  map[uint]models.User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[uint]models.User)}
+}
+
+func (r *MemoryUserRepository) Create(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Username == user.Username || user.Email != "" && u.Email == user.Email {
+			return ErrDuplicate
+		}
+	}
+
+	r.nextID++
+	user.ID = r.nextID
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if user.Status == "" {
+		user.Status = models.UserActive
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *MemoryUserRepository) GetByID(_ context.Context, id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetByUsername(_ context.Context, username string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if email != "" && u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) SetPassword(_ context.Context, userID uint, hash string) error {
+	return r.update(userID, func(u *models.User) { u.Password = hash })
+}
+
+func (r *MemoryUserRepository) MarkEmailVerified(_ context.Context, userID uint, at time.Time) error {
+	err := r.update(userID, func(u *models.User) {
+		if u.EmailVerifiedAt == nil {
+			u.EmailVerifiedAt = &at
+		}
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *MemoryUserRepository) SetRole(_ context.Context, userID uint, role models.Role) error {
+	return r.update(userID, func(u *models.User) { u.Role = role })
+}
+
+func (r *MemoryUserRepository) SetStatus(_ context.Context, userID uint, status models.UserStatus, until *time.Time, reason string) error {
+	return r.update(userID, func(u *models.User) {
+		u.Status, u.StatusUntil, u.StatusReason = status, until, reason
+	})
+}
+
+func (r *MemoryUserRepository) ClearExpiredStatus(_ context.Context, userID uint, now time.Time) error {
+	err := r.update(userID, func(u *models.User) {
+		if u.Status != models.UserActive && u.StatusUntil != nil && !u.StatusUntil.After(now) {
+			u.Status, u.StatusUntil, u.StatusReason = models.UserActive, nil, ""
+		}
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *MemoryUserRepository) update(userID uint, change func(u *models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	change(&user)
+	r.users[userID] = user
+	return nil
+}
+
+func (r *MemoryUserRepository) AddRating(_ context.Context, userID uint, rating int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.RatingSum += int64(rating)
+	user.ReviewCount++
+	r.users[userID] = user
+	return nil
+}
+
+// MemoryAdRepository хранит объявления в памяти процесса.
+// Автора объявления он берёт из переданного MemoryUserRepository.
+type MemoryAdRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	ads    map[uint]models.Ad
+	users  *MemoryUserRepository
+}
+
+func NewMemoryAdRepository(users *MemoryUserRepository) *MemoryAdRepository {
+	return &MemoryAdRepository{ads: make(map[uint]models.Ad), users: users}
+}
+
+func (r *MemoryAdRepository) Create(ctx context.Context, ad *models.Ad) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	now := time.Now()
+	ad.ID = r.nextID
+	if ad.Status == "" {
+		ad.Status = models.AdStatusPublished
+	}
+	if ad.CreatedAt.IsZero() {
+		ad.CreatedAt = now
+	}
+	ad.UpdatedAt = now
+
+	stored := *ad
+	stored.User = models.User{}
+	r.ads[ad.ID] = stored
+	return nil
+}
+
+func (r *MemoryAdRepository) GetByID(ctx context.Context, id uint) (*models.Ad, error) {
+	r.mu.RLock()
+	ad, ok := r.ads[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	r.attachUser(ctx, &ad)
+	return &ad, nil
+}
+
+func (r *MemoryAdRepository) Update(_ context.Context, ad *models.Ad) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.ads[ad.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if !stored.Status.Editable() {
+		return ErrConflict
+	}
+
+	ad.UpdatedAt = time.Now()
+	stored.Title = ad.Title
+	stored.Description = ad.Description
+	stored.ImageURL = ad.ImageURL
+	if stored.ListingType != models.ListingAuction {
+		stored.Price = ad.Price
+	}
+	stored.CategoryID = ad.CategoryID
+	stored.UpdatedAt = ad.UpdatedAt
+	r.ads[ad.ID] = stored
+	return nil
+}
+
+func (r *MemoryAdRepository) SetBuyer(_ context.Context, id uint, buyerID *uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.ads[id]
+	if !ok {
+		return ErrNotFound
+	}
+	ad.BuyerID = buyerID
+	r.ads[id] = ad
+	return nil
+}
+
+func (r *MemoryAdRepository) SetHidden(_ context.Context, id uint, reason models.HiddenReason) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.ads[id]
+	if !ok {
+		return ErrNotFound
+	}
+	ad.Hidden, ad.HiddenReason = reason != "", reason
+	r.ads[id] = ad
+	return nil
+}
+
+// setPrice меняет цену объявления; используется при ставках на аукционе.
+func (r *MemoryAdRepository) setPrice(id uint, price float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ad, ok := r.ads[id]; ok {
+		ad.Price = price
+		ad.UpdatedAt = time.Now()
+		r.ads[id] = ad
+	}
+}
+
+func (r *MemoryAdRepository) Delete(_ context.Context, ad *models.Ad) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.ads[ad.ID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.ads, ad.ID)
+	return nil
+}
+
+func (r *MemoryAdRepository) UpdateStatus(_ context.Context, id uint, from, to models.AdStatus, expiresAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.ads[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if ad.Status != from {
+		return ErrConflict
+	}
+	ad.Status = to
+	if expiresAt != nil {
+		ad.ExpiresAt = expiresAt
+	}
+	ad.UpdatedAt = time.Now()
+	r.ads[id] = ad
+	return nil
+}
+
+func (r *MemoryAdRepository) ExpirePublished(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for id, ad := range r.ads {
+		if ad.Status == models.AdStatusPublished && ad.ExpiresAt != nil && !ad.ExpiresAt.After(now) {
+			ad.Status = models.AdStatusExpired
+			ad.UpdatedAt = now
+			r.ads[id] = ad
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *MemoryAdRepository) List(ctx context.Context, filter AdFilter) ([]models.Ad, error) {
+	terms := searchTerms(filter.Query)
+	rank := make(map[uint]int)
+
+	r.mu.RLock()
+	ads := make([]models.Ad, 0, len(r.ads))
+	for _, ad := range r.ads {
+		if filter.Query != "" {
+			score, ok := naiveMatch(terms, ad.Title, ad.Description)
+			if !ok {
+				continue
+			}
+			rank[ad.ID] = score
+		}
+		if r.matchAdFilter(ctx, &ad, filter) {
+			ads = append(ads, ad)
+		}
+	}
+	r.mu.RUnlock()
+
+	desc := strings.EqualFold(filter.Order, "DESC")
+	sort.Slice(ads, func(i, j int) bool {
+		var c int
+		if filter.SortField == "relevance" {
+			c = rank[ads[i].ID] - rank[ads[j].ID]
+		} else {
+			c = compareAds(&ads[i], &ads[j], filter.SortField)
+		}
+		if c == 0 {
+			c = compareUint(ads[i].ID, ads[j].ID)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if filter.After != nil && filter.SortField != "relevance" {
+		cursor := models.Ad{
+			ID:        filter.After.ID,
+			CreatedAt: filter.After.CreatedAt,
+			Price:     filter.After.Price,
+			Title:     filter.After.Title,
+		}
+		// Первое объявление, которое в порядке выдачи идёт после курсора
+		start := sort.Search(len(ads), func(i int) bool {
+			c := compareAds(&ads[i], &cursor, filter.SortField)
+			if c == 0 {
+				c = compareUint(ads[i].ID, cursor.ID)
+			}
+			if desc {
+				return c < 0
+			}
+			return c > 0
+		})
+		ads = ads[start:]
+	}
+
+	ads = paginate(ads, filter.Limit, filter.Offset)
+	for i := range ads {
+		r.attachUser(ctx, &ads[i])
+	}
+	return ads, nil
+}
+
+func (r *MemoryAdRepository) Count(ctx context.Context, filter AdFilter) (int64, error) {
+	terms := searchTerms(filter.Query)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var total int64
+	for _, ad := range r.ads {
+		if filter.Query != "" {
+			if _, ok := naiveMatch(terms, ad.Title, ad.Description); !ok {
+				continue
+			}
+		}
+		if r.matchAdFilter(ctx, &ad, filter) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (r *MemoryAdRepository) Highlight(_ context.Context, query string, adIDs []uint) (map[uint]AdHighlight, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	terms := searchTerms(query)
+	result := make(map[uint]AdHighlight, len(adIDs))
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	for _, id := range adIDs {
+		if ad, ok := r.ads[id]; ok {
+			result[id] = AdHighlight{
+				Title:       naiveHighlight(terms, ad.Title, 0),
+				Description: naiveHighlight(terms, ad.Description, 200),
+			}
+		}
+	}
+	return result, nil
+}
+
+// attachUser подставляет автора объявления, как это делает Preload("User").
+func (r *MemoryAdRepository) attachUser(ctx context.Context, ad *models.Ad) {
+	if user, err := r.users.GetByID(ctx, ad.UserID); err == nil {
+		ad.User = *user
+	}
+}
+
+// matchAdFilter проверяет условия фильтра, кроме полнотекстового запроса.
+func (r *MemoryAdRepository) matchAdFilter(ctx context.Context, ad *models.Ad, filter AdFilter) bool {
+	if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, ad.ID) {
+		return false
+	}
+	if filter.MinPrice != nil && ad.Price < *filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice != nil && ad.Price > *filter.MaxPrice {
+		return false
+	}
+	if len(filter.CategoryIDs) > 0 && (ad.CategoryID == nil || !slices.Contains(filter.CategoryIDs, *ad.CategoryID)) {
+		return false
+	}
+	if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, ad.Status) {
+		return false
+	}
+	if filter.UserID != nil && ad.UserID != *filter.UserID {
+		return false
+	}
+	if !filter.ActiveAt.IsZero() && ad.ExpiresAt != nil && !ad.ExpiresAt.After(filter.ActiveAt) {
+		return false
+	}
+	if filter.VisibleOnly && ad.Hidden {
+		return false
+	}
+	if filter.VisibleOnly {
+		if owner, err := r.users.GetByID(ctx, ad.UserID); err == nil && owner.StatusAt(time.Now()) == models.UserBanned {
+			return false
+		}
+	}
+	return true
+}
+
+// compareAds сравнивает объявления по полю сортировки.
+func compareAds(a, b *models.Ad, field string) int {
+	switch field {
+	case "price":
+		switch {
+		case a.Price < b.Price:
+			return -1
+		case a.Price > b.Price:
+			return 1
+		}
+		return 0
+	case "title":
+		return strings.Compare(a.Title, b.Title)
+	default:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+}
+
+func compareUint(a, b uint) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// paginate применяет limit/offset к уже отсортированной выборке.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
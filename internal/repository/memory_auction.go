@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryAuctionRepository хранит аукционы и ставки в памяти процесса.
+// Текущую цену объявления он обновляет в переданном MemoryAdRepository.
+type MemoryAuctionRepository struct {
+	mu        sync.Mutex
+	nextBidID uint
+	auctions  map[uint]models.Auction
+	bids      map[uint][]models.Bid // по ID объявления, в порядке ставок
+	ads       *MemoryAdRepository
+}
+
+func NewMemoryAuctionRepository(ads *MemoryAdRepository) *MemoryAuctionRepository {
+	return &MemoryAuctionRepository{
+		auctions: make(map[uint]models.Auction),
+		bids:     make(map[uint][]models.Bid),
+		ads:      ads,
+	}
+}
+
+func (r *MemoryAuctionRepository) Create(_ context.Context, auction *models.Auction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.auctions[auction.AdID]; ok {
+		return ErrDuplicate
+	}
+	if auction.CreatedAt.IsZero() {
+		auction.CreatedAt = time.Now()
+	}
+	r.auctions[auction.AdID] = *auction
+	return nil
+}
+
+func (r *MemoryAuctionRepository) Get(_ context.Context, adID uint) (*models.Auction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	auction, ok := r.auctions[adID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &auction, nil
+}
+
+func (r *MemoryAuctionRepository) ListByAds(_ context.Context, adIDs []uint) (map[uint]models.Auction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make(map[uint]models.Auction)
+	for _, id := range adIDs {
+		if a, ok := r.auctions[id]; ok {
+			result[id] = a
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryAuctionRepository) PlaceBid(_ context.Context, auction *models.Auction, bid *models.Bid) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.auctions[auction.AdID]
+	if !ok || stored.Version != auction.Version || stored.Status != models.AuctionOpen {
+		return ErrConflict
+	}
+
+	r.nextBidID++
+	bid.ID = r.nextBidID
+	if bid.CreatedAt.IsZero() {
+		bid.CreatedAt = time.Now()
+	}
+	r.bids[auction.AdID] = append(r.bids[auction.AdID], *bid)
+
+	auction.Version++
+	r.auctions[auction.AdID] = *auction
+	r.ads.setPrice(auction.AdID, bid.Amount)
+	return nil
+}
+
+func (r *MemoryAuctionRepository) ListBids(_ context.Context, adID uint, limit, offset int) ([]models.Bid, error) {
+	r.mu.Lock()
+	stored := r.bids[adID]
+	bids := make([]models.Bid, len(stored))
+	for i, b := range stored {
+		bids[len(stored)-1-i] = b
+	}
+	r.mu.Unlock()
+
+	return paginate(bids, limit, offset), nil
+}
+
+func (r *MemoryAuctionRepository) ListDue(_ context.Context, now time.Time, limit int) ([]models.Auction, error) {
+	r.mu.Lock()
+	var due []models.Auction
+	for _, a := range r.auctions {
+		if a.Status == models.AuctionOpen && !a.EndsAt.After(now) {
+			due = append(due, a)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].EndsAt.Before(due[j].EndsAt) })
+	return paginate(due, limit, 0), nil
+}
+
+func (r *MemoryAuctionRepository) Close(_ context.Context, auction *models.Auction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.auctions[auction.AdID]
+	if !ok || stored.Version != auction.Version || stored.Status != models.AuctionOpen {
+		return ErrConflict
+	}
+
+	auction.Status = models.AuctionClosed
+	auction.Version++
+	r.auctions[auction.AdID] = *auction
+	return nil
+}
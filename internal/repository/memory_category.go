@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryCategoryRepository хранит разделы каталога в памяти процесса.
+type MemoryCategoryRepository struct {
+	mu         sync.RWMutex
+	nextID     uint
+	categories map[uint]models.Category
+}
+
+func NewMemoryCategoryRepository() *MemoryCategoryRepository {
+	return &MemoryCategoryRepository{categories: make(map[uint]models.Category)}
+}
+
+func (r *MemoryCategoryRepository) Create(_ context.Context, category *models.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	now := time.Now()
+	category.ID = r.nextID
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	r.categories[category.ID] = *category
+	return nil
+}
+
+func (r *MemoryCategoryRepository) GetByID(_ context.Context, id uint) (*models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	category, ok := r.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &category, nil
+}
+
+func (r *MemoryCategoryRepository) Update(_ context.Context, category *models.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.categories[category.ID]; !ok {
+		return ErrNotFound
+	}
+	category.UpdatedAt = time.Now()
+	r.categories[category.ID] = *category
+	return nil
+}
+
+func (r *MemoryCategoryRepository) Delete(_ context.Context, category *models.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.categories[category.ID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.categories, category.ID)
+	return nil
+}
+
+func (r *MemoryCategoryRepository) List(_ context.Context) ([]models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categories := make([]models.Category, 0, len(r.categories))
+	for _, c := range r.categories {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
+
+func (r *MemoryCategoryRepository) DescendantIDs(_ context.Context, id uint) ([]uint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.categories[id]; !ok {
+		return nil, ErrNotFound
+	}
+
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		for _, c := range r.categories {
+			if c.ParentID != nil && *c.ParentID == ids[i] {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+type favoriteKey struct {
+	userID, adID uint
+}
+
+// MemoryFavoriteRepository хранит избранное в памяти процесса.
+// Сами объявления он берёт из переданного MemoryAdRepository.
+type MemoryFavoriteRepository struct {
+	mu        sync.RWMutex
+	favorites map[favoriteKey]time.Time
+	ads       *MemoryAdRepository
+}
+
+func NewMemoryFavoriteRepository(ads *MemoryAdRepository) *MemoryFavoriteRepository {
+	return &MemoryFavoriteRepository{favorites: make(map[favoriteKey]time.Time), ads: ads}
+}
+
+func (r *MemoryFavoriteRepository) Add(_ context.Context, userID, adID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey{userID, adID}
+	if _, ok := r.favorites[key]; ok {
+		return ErrDuplicate
+	}
+	r.favorites[key] = time.Now()
+	return nil
+}
+
+func (r *MemoryFavoriteRepository) Remove(_ context.Context, userID, adID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey{userID, adID}
+	if _, ok := r.favorites[key]; !ok {
+		return ErrNotFound
+	}
+	delete(r.favorites, key)
+	return nil
+}
+
+func (r *MemoryFavoriteRepository) ListAds(ctx context.Context, userID uint, statuses []models.AdStatus, limit, offset int) ([]models.Ad, error) {
+	type entry struct {
+		adID  uint
+		added time.Time
+	}
+
+	r.mu.RLock()
+	var entries []entry
+	for key, added := range r.favorites {
+		if key.userID == userID {
+			entries = append(entries, entry{key.adID, added})
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].added.Equal(entries[j].added) {
+			return entries[i].added.After(entries[j].added)
+		}
+		return entries[i].adID > entries[j].adID
+	})
+
+	// Удалённые объявления пропускаются, как при JOIN с ads
+	var ads []models.Ad
+	for _, e := range entries {
+		ad, err := r.ads.GetByID(ctx, e.adID)
+		if err != nil {
+			continue
+		}
+		if len(statuses) > 0 && !slices.Contains(statuses, ad.Status) {
+			continue
+		}
+		ads = append(ads, *ad)
+	}
+	return paginate(ads, limit, offset), nil
+}
+
+func (r *MemoryFavoriteRepository) FavoritedAdIDs(_ context.Context, userID uint, adIDs []uint) (map[uint]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[uint]bool)
+	for _, id := range adIDs {
+		if _, ok := r.favorites[favoriteKey{userID, id}]; ok {
+			result[id] = true
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryFavoriteRepository) CountByAds(_ context.Context, adIDs []uint) (map[uint]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[uint]int64)
+	for key := range r.favorites {
+		if slices.Contains(adIDs, key.adID) {
+			result[key.adID]++
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryFavoriteRepository) UserIDsByAd(_ context.Context, adID uint) ([]uint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []uint
+	for key := range r.favorites {
+		if key.adID == adID {
+			ids = append(ids, key.userID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryAdImageRepository хранит метаданные картинок в памяти процесса.
+type MemoryAdImageRepository struct {
+	mu     sync.RWMutex
+	nextID uint
+	images map[uint]models.AdImage
+}
+
+func NewMemoryAdImageRepository() *MemoryAdImageRepository {
+	return &MemoryAdImageRepository{images: make(map[uint]models.AdImage)}
+}
+
+func (r *MemoryAdImageRepository) Append(_ context.Context, image *models.AdImage, limit int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count, position := 0, 0
+	for _, img := range r.images {
+		if img.AdID == image.AdID {
+			count++
+			position = max(position, img.Position+1)
+		}
+	}
+	if count >= limit {
+		return ErrConflict
+	}
+
+	image.Position = position
+	r.nextID++
+	image.ID = r.nextID
+	if image.CreatedAt.IsZero() {
+		image.CreatedAt = time.Now()
+	}
+	r.images[image.ID] = *image
+	return nil
+}
+
+func (r *MemoryAdImageRepository) GetByID(_ context.Context, id uint) (*models.AdImage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	image, ok := r.images[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &image, nil
+}
+
+func (r *MemoryAdImageRepository) UpdatePosition(_ context.Context, id uint, position int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	image, ok := r.images[id]
+	if !ok {
+		return ErrNotFound
+	}
+	image.Position = position
+	r.images[id] = image
+	return nil
+}
+
+func (r *MemoryAdImageRepository) UpdateVariants(_ context.Context, id uint, thumbKey, mediumKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	image, ok := r.images[id]
+	if !ok {
+		return ErrNotFound
+	}
+	image.ThumbKey = thumbKey
+	image.MediumKey = mediumKey
+	r.images[id] = image
+	return nil
+}
+
+func (r *MemoryAdImageRepository) Delete(_ context.Context, image *models.AdImage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.images[image.ID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.images, image.ID)
+	return nil
+}
+
+func (r *MemoryAdImageRepository) ListByAd(ctx context.Context, adID uint) ([]models.AdImage, error) {
+	return r.ListByAds(ctx, []uint{adID})
+}
+
+func (r *MemoryAdImageRepository) ListByAds(_ context.Context, adIDs []uint) ([]models.AdImage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var images []models.AdImage
+	for _, image := range r.images {
+		if slices.Contains(adIDs, image.AdID) {
+			images = append(images, image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		if a.AdID != b.AdID {
+			return a.AdID < b.AdID
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.ID < b.ID
+	})
+	return images, nil
+}
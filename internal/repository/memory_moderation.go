@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryModerationRepository хранит журнал модерации в памяти процесса.
+// Модераторов он берёт из переданного MemoryUserRepository.
+type MemoryModerationRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	actions []models.ModerationAction
+	users   *MemoryUserRepository
+}
+
+func NewMemoryModerationRepository(users *MemoryUserRepository) *MemoryModerationRepository {
+	return &MemoryModerationRepository{users: users}
+}
+
+func (r *MemoryModerationRepository) Record(_ context.Context, action *models.ModerationAction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	action.ID = r.nextID
+	if action.CreatedAt.IsZero() {
+		action.CreatedAt = time.Now()
+	}
+	stored := *action
+	stored.Moderator = models.User{}
+	r.actions = append(r.actions, stored)
+	return nil
+}
+
+func (r *MemoryModerationRepository) List(ctx context.Context, adID uint, limit, offset int) ([]models.ModerationAction, error) {
+	r.mu.Lock()
+	var actions []models.ModerationAction
+	for _, a := range slices.Backward(r.actions) {
+		if adID == 0 || a.AdID == adID {
+			actions = append(actions, a)
+		}
+	}
+	r.mu.Unlock()
+
+	actions = paginate(actions, limit, offset)
+	for i := range actions {
+		if user, err := r.users.GetByID(ctx, actions[i].ModeratorID); err == nil {
+			actions[i].Moderator = *user
+		}
+	}
+	return actions, nil
+}
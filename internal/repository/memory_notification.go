@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryNotificationRepository хранит уведомления в памяти процесса.
+type MemoryNotificationRepository struct {
+	mu            sync.RWMutex
+	nextID        uint
+	notifications map[uint]models.Notification
+}
+
+func NewMemoryNotificationRepository() *MemoryNotificationRepository {
+	return &MemoryNotificationRepository{notifications: make(map[uint]models.Notification)}
+}
+
+func (r *MemoryNotificationRepository) Create(_ context.Context, notification *models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if notification.DedupKey != nil {
+		for _, n := range r.notifications {
+			if n.UserID == notification.UserID && n.DedupKey != nil && *n.DedupKey == *notification.DedupKey {
+				return ErrDuplicate
+			}
+		}
+	}
+
+	r.nextID++
+	notification.ID = r.nextID
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	r.notifications[notification.ID] = *notification
+	return nil
+}
+
+func (r *MemoryNotificationRepository) ListByUser(_ context.Context, userID uint, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	r.mu.RLock()
+	var notifications []models.Notification
+	for _, n := range r.notifications {
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			notifications = append(notifications, n)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(notifications, func(i, j int) bool {
+		a, b := notifications[i], notifications[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return paginate(notifications, limit, offset), nil
+}
+
+func (r *MemoryNotificationRepository) CountUnread(_ context.Context, userID uint) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryNotificationRepository) MarkRead(_ context.Context, userID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.notifications[id]
+	if !ok || n.UserID != userID {
+		return ErrNotFound
+	}
+	if n.ReadAt == nil {
+		now := time.Now()
+		n.ReadAt = &now
+		r.notifications[id] = n
+	}
+	return nil
+}
+
+func (r *MemoryNotificationRepository) MarkAllRead(_ context.Context, userID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var count int64
+	for id, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &now
+			r.notifications[id] = n
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryOfferRepository хранит предложения цены в памяти процесса.
+// Покупателей он берёт из переданного MemoryUserRepository.
+type MemoryOfferRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	offers map[uint]models.Offer
+	users  *MemoryUserRepository
+}
+
+func NewMemoryOfferRepository(users *MemoryUserRepository) *MemoryOfferRepository {
+	return &MemoryOfferRepository{offers: make(map[uint]models.Offer), users: users}
+}
+
+func (r *MemoryOfferRepository) Create(_ context.Context, offer *models.Offer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, o := range r.offers {
+		if o.AdID == offer.AdID && o.BuyerID == offer.BuyerID && o.Status == models.OfferPending {
+			return ErrDuplicate
+		}
+	}
+
+	r.nextID++
+	offer.ID = r.nextID
+	now := time.Now()
+	if offer.CreatedAt.IsZero() {
+		offer.CreatedAt = now
+	}
+	offer.UpdatedAt = offer.CreatedAt
+
+	stored := *offer
+	stored.Buyer = models.User{}
+	r.offers[offer.ID] = stored
+	return nil
+}
+
+func (r *MemoryOfferRepository) GetByID(ctx context.Context, id uint) (*models.Offer, error) {
+	r.mu.Lock()
+	offer, ok := r.offers[id]
+	r.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	r.attachBuyer(ctx, &offer)
+	return &offer, nil
+}
+
+func (r *MemoryOfferRepository) ListByAd(ctx context.Context, adID uint, buyerID *uint) ([]models.Offer, error) {
+	return r.list(ctx, func(o *models.Offer) bool {
+		return o.AdID == adID && (buyerID == nil || o.BuyerID == *buyerID)
+	}, 0, 0), nil
+}
+
+func (r *MemoryOfferRepository) ListByBuyer(ctx context.Context, buyerID uint, limit, offset int) ([]models.Offer, error) {
+	return r.list(ctx, func(o *models.Offer) bool { return o.BuyerID == buyerID }, limit, offset), nil
+}
+
+func (r *MemoryOfferRepository) list(ctx context.Context, keep func(*models.Offer) bool, limit, offset int) []models.Offer {
+	r.mu.Lock()
+	var offers []models.Offer
+	for _, o := range r.offers {
+		if keep(&o) {
+			offers = append(offers, o)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(offers, func(i, j int) bool {
+		a, b := offers[i], offers[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	offers = paginate(offers, limit, offset)
+	for i := range offers {
+		r.attachBuyer(ctx, &offers[i])
+	}
+	return offers
+}
+
+func (r *MemoryOfferRepository) Update(_ context.Context, offer *models.Offer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.offers[offer.ID]
+	if !ok || stored.Version != offer.Version {
+		return ErrConflict
+	}
+
+	offer.Version++
+	offer.UpdatedAt = time.Now()
+	stored = *offer
+	stored.Buyer = models.User{}
+	r.offers[offer.ID] = stored
+	return nil
+}
+
+func (r *MemoryOfferRepository) RejectPending(_ context.Context, adID, exceptID uint) ([]models.Offer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rejected []models.Offer
+	now := time.Now()
+	for id, o := range r.offers {
+		if o.AdID == adID && id != exceptID && o.Status == models.OfferPending {
+			o.Status = models.OfferRejected
+			o.Version++
+			o.UpdatedAt = now
+			r.offers[id] = o
+			rejected = append(rejected, o)
+		}
+	}
+	return rejected, nil
+}
+
+func (r *MemoryOfferRepository) CancelAccepted(_ context.Context, adID uint) ([]models.Offer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var cancelled []models.Offer
+	now := time.Now()
+	for id, o := range r.offers {
+		if o.AdID == adID && o.Status == models.OfferAccepted {
+			o.Status = models.OfferCancelled
+			o.Version++
+			o.UpdatedAt = now
+			r.offers[id] = o
+			cancelled = append(cancelled, o)
+		}
+	}
+	return cancelled, nil
+}
+
+func (r *MemoryOfferRepository) ExpirePending(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for id, o := range r.offers {
+		if o.Status == models.OfferPending && !o.ExpiresAt.After(now) {
+			o.Status = models.OfferExpired
+			o.Version++
+			o.UpdatedAt = now
+			r.offers[id] = o
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *MemoryOfferRepository) attachBuyer(ctx context.Context, offer *models.Offer) {
+	if user, err := r.users.GetByID(ctx, offer.BuyerID); err == nil {
+		offer.Buyer = *user
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryTransactor выполняет fn без транзакции: хранилища в памяти
+// не умеют откатывать изменения. Подходит для тестов.
+type MemoryTransactor struct{}
+
+func (MemoryTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// MemoryOutboxRepository хранит исходящие события в памяти процесса.
+type MemoryOutboxRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	events map[uint]models.OutboxEvent
+}
+
+func NewMemoryOutboxRepository() *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{events: make(map[uint]models.OutboxEvent)}
+}
+
+func (r *MemoryOutboxRepository) Add(_ context.Context, event *models.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	event.ID = r.nextID
+	now := time.Now()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now
+	}
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = now
+	}
+	r.events[event.ID] = *event
+	return nil
+}
+
+func (r *MemoryOutboxRepository) ClaimPending(_ context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.OutboxEvent
+	for _, e := range r.events {
+		if e.PublishedAt == nil && !e.NextAttemptAt.After(now) {
+			due = append(due, e)
+		}
+	}
+	sortOutbox(due)
+	due = paginate(due, limit, 0)
+
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		r.events[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *MemoryOutboxRepository) MarkPublished(_ context.Context, id uint, at time.Time) error {
+	return r.update(id, func(e *models.OutboxEvent) {
+		e.PublishedAt = &at
+		e.LastError = ""
+	})
+}
+
+func (r *MemoryOutboxRepository) MarkFailed(_ context.Context, id uint, attempts int, next time.Time, lastError string) error {
+	return r.update(id, func(e *models.OutboxEvent) {
+		e.Attempts, e.NextAttemptAt, e.LastError = attempts, next, lastError
+	})
+}
+
+func (r *MemoryOutboxRepository) PurgePublished(_ context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for id, e := range r.events {
+		if e.PublishedAt != nil && e.PublishedAt.Before(before) {
+			delete(r.events, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *MemoryOutboxRepository) update(id uint, fn func(*models.OutboxEvent)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.events[id]
+	if !ok {
+		return ErrNotFound
+	}
+	fn(&e)
+	r.events[id] = e
+	return nil
+}
+
+func sortOutbox(events []models.OutboxEvent) {
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+}
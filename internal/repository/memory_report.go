@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryReportRepository хранит жалобы в памяти процесса.
+// Авторов жалоб он берёт из переданного MemoryUserRepository.
+type MemoryReportRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	reports []models.Report
+	users   *MemoryUserRepository
+}
+
+func NewMemoryReportRepository(users *MemoryUserRepository) *MemoryReportRepository {
+	return &MemoryReportRepository{users: users}
+}
+
+func (r *MemoryReportRepository) Create(_ context.Context, report *models.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rp := range r.reports {
+		if rp.AdID == report.AdID && rp.Status == models.ReportOpen &&
+			rp.ReporterID != nil && report.ReporterID != nil && *rp.ReporterID == *report.ReporterID {
+			return ErrDuplicate
+		}
+	}
+
+	r.nextID++
+	report.ID = r.nextID
+	if report.Status == "" {
+		report.Status = models.ReportOpen
+	}
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now()
+	}
+	stored := *report
+	stored.Reporter = nil
+	r.reports = append(r.reports, stored)
+	return nil
+}
+
+func (r *MemoryReportRepository) CountOpen(_ context.Context, adID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, rp := range r.reports {
+		if rp.AdID == adID && rp.Status == models.ReportOpen && rp.ReporterID != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryReportRepository) ListOpen(ctx context.Context, adID uint) ([]models.Report, error) {
+	r.mu.Lock()
+	var reports []models.Report
+	for _, rp := range r.reports {
+		if rp.AdID == adID && rp.Status == models.ReportOpen {
+			reports = append(reports, rp)
+		}
+	}
+	r.mu.Unlock()
+
+	for i := range reports {
+		if reports[i].ReporterID == nil {
+			continue
+		}
+		if user, err := r.users.GetByID(ctx, *reports[i].ReporterID); err == nil {
+			reports[i].Reporter = user
+		}
+	}
+	return reports, nil
+}
+
+func (r *MemoryReportRepository) Queue(_ context.Context, limit, offset int) ([]ReportSummary, error) {
+	r.mu.Lock()
+	byAd := make(map[uint]*ReportSummary)
+	var summaries []*ReportSummary
+	for _, rp := range r.reports {
+		if rp.Status != models.ReportOpen {
+			continue
+		}
+		s, ok := byAd[rp.AdID]
+		if !ok {
+			s = &ReportSummary{AdID: rp.AdID, Reasons: map[models.ReportReason]int64{}}
+			byAd[rp.AdID] = s
+			summaries = append(summaries, s)
+		}
+		s.Reports++
+		s.Reasons[rp.Reason]++
+		if rp.CreatedAt.After(s.LastReportedAt) {
+			s.LastReportedAt = rp.CreatedAt
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Reports != b.Reports {
+			return a.Reports > b.Reports
+		}
+		if !a.LastReportedAt.Equal(b.LastReportedAt) {
+			return a.LastReportedAt.After(b.LastReportedAt)
+		}
+		return a.AdID < b.AdID
+	})
+	summaries = paginate(summaries, limit, offset)
+
+	result := make([]ReportSummary, len(summaries))
+	for i, s := range summaries {
+		result[i] = *s
+	}
+	return result, nil
+}
+
+func (r *MemoryReportRepository) Resolve(_ context.Context, adID uint, resolution string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for i := range r.reports {
+		rp := &r.reports[i]
+		if rp.AdID == adID && rp.Status == models.ReportOpen {
+			rp.Status, rp.Resolution, rp.ResolvedAt = models.ReportResolved, resolution, &at
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryReviewRepository хранит отзывы в памяти процесса.
+// Авторов он берёт из переданного MemoryUserRepository.
+type MemoryReviewRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	reviews []models.Review
+	users   *MemoryUserRepository
+}
+
+func NewMemoryReviewRepository(users *MemoryUserRepository) *MemoryReviewRepository {
+	return &MemoryReviewRepository{users: users}
+}
+
+func (r *MemoryReviewRepository) Create(_ context.Context, review *models.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rv := range r.reviews {
+		if rv.AdID == review.AdID && rv.AuthorID == review.AuthorID {
+			return ErrDuplicate
+		}
+	}
+
+	r.nextID++
+	review.ID = r.nextID
+	if review.CreatedAt.IsZero() {
+		review.CreatedAt = time.Now()
+	}
+	stored := *review
+	stored.Author = models.User{}
+	r.reviews = append(r.reviews, stored)
+	return nil
+}
+
+func (r *MemoryReviewRepository) ListByTarget(ctx context.Context, userID uint, limit, offset int) ([]models.Review, error) {
+	r.mu.Lock()
+	var reviews []models.Review
+	for _, rv := range r.reviews {
+		if rv.TargetID == userID {
+			reviews = append(reviews, rv)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(reviews, func(i, j int) bool {
+		a, b := reviews[i], reviews[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	reviews = paginate(reviews, limit, offset)
+	for i := range reviews {
+		if user, err := r.users.GetByID(ctx, reviews[i].AuthorID); err == nil {
+			reviews[i].Author = *user
+		}
+	}
+	return reviews, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemorySavedSearchRepository хранит сохранённые поиски в памяти процесса.
+type MemorySavedSearchRepository struct {
+	mu       sync.RWMutex
+	nextID   uint
+	searches map[uint]models.SavedSearch
+}
+
+func NewMemorySavedSearchRepository() *MemorySavedSearchRepository {
+	return &MemorySavedSearchRepository{searches: make(map[uint]models.SavedSearch)}
+}
+
+func (r *MemorySavedSearchRepository) Create(_ context.Context, search *models.SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	search.ID = r.nextID
+	if search.CreatedAt.IsZero() {
+		search.CreatedAt = time.Now()
+	}
+	r.searches[search.ID] = *search
+	return nil
+}
+
+func (r *MemorySavedSearchRepository) GetByID(_ context.Context, id uint) (*models.SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search, ok := r.searches[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &search, nil
+}
+
+func (r *MemorySavedSearchRepository) Delete(_ context.Context, search *models.SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.searches[search.ID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.searches, search.ID)
+	return nil
+}
+
+func (r *MemorySavedSearchRepository) ListByUser(_ context.Context, userID uint) ([]models.SavedSearch, error) {
+	searches := r.filter(func(s *models.SavedSearch) bool { return s.UserID == userID })
+	sort.Slice(searches, func(i, j int) bool {
+		if !searches[i].CreatedAt.Equal(searches[j].CreatedAt) {
+			return searches[i].CreatedAt.After(searches[j].CreatedAt)
+		}
+		return searches[i].ID > searches[j].ID
+	})
+	return searches, nil
+}
+
+func (r *MemorySavedSearchRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	searches, _ := r.ListByUser(ctx, userID)
+	return int64(len(searches)), nil
+}
+
+func (r *MemorySavedSearchRepository) ListCandidates(_ context.Context, price float64, excludeUserID uint) ([]models.SavedSearch, error) {
+	searches := r.filter(func(s *models.SavedSearch) bool {
+		return s.UserID != excludeUserID &&
+			(s.MinPrice == nil || *s.MinPrice <= price) &&
+			(s.MaxPrice == nil || *s.MaxPrice >= price)
+	})
+	sort.Slice(searches, func(i, j int) bool { return searches[i].ID < searches[j].ID })
+	return searches, nil
+}
+
+func (r *MemorySavedSearchRepository) filter(keep func(*models.SavedSearch) bool) []models.SavedSearch {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var searches []models.SavedSearch
+	for _, s := range r.searches {
+		if keep(&s) {
+			searches = append(searches, s)
+		}
+	}
+	return searches
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryThreadRepository хранит переписки и сообщения в памяти процесса.
+// Участников переписки он берёт из переданного MemoryUserRepository.
+type MemoryThreadRepository struct {
+	mu            sync.RWMutex
+	nextThreadID  uint
+	nextMessageID uint
+	threads       map[uint]models.Thread
+	messages      map[uint][]models.Message // по ID переписки, в порядке отправки
+	users         *MemoryUserRepository
+}
+
+func NewMemoryThreadRepository(users *MemoryUserRepository) *MemoryThreadRepository {
+	return &MemoryThreadRepository{
+		threads:  make(map[uint]models.Thread),
+		messages: make(map[uint][]models.Message),
+		users:    users,
+	}
+}
+
+func (r *MemoryThreadRepository) CreateThread(_ context.Context, thread *models.Thread) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.threads {
+		if t.AdID == thread.AdID && t.BuyerID == thread.BuyerID {
+			return ErrDuplicate
+		}
+	}
+
+	r.nextThreadID++
+	thread.ID = r.nextThreadID
+	if thread.CreatedAt.IsZero() {
+		thread.CreatedAt = time.Now()
+	}
+	if thread.LastMessageAt.IsZero() {
+		thread.LastMessageAt = thread.CreatedAt
+	}
+
+	stored := *thread
+	stored.Buyer, stored.Seller = models.User{}, models.User{}
+	r.threads[thread.ID] = stored
+	return nil
+}
+
+func (r *MemoryThreadRepository) GetThread(ctx context.Context, id uint) (*models.Thread, error) {
+	r.mu.RLock()
+	thread, ok := r.threads[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	r.attachUsers(ctx, &thread)
+	return &thread, nil
+}
+
+func (r *MemoryThreadRepository) FindThread(ctx context.Context, adID, buyerID uint) (*models.Thread, error) {
+	r.mu.RLock()
+	var found *models.Thread
+	for _, t := range r.threads {
+		if t.AdID == adID && t.BuyerID == buyerID {
+			found = &t
+			break
+		}
+	}
+	r.mu.RUnlock()
+	if found == nil {
+		return nil, ErrNotFound
+	}
+
+	r.attachUsers(ctx, found)
+	return found, nil
+}
+
+func (r *MemoryThreadRepository) ListThreads(ctx context.Context, userID uint, limit, offset int) ([]ThreadSummary, error) {
+	r.mu.RLock()
+	var summaries []ThreadSummary
+	for _, t := range r.threads {
+		if !t.HasParticipant(userID) {
+			continue
+		}
+		lastRead := t.SellerLastReadID
+		if t.BuyerID == userID {
+			lastRead = t.BuyerLastReadID
+		}
+		var unread int64
+		for _, m := range r.messages[t.ID] {
+			if m.SenderID != userID && m.ID > lastRead {
+				unread++
+			}
+		}
+		summaries = append(summaries, ThreadSummary{Thread: t, Unread: unread})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i].Thread, summaries[j].Thread
+		if !a.LastMessageAt.Equal(b.LastMessageAt) {
+			return a.LastMessageAt.After(b.LastMessageAt)
+		}
+		return a.ID > b.ID
+	})
+
+	summaries = paginate(summaries, limit, offset)
+	for i := range summaries {
+		r.attachUsers(ctx, &summaries[i].Thread)
+	}
+	return summaries, nil
+}
+
+func (r *MemoryThreadRepository) AddMessage(_ context.Context, message *models.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	thread, ok := r.threads[message.ThreadID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	r.nextMessageID++
+	message.ID = r.nextMessageID
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+	r.messages[thread.ID] = append(r.messages[thread.ID], *message)
+
+	thread.LastMessageAt = message.CreatedAt
+	r.threads[thread.ID] = thread
+	return nil
+}
+
+func (r *MemoryThreadRepository) ListMessages(_ context.Context, threadID, afterID uint, limit int) ([]models.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var messages []models.Message
+	for _, m := range r.messages[threadID] {
+		if m.ID > afterID {
+			messages = append(messages, m)
+		}
+	}
+	return paginate(messages, limit, 0), nil
+}
+
+func (r *MemoryThreadRepository) MarkRead(_ context.Context, threadID, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	thread, ok := r.threads[threadID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	var latest uint
+	if msgs := r.messages[threadID]; len(msgs) > 0 {
+		latest = msgs[len(msgs)-1].ID
+	}
+	if thread.BuyerID == userID {
+		thread.BuyerLastReadID = latest
+	}
+	if thread.SellerID == userID {
+		thread.SellerLastReadID = latest
+	}
+	r.threads[threadID] = thread
+	return nil
+}
+
+// attachUsers подставляет участников переписки, как это делает Preload.
+func (r *MemoryThreadRepository) attachUsers(ctx context.Context, thread *models.Thread) {
+	if user, err := r.users.GetByID(ctx, thread.BuyerID); err == nil {
+		thread.Buyer = *user
+	}
+	if user, err := r.users.GetByID(ctx, thread.SellerID); err == nil {
+		thread.Seller = *user
+	}
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+// MemoryWebhookRepository хранит вебхуки и журнал доставок в памяти процесса.
+type MemoryWebhookRepository struct {
+	mu             sync.Mutex
+	nextID         uint
+	nextDeliveryID uint
+	webhooks       map[uint]models.Webhook
+	deliveries     map[uint]models.WebhookDelivery
+	users          *MemoryUserRepository
+}
+
+func NewMemoryWebhookRepository(users *MemoryUserRepository) *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		webhooks:   make(map[uint]models.Webhook),
+		deliveries: make(map[uint]models.WebhookDelivery),
+		users:      users,
+	}
+}
+
+func (r *MemoryWebhookRepository) Create(_ context.Context, webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	webhook.ID = r.nextID
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now()
+	}
+	r.webhooks[webhook.ID] = *webhook
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetByID(_ context.Context, id uint) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &webhook, nil
+}
+
+func (r *MemoryWebhookRepository) Delete(_ context.Context, webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[webhook.ID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.webhooks, webhook.ID)
+	for id, d := range r.deliveries {
+		if d.WebhookID == webhook.ID {
+			delete(r.deliveries, id)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ListByUser(_ context.Context, userID uint) ([]models.Webhook, error) {
+	return r.listWebhooks(func(w *models.Webhook) bool { return w.UserID == userID }), nil
+}
+
+func (r *MemoryWebhookRepository) ListSubscribed(ctx context.Context, event string, adOwnerID uint) ([]models.Webhook, error) {
+	webhooks := r.listWebhooks(func(w *models.Webhook) bool {
+		return (w.UserID == adOwnerID || w.AllAds) && w.Subscribed(event)
+	})
+
+	// Права на all_ads проверяются на момент события, как в GORM-версии
+	now := time.Now()
+	kept := webhooks[:0]
+	for _, w := range webhooks {
+		if w.UserID != adOwnerID {
+			owner, err := r.users.GetByID(ctx, w.UserID)
+			if err != nil || !owner.Can(models.PermWebhooksAllAds) || owner.StatusAt(now) != models.UserActive {
+				continue
+			}
+		}
+		kept = append(kept, w)
+	}
+	return kept, nil
+}
+
+func (r *MemoryWebhookRepository) listWebhooks(keep func(*models.Webhook) bool) []models.Webhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var webhooks []models.Webhook
+	for _, w := range r.webhooks {
+		if keep(&w) {
+			webhooks = append(webhooks, w)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks
+}
+
+func (r *MemoryWebhookRepository) CreateDelivery(_ context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextDeliveryID++
+	delivery.ID = r.nextDeliveryID
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ListDeliveries(_ context.Context, webhookID uint, limit, offset int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	var deliveries []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.WebhookID == webhookID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		a, b := deliveries[i], deliveries[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return paginate(deliveries, limit, offset), nil
+}
+
+func (r *MemoryWebhookRepository) ClaimDueDeliveries(_ context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	due = paginate(due, limit, 0)
+
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		r.deliveries[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *MemoryWebhookRepository) UpdateDelivery(_ context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deliveries[delivery.ID]; !ok {
+		return ErrNotFound
+	}
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
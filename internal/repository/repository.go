@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+)
+
+var (
+	// ErrNotFound возвращается, когда запрошенная запись отсутствует.
+	ErrNotFound = errors.New("запись не найдена")
+	// ErrDuplicate возвращается при нарушении уникальности.
+	ErrDuplicate = errors.New("запись уже существует")
+	// ErrConflict возвращается, когда запись успели изменить параллельно.
+	ErrConflict = errors.New("запись была изменена")
+)
+
+// Transactor выполняет fn в одной транзакции: репозитории, получившие ctx
+// из fn, либо все фиксируют изменения, либо все откатывают.
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// UserRepository описывает хранилище пользователей.
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// GetByEmail ищет пользователя по адресу почты; пустой адрес не найдётся.
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	// SetPassword сохраняет новый хэш пароля.
+	SetPassword(ctx context.Context, userID uint, hash string) error
+	// MarkEmailVerified отмечает почту подтверждённой в момент at.
+	// Уже подтверждённую почту не меняет.
+	MarkEmailVerified(ctx context.Context, userID uint, at time.Time) error
+	SetRole(ctx context.Context, userID uint, role models.Role) error
+	// SetStatus меняет статус учётной записи, его срок и причину.
+	SetStatus(ctx context.Context, userID uint, status models.UserStatus, until *time.Time, reason string) error
+	// ClearExpiredStatus снимает ограничение, срок которого истёк к now.
+	// Действующее или бессрочное ограничение не меняется.
+	ClearExpiredStatus(ctx context.Context, userID uint, now time.Time) error
+	// AddRating учитывает новую оценку в агрегате пользователя.
+	AddRating(ctx context.Context, userID uint, rating int) error
+}
+
+// AdFilter описывает параметры выборки ленты объявлений.
+type AdFilter struct {
+	IDs         []uint // пустой список — без ограничения по ID
+	MinPrice    *float64
+	MaxPrice    *float64
+	CategoryIDs []uint // пустой список — без фильтра по разделу
+	Statuses    []models.AdStatus
+	UserID      *uint     // только объявления этого пользователя
+	ActiveAt    time.Time // если задано — без объявлений, истёкших к этому моменту
+	VisibleOnly bool      // без объявлений, скрытых модерацией, и объявлений забаненных пользователей
+	Query       string    // полнотекстовый поиск по заголовку и описанию
+	SortField   string    // created_at, price, title или relevance (только вместе с Query)
+	Order       string    // ASC или DESC
+	After       *AdCursor // keyset-пагинация: только объявления после курсора
+	Limit       int
+	Offset      int
+}
+
+// AdCursor — позиция в ленте: значение поля сортировки и ID последнего
+// показанного объявления. Заполняется только поле, соответствующее SortField.
+type AdCursor struct {
+	CreatedAt time.Time
+	Price     float64
+	Title     string
+	ID        uint
+}
+
+// Value возвращает значение курсора для поля сортировки.
+func (c *AdCursor) Value(field string) any {
+	switch field {
+	case "price":
+		return c.Price
+	case "title":
+		return c.Title
+	default:
+		return c.CreatedAt
+	}
+}
+
+// AdRepository описывает хранилище объявлений.
+// Все методы чтения возвращают объявления с заполненным полем User.
+type AdRepository interface {
+	Create(ctx context.Context, ad *models.Ad) error
+	GetByID(ctx context.Context, id uint) (*models.Ad, error)
+	// Update сохраняет содержимое объявления: заголовок, описание, картинку,
+	// категорию и цену (кроме аукционов, где цену ведут ставки). Состояние,
+	// покупателя и скрытие меняют отдельные методы. Если объявление уже
+	// нельзя редактировать, возвращает ErrConflict.
+	Update(ctx context.Context, ad *models.Ad) error
+	Delete(ctx context.Context, ad *models.Ad) error
+	// UpdateStatus переводит объявление из состояния from в to и,
+	// если expiresAt не nil, заодно меняет срок показа.
+	// Если состояние уже не from, возвращает ErrConflict.
+	UpdateStatus(ctx context.Context, id uint, from, to models.AdStatus, expiresAt *time.Time) error
+	// ExpirePublished переводит опубликованные объявления с истёкшим сроком
+	// в состояние expired и возвращает их число.
+	ExpirePublished(ctx context.Context, now time.Time) (int64, error)
+	List(ctx context.Context, filter AdFilter) ([]models.Ad, error)
+	// Count возвращает число объявлений под фильтром без учёта пагинации.
+	Count(ctx context.Context, filter AdFilter) (int64, error)
+	// SetBuyer запоминает покупателя объявления; nil снимает его.
+	SetBuyer(ctx context.Context, id uint, buyerID *uint) error
+	// SetHidden скрывает объявление из ленты по причине reason,
+	// а с пустой причиной возвращает его туда.
+	SetHidden(ctx context.Context, id uint, reason models.HiddenReason) error
+	// Highlight возвращает фрагменты объявлений с подсвеченными словами запроса.
+	Highlight(ctx context.Context, query string, adIDs []uint) (map[uint]AdHighlight, error)
+}
+
+// SessionRepository описывает хранилище сессий и refresh-токенов.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userID uint) error
+
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// ConsumeRefreshToken атомарно помечает токен использованным.
+	// Возвращает false, если токен уже был использован ранее.
+	ConsumeRefreshToken(ctx context.Context, id uint) (bool, error)
+}
+
+// UserTokenRepository описывает хранилище одноразовых токенов из писем.
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	Get(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.UserToken, error)
+	// Consume атомарно помечает токен использованным.
+	// Возвращает false, если токен уже был использован ранее.
+	Consume(ctx context.Context, id uint) (bool, error)
+	// InvalidateUser помечает использованными все неиспользованные токены
+	// пользователя с назначением purpose.
+	InvalidateUser(ctx context.Context, userID uint, purpose models.TokenPurpose) error
+}
+
+// CategoryRepository описывает хранилище разделов каталога.
+type CategoryRepository interface {
+	Create(ctx context.Context, category *models.Category) error
+	GetByID(ctx context.Context, id uint) (*models.Category, error)
+	Update(ctx context.Context, category *models.Category) error
+	Delete(ctx context.Context, category *models.Category) error
+	List(ctx context.Context) ([]models.Category, error)
+	// DescendantIDs возвращает ID раздела и всех его потомков.
+	DescendantIDs(ctx context.Context, id uint) ([]uint, error)
+}
+
+// AdImageRepository описывает хранилище метаданных картинок объявлений.
+// Списки всегда упорядочены по Position.
+type AdImageRepository interface {
+	// Append добавляет картинку в конец списка объявления и выставляет ей
+	// Position. Если у объявления уже limit картинок, возвращает ErrConflict;
+	// параллельные загрузки не могут превысить лимит.
+	Append(ctx context.Context, image *models.AdImage, limit int) error
+	GetByID(ctx context.Context, id uint) (*models.AdImage, error)
+	UpdatePosition(ctx context.Context, id uint, position int) error
+	UpdateVariants(ctx context.Context, id uint, thumbKey, mediumKey string) error
+	Delete(ctx context.Context, image *models.AdImage) error
+	ListByAd(ctx context.Context, adID uint) ([]models.AdImage, error)
+	ListByAds(ctx context.Context, adIDs []uint) ([]models.AdImage, error)
+}
+
+// FavoriteRepository описывает хранилище избранного.
+type FavoriteRepository interface {
+	// Add возвращает ErrDuplicate, если объявление уже в избранном.
+	Add(ctx context.Context, userID, adID uint) error
+	// Remove возвращает ErrNotFound, если объявления нет в избранном.
+	Remove(ctx context.Context, userID, adID uint) error
+	// ListAds возвращает избранные объявления пользователя в указанных
+	// состояниях, недавно добавленные первыми.
+	ListAds(ctx context.Context, userID uint, statuses []models.AdStatus, limit, offset int) ([]models.Ad, error)
+	// FavoritedAdIDs возвращает, какие из объявлений пользователь добавил в избранное.
+	FavoritedAdIDs(ctx context.Context, userID uint, adIDs []uint) (map[uint]bool, error)
+	// CountByAds возвращает, сколько пользователей добавили каждое объявление в избранное.
+	CountByAds(ctx context.Context, adIDs []uint) (map[uint]int64, error)
+	// UserIDsByAd возвращает пользователей, добавивших объявление в избранное.
+	UserIDsByAd(ctx context.Context, adID uint) ([]uint, error)
+}
+
+// ThreadSummary — переписка с числом непрочитанных сообщений для пользователя.
+type ThreadSummary struct {
+	Thread models.Thread
+	Unread int64
+}
+
+// ThreadRepository описывает хранилище переписок и сообщений.
+// Переписки возвращаются с заполненными Buyer и Seller.
+type ThreadRepository interface {
+	// CreateThread возвращает ErrDuplicate, если у покупателя уже есть
+	// переписка по этому объявлению.
+	CreateThread(ctx context.Context, thread *models.Thread) error
+	GetThread(ctx context.Context, id uint) (*models.Thread, error)
+	FindThread(ctx context.Context, adID, buyerID uint) (*models.Thread, error)
+	// ListThreads возвращает переписки пользователя, последние по активности первыми.
+	ListThreads(ctx context.Context, userID uint, limit, offset int) ([]ThreadSummary, error)
+
+	// AddMessage сохраняет сообщение и сдвигает LastMessageAt переписки.
+	AddMessage(ctx context.Context, message *models.Message) error
+	// ListMessages возвращает сообщения с ID больше afterID в порядке отправки.
+	ListMessages(ctx context.Context, threadID, afterID uint, limit int) ([]models.Message, error)
+	// MarkRead отмечает все сообщения переписки прочитанными пользователем.
+	MarkRead(ctx context.Context, threadID, userID uint) error
+}
+
+// SavedSearchRepository описывает хранилище сохранённых поисков.
+type SavedSearchRepository interface {
+	Create(ctx context.Context, search *models.SavedSearch) error
+	GetByID(ctx context.Context, id uint) (*models.SavedSearch, error)
+	Delete(ctx context.Context, search *models.SavedSearch) error
+	ListByUser(ctx context.Context, userID uint) ([]models.SavedSearch, error)
+	CountByUser(ctx context.Context, userID uint) (int64, error)
+	// ListCandidates возвращает чужие для excludeUserID поиски, в ценовой
+	// диапазон которых попадает price. Остальные условия проверяет вызывающий.
+	ListCandidates(ctx context.Context, price float64, excludeUserID uint) ([]models.SavedSearch, error)
+}
+
+// NotificationRepository описывает хранилище уведомлений.
+type NotificationRepository interface {
+	// Create возвращает ErrDuplicate, если у пользователя уже есть
+	// уведомление с тем же DedupKey.
+	Create(ctx context.Context, notification *models.Notification) error
+	// ListByUser возвращает уведомления пользователя, новые первыми.
+	ListByUser(ctx context.Context, userID uint, unreadOnly bool, limit, offset int) ([]models.Notification, error)
+	CountUnread(ctx context.Context, userID uint) (int64, error)
+	// MarkRead отмечает уведомление прочитанным. Чужое уведомление — ErrNotFound.
+	MarkRead(ctx context.Context, userID, id uint) error
+	// MarkAllRead отмечает прочитанными все уведомления пользователя
+	// и возвращает число изменённых.
+	MarkAllRead(ctx context.Context, userID uint) (int64, error)
+}
+
+// WebhookRepository описывает хранилище вебхуков и журнала их доставок.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	GetByID(ctx context.Context, id uint) (*models.Webhook, error)
+	// Delete удаляет вебхук вместе с журналом доставок.
+	Delete(ctx context.Context, webhook *models.Webhook) error
+	ListByUser(ctx context.Context, userID uint) ([]models.Webhook, error)
+	// ListSubscribed возвращает вебхуки, подписанные на событие об объявлении
+	// пользователя adOwnerID: его собственные и вебхуки с AllAds тех, у кого
+	// сейчас есть право PermWebhooksAllAds и нет ограничения учётной записи.
+	ListSubscribed(ctx context.Context, event string, adOwnerID uint) ([]models.Webhook, error)
+
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// ListDeliveries возвращает журнал доставок вебхука, новые первыми.
+	ListDeliveries(ctx context.Context, webhookID uint, limit, offset int) ([]models.WebhookDelivery, error)
+	// ClaimDueDeliveries забирает до limit ожидающих доставок, время которых
+	// наступило, и откладывает их следующую попытку на lease, чтобы другой
+	// обработчик не взял их одновременно.
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// UpdateDelivery сохраняет результат попытки доставки.
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+// OutboxRepository описывает хранилище исходящих событий.
+type OutboxRepository interface {
+	// Add записывает событие; вызывайте внутри Transactor.Transaction
+	// вместе с изменением, которое его вызвало.
+	Add(ctx context.Context, event *models.OutboxEvent) error
+	// ClaimPending забирает до limit неопубликованных событий, время которых
+	// наступило, в порядке записи и откладывает их следующую попытку на lease.
+	ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error)
+	// MarkPublished отмечает событие опубликованным.
+	MarkPublished(ctx context.Context, id uint, at time.Time) error
+	// MarkFailed сохраняет ошибку публикации и время следующей попытки.
+	MarkFailed(ctx context.Context, id uint, attempts int, next time.Time, lastError string) error
+	// PurgePublished удаляет события, опубликованные раньше before.
+	PurgePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OfferRepository описывает хранилище предложений цены.
+type OfferRepository interface {
+	// Create возвращает ErrDuplicate, если у покупателя уже есть открытое
+	// предложение на это объявление.
+	Create(ctx context.Context, offer *models.Offer) error
+	GetByID(ctx context.Context, id uint) (*models.Offer, error)
+	// ListByAd возвращает предложения на объявление, новые первыми.
+	// Если buyerID задан — только предложения этого покупателя.
+	ListByAd(ctx context.Context, adID uint, buyerID *uint) ([]models.Offer, error)
+	// ListByBuyer возвращает предложения покупателя, новые первыми.
+	ListByBuyer(ctx context.Context, buyerID uint, limit, offset int) ([]models.Offer, error)
+	// Update сохраняет предложение, если его не изменили с момента чтения,
+	// и увеличивает Version. Иначе возвращает ErrConflict.
+	Update(ctx context.Context, offer *models.Offer) error
+	// RejectPending отклоняет открытые предложения на объявление, кроме exceptID,
+	// и возвращает отклонённые.
+	RejectPending(ctx context.Context, adID, exceptID uint) ([]models.Offer, error)
+	// CancelAccepted переводит принятые предложения на объявление в cancelled
+	// и возвращает их.
+	CancelAccepted(ctx context.Context, adID uint) ([]models.Offer, error)
+	// ExpirePending переводит в expired открытые предложения с истёкшим сроком.
+	ExpirePending(ctx context.Context, now time.Time) (int64, error)
+}
+
+// AuctionRepository описывает хранилище аукционов и ставок.
+type AuctionRepository interface {
+	Create(ctx context.Context, auction *models.Auction) error
+	Get(ctx context.Context, adID uint) (*models.Auction, error)
+	// ListByAds возвращает аукционы перечисленных объявлений по ID объявления.
+	ListByAds(ctx context.Context, adIDs []uint) (map[uint]models.Auction, error)
+	// PlaceBid записывает ставку и сохраняет новое состояние торгов (текущую
+	// ставку, лидера, срок окончания), а также текущую цену объявления.
+	// Если аукцион изменили с момента чтения или он закрыт — ErrConflict.
+	PlaceBid(ctx context.Context, auction *models.Auction, bid *models.Bid) error
+	// ListBids возвращает ставки, новые первыми.
+	ListBids(ctx context.Context, adID uint, limit, offset int) ([]models.Bid, error)
+	// ListDue возвращает до limit открытых аукционов, закончившихся к now.
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.Auction, error)
+	// Close закрывает аукцион, если его не изменили с момента чтения,
+	// иначе возвращает ErrConflict.
+	Close(ctx context.Context, auction *models.Auction) error
+}
+
+// ReviewRepository описывает хранилище отзывов.
+type ReviewRepository interface {
+	// Create возвращает ErrDuplicate, если автор уже оставил отзыв по этому объявлению.
+	Create(ctx context.Context, review *models.Review) error
+	// ListByTarget возвращает отзывы о пользователе, новые первыми.
+	ListByTarget(ctx context.Context, userID uint, limit, offset int) ([]models.Review, error)
+}
+
+// ModerationRepository описывает журнал действий модераторов.
+type ModerationRepository interface {
+	Record(ctx context.Context, action *models.ModerationAction) error
+	// List возвращает записи журнала, новые первыми. Ненулевой adID
+	// оставляет только действия над этим объявлением.
+	List(ctx context.Context, adID uint, limit, offset int) ([]models.ModerationAction, error)
+}
+
+// ReportSummary — открытые жалобы на одно объявление в очереди модерации.
+type ReportSummary struct {
+	AdID           uint
+	Reports        int64
+	Reasons        map[models.ReportReason]int64
+	LastReportedAt time.Time
+}
+
+// ReportRepository описывает хранилище жалоб на объявления.
+type ReportRepository interface {
+	// Create возвращает ErrDuplicate, если у пользователя уже есть открытая
+	// жалоба на объявление. Жалоб фильтра без ReporterID может быть несколько.
+	Create(ctx context.Context, report *models.Report) error
+	// CountOpen возвращает число открытых жалоб пользователей на объявление.
+	CountOpen(ctx context.Context, adID uint) (int64, error)
+	// ListOpen возвращает открытые жалобы на объявление, старые первыми.
+	ListOpen(ctx context.Context, adID uint) ([]models.Report, error)
+	// Queue возвращает объявления с открытыми жалобами: сначала те,
+	// на которые жалуются чаще, при равенстве — с более свежей жалобой.
+	Queue(ctx context.Context, limit, offset int) ([]ReportSummary, error)
+	// Resolve закрывает все открытые жалобы на объявление.
+	Resolve(ctx context.Context, adID uint, resolution string, at time.Time) (int64, error)
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Маркеры подсветки совпадений. Поисковый движок расставляет их вместо тегов,
+// а FormatHighlight экранирует текст и только потом превращает маркеры в <mark>,
+// поэтому HTML из текста объявления не попадает в ответ как разметка.
+const (
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+)
+
+// AdHighlight — фрагменты объявления с подсвеченными совпадениями.
+type AdHighlight struct {
+	Title       string
+	Description string
+}
+
+// FormatHighlight экранирует фрагмент и заменяет маркеры на <mark>...</mark>.
+func FormatHighlight(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	return strings.ReplaceAll(s, highlightStop, "</mark>")
+}
+
+// searchTerms разбивает поисковый запрос на слова в нижнем регистре.
+func searchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// naiveMatch — упрощённая замена полнотекстового поиска для хранилища в памяти:
+// все слова запроса должны встречаться в заголовке или описании как подстроки.
+// Возвращает релевантность: совпадения в заголовке весят вдвое больше.
+func naiveMatch(terms []string, title, description string) (int, bool) {
+	title, description = strings.ToLower(title), strings.ToLower(description)
+
+	rank := 0
+	for _, term := range terms {
+		inTitle := strings.Count(title, term)
+		inDescription := strings.Count(description, term)
+		if inTitle+inDescription == 0 {
+			return 0, false
+		}
+		rank += 2*inTitle + inDescription
+	}
+	return rank, true
+}
+
+// naiveHighlight расставляет маркеры вокруг вхождений слов запроса.
+// Длинный текст обрезается до окна вокруг первого совпадения.
+func naiveHighlight(terms []string, text string, window int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	marked := make([]bool, len(runes))
+
+	first := -1
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != term {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if window > 0 && len(runes) > window {
+		start = max(0, first-window/4)
+		end = min(len(runes), start+window)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString(highlightStart)
+		}
+		b.WriteRune(runes[i])
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			b.WriteString(highlightStop)
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -2,49 +2,361 @@ package router
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/WalnutBagel/go-marketplace/internal/api"
 	"github.com/WalnutBagel/go-marketplace/internal/middleware"
+	"github.com/WalnutBagel/go-marketplace/internal/models"
 )
 
-func NewRouter() http.Handler {
+func NewRouter(srv *api.Server) http.Handler {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/register", api.RegisterHandler)
-	mux.HandleFunc("/login", api.LoginHandler)
-	mux.Handle("/ads", middleware.AuthMiddleware(http.HandlerFunc(adRouter)))
-	mux.Handle("/ads/", middleware.AuthMiddleware(http.HandlerFunc(adRouter)))
+	mux.HandleFunc("/register", srv.RegisterHandler)
+	mux.HandleFunc("/login", srv.LoginHandler)
+	mux.HandleFunc("/token/refresh", srv.RefreshHandler)
+	mux.HandleFunc("/logout", srv.LogoutHandler)
+	mux.HandleFunc("/email/verify", methodHandler(http.MethodPost, srv.VerifyEmailHandler))
+	mux.HandleFunc("/email/verify/resend", methodHandler(http.MethodPost, srv.ResendVerificationHandler))
+	mux.HandleFunc("/password/forgot", methodHandler(http.MethodPost, srv.ForgotPasswordHandler))
+	mux.HandleFunc("/password/reset", methodHandler(http.MethodPost, srv.ResetPasswordHandler))
+	mux.HandleFunc("/images/", srv.ServeImageHandler)
+
+	auth := middleware.AuthMiddleware(srv.Sessions, srv.Users)
+	mux.Handle("/ads", auth(adRouter(srv)))
+	mux.Handle("/ads/", auth(adRouter(srv)))
+	mux.Handle("/me/", auth(meRouter(srv)))
+	mux.Handle("/events", auth(methodHandler(http.MethodGet, srv.EventsHandler)))
+	mux.Handle("/offers/", auth(offerRouter(srv)))
+	mux.Handle("/users/", auth(userRouter(srv)))
+	mux.Handle("/threads", auth(threadRouter(srv)))
+	mux.Handle("/threads/", auth(threadRouter(srv)))
+	mux.Handle("/categories", auth(categoryRouter(srv)))
+	mux.Handle("/categories/", auth(categoryRouter(srv)))
+	mux.Handle("/moderation/", auth(requirePermission(models.PermModerateAds, moderationRouter(srv))))
+	mux.Handle("/admin/", auth(requirePermission(models.PermManageUsers, adminRouter(srv))))
 
 	return mux
 }
 
-func adRouter(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
+func adRouter(srv *api.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// /ads, /ads/{id}, /ads/{id}/{action}, /ads/{id}/favorite, /ads/{id}/threads,
+		// /ads/{id}/offers, /ads/{id}/bids, /ads/{id}/reviews, /ads/{id}/report, /ads/{id}/images, /ads/{id}/images/{imageID}
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		switch {
+		case len(segments) == 1:
+			switch r.Method {
+			case http.MethodGet:
+				srv.GetAdsHandler(w, r)
+			case http.MethodPost:
+				srv.CreateAdHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 2:
+			switch r.Method {
+			case http.MethodPut:
+				srv.UpdateAdHandler(w, r)
+			case http.MethodDelete:
+				srv.DeleteAdHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 3 && api.IsAdAction(segments[2]):
+			if r.Method != http.MethodPost {
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+				return
+			}
+			srv.AdActionHandler(w, r)
+
+		case len(segments) == 3 && segments[2] == "renew":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+				return
+			}
+			srv.RenewAdHandler(w, r)
+
+		case len(segments) == 3 && segments[2] == "favorite":
+			switch r.Method {
+			case http.MethodPost:
+				srv.AddFavoriteHandler(w, r)
+			case http.MethodDelete:
+				srv.RemoveFavoriteHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 3 && segments[2] == "threads":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+				return
+			}
+			srv.StartThreadHandler(w, r)
+
+		case len(segments) == 3 && segments[2] == "offers":
+			switch r.Method {
+			case http.MethodGet:
+				srv.ListAdOffersHandler(w, r)
+			case http.MethodPost:
+				srv.CreateOfferHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 3 && segments[2] == "bids":
+			switch r.Method {
+			case http.MethodGet:
+				srv.ListBidsHandler(w, r)
+			case http.MethodPost:
+				srv.PlaceBidHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 3 && segments[2] == "reviews":
+			methodHandler(http.MethodPost, srv.CreateReviewHandler)(w, r)
+
+		case len(segments) == 3 && segments[2] == "report":
+			methodHandler(http.MethodPost, srv.CreateReportHandler)(w, r)
+
+		case len(segments) == 3 && segments[2] == "images":
+			switch r.Method {
+			case http.MethodPost:
+				srv.UploadAdImageHandler(w, r)
+			case http.MethodPut:
+				srv.ReorderAdImagesHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 4 && segments[2] == "images":
+			switch r.Method {
+			case http.MethodDelete:
+				srv.DeleteAdImageHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
 
-	if path == "/ads" {
-		switch r.Method {
-		case http.MethodGet:
-			api.GetAdsHandler(w, r)
-		case http.MethodPost:
-			api.CreateAdHandler(w, r)
 		default:
-			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func offerRouter(srv *api.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// /offers/{id}/accept, /offers/{id}/reject, /offers/{id}/counter, /offers/{id}/withdraw
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		switch {
+		case len(segments) == 3 && slices.Contains([]string{"accept", "reject", "counter", "withdraw"}, segments[2]):
+			methodHandler(http.MethodPost, srv.OfferActionHandler)(w, r)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func userRouter(srv *api.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// /users/{id}, /users/{id}/reviews
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		switch {
+		case len(segments) == 2:
+			methodHandler(http.MethodGet, srv.GetUserHandler)(w, r)
+
+		case len(segments) == 3 && segments[2] == "reviews":
+			methodHandler(http.MethodGet, srv.ListUserReviewsHandler)(w, r)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func threadRouter(srv *api.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// /threads, /threads/{id}/messages, /threads/{id}/read
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		switch {
+		case len(segments) == 1:
+			if r.Method != http.MethodGet {
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+				return
+			}
+			srv.ListThreadsHandler(w, r)
+
+		case len(segments) == 3 && segments[2] == "messages":
+			switch r.Method {
+			case http.MethodGet:
+				srv.ListMessagesHandler(w, r)
+			case http.MethodPost:
+				srv.PostMessageHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 3 && segments[2] == "read":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+				return
+			}
+			srv.MarkThreadReadHandler(w, r)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func meRouter(srv *api.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// /me/favorites, /me/searches, /me/searches/{id}, /me/notifications,
+		// /me/notifications/unread-count, /me/notifications/read-all, /me/notifications/{id}/read,
+		// /me/webhooks, /me/webhooks/{id}, /me/webhooks/{id}/deliveries, /me/offers
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		switch {
+		case len(segments) == 2 && segments[1] == "favorites":
+			methodHandler(http.MethodGet, srv.ListFavoritesHandler)(w, r)
+
+		case len(segments) == 2 && segments[1] == "searches":
+			switch r.Method {
+			case http.MethodGet:
+				srv.ListSavedSearchesHandler(w, r)
+			case http.MethodPost:
+				srv.CreateSavedSearchHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 3 && segments[1] == "searches":
+			methodHandler(http.MethodDelete, srv.DeleteSavedSearchHandler)(w, r)
+
+		case len(segments) == 2 && segments[1] == "notifications":
+			methodHandler(http.MethodGet, srv.ListNotificationsHandler)(w, r)
+
+		case len(segments) == 3 && segments[1] == "notifications" && segments[2] == "unread-count":
+			methodHandler(http.MethodGet, srv.UnreadCountHandler)(w, r)
+
+		case len(segments) == 3 && segments[1] == "notifications" && segments[2] == "read-all":
+			methodHandler(http.MethodPost, srv.MarkAllNotificationsReadHandler)(w, r)
+
+		case len(segments) == 4 && segments[1] == "notifications" && segments[3] == "read":
+			methodHandler(http.MethodPost, srv.MarkNotificationReadHandler)(w, r)
+
+		case len(segments) == 2 && segments[1] == "offers":
+			methodHandler(http.MethodGet, srv.ListMyOffersHandler)(w, r)
+
+		case len(segments) == 2 && segments[1] == "webhooks":
+			switch r.Method {
+			case http.MethodGet:
+				srv.ListWebhooksHandler(w, r)
+			case http.MethodPost:
+				srv.CreateWebhookHandler(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+
+		case len(segments) == 3 && segments[1] == "webhooks":
+			methodHandler(http.MethodDelete, srv.DeleteWebhookHandler)(w, r)
+
+		case len(segments) == 4 && segments[1] == "webhooks" && segments[3] == "deliveries":
+			methodHandler(http.MethodGet, srv.ListWebhookDeliveriesHandler)(w, r)
+
+		default:
+			http.NotFound(w, r)
 		}
-		return
 	}
+}
+
+func categoryRouter(srv *api.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/categories" {
+			switch r.Method {
+			case http.MethodGet:
+				srv.ListCategoriesHandler(w, r)
+			case http.MethodPost:
+				requirePermission(models.PermManageCategories, srv.CreateCategoryHandler)(w, r)
+			default:
+				http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			}
+			return
+		}
 
-	if strings.HasPrefix(path, "/ads/") {
 		switch r.Method {
 		case http.MethodPut:
-			api.UpdateAdHandler(w, r)
+			requirePermission(models.PermManageCategories, srv.UpdateCategoryHandler)(w, r)
 		case http.MethodDelete:
-			api.DeleteAdHandler(w, r)
+			requirePermission(models.PermManageCategories, srv.DeleteCategoryHandler)(w, r)
 		default:
 			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		}
-		return
 	}
+}
+
+func moderationRouter(srv *api.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// /moderation/actions, /moderation/queue, /moderation/ads/{id}/reports, /moderation/ads/{id}/actions
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		switch {
+		case len(segments) == 2 && segments[1] == "actions":
+			methodHandler(http.MethodGet, srv.ListModerationActionsHandler)(w, r)
 
-	http.NotFound(w, r)
+		case len(segments) == 2 && segments[1] == "queue":
+			methodHandler(http.MethodGet, srv.ModerationQueueHandler)(w, r)
+
+		case len(segments) == 4 && segments[1] == "ads" && segments[3] == "reports":
+			methodHandler(http.MethodGet, srv.ListAdReportsHandler)(w, r)
+
+		case len(segments) == 4 && segments[1] == "ads" && segments[3] == "actions":
+			methodHandler(http.MethodPost, srv.ModerateAdHandler)(w, r)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func adminRouter(srv *api.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// /admin/users/{id}/role, /admin/users/{id}/status
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		switch {
+		case len(segments) == 4 && segments[1] == "users" && segments[3] == "role":
+			methodHandler(http.MethodPut, srv.SetUserRoleHandler)(w, r)
+		case len(segments) == 4 && segments[1] == "users" && segments[3] == "status":
+			methodHandler(http.MethodPut, srv.SetUserStatusHandler)(w, r)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// requirePermission пропускает к обработчику только запросы с правом
+// permission в токене.
+func requirePermission(permission models.Permission, h http.HandlerFunc) http.HandlerFunc {
+	return middleware.RequirePermission(permission)(h).ServeHTTP
+}
+
+// methodHandler пропускает к обработчику только запросы с указанным методом.
+func methodHandler(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
 }
@@ -0,0 +1,73 @@
+package screening
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config описывает набор фильтров. Решение allow отключает фильтр.
+type Config struct {
+	BannedWords        []string `json:"banned_words"`
+	BannedPatterns     []string `json:"banned_patterns"`
+	BannedVerdict      Verdict  `json:"banned_verdict"`
+	ContactVerdict     Verdict  `json:"contact_verdict"`
+	DuplicateVerdict   Verdict  `json:"duplicate_verdict"`
+	DuplicateThreshold float64  `json:"duplicate_threshold"`
+}
+
+// DefaultConfig — настройки без файла конфигурации: контакты отправляются
+// на модерацию, дубликаты отклоняются, запрещённых слов нет.
+func DefaultConfig() Config {
+	return Config{
+		BannedVerdict:      Reject,
+		ContactVerdict:     Flag,
+		DuplicateVerdict:   Reject,
+		DuplicateThreshold: 0.9,
+	}
+}
+
+// LoadConfig читает JSON-файл поверх DefaultConfig. Пустой path — настройки по умолчанию.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, v := range []Verdict{cfg.BannedVerdict, cfg.ContactVerdict, cfg.DuplicateVerdict} {
+		if _, err := ParseVerdict(string(v)); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.DuplicateThreshold <= 0 || cfg.DuplicateThreshold > 1 {
+		return Config{}, fmt.Errorf("%s: duplicate_threshold должен быть в (0, 1]", path)
+	}
+	return cfg, nil
+}
+
+// NewChain собирает фильтры по конфигурации: запрещённые слова, контакты, дубликаты.
+func NewChain(cfg Config, ads AdLister) (Chain, error) {
+	var chain Chain
+	if cfg.BannedVerdict != Allow && (len(cfg.BannedWords) > 0 || len(cfg.BannedPatterns) > 0) {
+		words, err := NewWordFilter(cfg.BannedWords, cfg.BannedPatterns, cfg.BannedVerdict)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, words)
+	}
+	if cfg.ContactVerdict != Allow {
+		chain = append(chain, NewContactFilter(cfg.ContactVerdict))
+	}
+	if cfg.DuplicateVerdict != Allow {
+		chain = append(chain, NewDuplicateFilter(ads, cfg.DuplicateThreshold, cfg.DuplicateVerdict))
+	}
+	return chain, nil
+}
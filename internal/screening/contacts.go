@@ -0,0 +1,55 @@
+package screening
+
+import (
+	"context"
+	"regexp"
+	"unicode"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[\p{L}\p{N}._%+-]+@[\p{L}\p{N}-]+(?:\.[\p{L}\p{N}-]+)*\.\p{L}{2,}`)
+	// \b в RE2 понимает только ASCII, поэтому границы доменов — явные
+	linkPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+|(?:^|[^\p{L}\p{N}.@-])[\p{L}\p{N}-]+\.(?:ru|com|net|org|io|me|su|by|kz|ua|рф)(?:$|[^\p{L}\p{N}])`)
+	// Кандидат в телефоны: цифры с пробелами, дефисами, скобками и точками.
+	// Телефоном считается кандидат с 10–15 цифрами, чтобы не ловить цены.
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s\-().]{8,}\d`)
+)
+
+// ContactFilter находит в тексте телефоны, адреса почты и ссылки, через
+// которые покупателя уводят общаться в обход площадки.
+type ContactFilter struct {
+	verdict Verdict
+}
+
+// NewContactFilter создаёт фильтр; найденный контакт приводит к решению verdict.
+func NewContactFilter(verdict Verdict) *ContactFilter {
+	return &ContactFilter{verdict: verdict}
+}
+
+func (f *ContactFilter) Check(_ context.Context, c *Content) (Decision, error) {
+	text := c.Title + "\n" + c.Description
+	switch {
+	case emailPattern.MatchString(text):
+		return decide(f.verdict, "в тексте указан адрес электронной почты", "в тексте указан адрес электронной почты"), nil
+	case linkPattern.MatchString(text):
+		return decide(f.verdict, "в тексте указана ссылка", "в тексте указана ссылка"), nil
+	case hasPhone(text):
+		return decide(f.verdict, "в тексте указан номер телефона", "в тексте указан номер телефона"), nil
+	}
+	return Decision{Verdict: Allow}, nil
+}
+
+func hasPhone(text string) bool {
+	for _, candidate := range phonePattern.FindAllString(text, -1) {
+		digits := 0
+		for _, r := range candidate {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits >= 10 && digits <= 15 {
+			return true
+		}
+	}
+	return false
+}
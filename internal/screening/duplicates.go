@@ -0,0 +1,84 @@
+package screening
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+)
+
+// duplicateScanLimit — сколько последних объявлений автора сравнивается с новым.
+const duplicateScanLimit = 500
+
+// AdLister — часть AdRepository, нужная для поиска дубликатов.
+type AdLister interface {
+	List(ctx context.Context, filter repository.AdFilter) ([]models.Ad, error)
+}
+
+// DuplicateFilter находит у автора другое активное объявление с почти
+// тем же текстом. Тексты сравниваются как множества слов (коэффициент
+// Жаккара) без учёта регистра и знаков препинания.
+type DuplicateFilter struct {
+	ads       AdLister
+	threshold float64
+	verdict   Verdict
+}
+
+// NewDuplicateFilter создаёт фильтр. threshold — доля общих слов
+// (от 0 до 1), начиная с которой объявления считаются дубликатами.
+func NewDuplicateFilter(ads AdLister, threshold float64, verdict Verdict) *DuplicateFilter {
+	return &DuplicateFilter{ads: ads, threshold: threshold, verdict: verdict}
+}
+
+func (f *DuplicateFilter) Check(ctx context.Context, c *Content) (Decision, error) {
+	// Черновики и архив не мешают: дубликатом считается только то, что видят покупатели
+	ads, err := f.ads.List(ctx, repository.AdFilter{
+		UserID:    &c.UserID,
+		Statuses:  []models.AdStatus{models.AdStatusPublished, models.AdStatusReserved},
+		SortField: "created_at",
+		Order:     "DESC",
+		Limit:     duplicateScanLimit,
+	})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	words := wordSet(c.Title + " " + c.Description)
+	for _, ad := range ads {
+		if ad.ID == c.AdID {
+			continue
+		}
+		if similarity(words, wordSet(ad.Title+" "+ad.Description)) >= f.threshold {
+			return decide(f.verdict, "повторяет другое объявление автора", fmt.Sprintf("повторяет объявление %d", ad.ID)), nil
+		}
+	}
+	return Decision{Verdict: Allow}, nil
+}
+
+func wordSet(text string) map[string]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		set[w] = struct{}{}
+	}
+	return set
+}
+
+// similarity — коэффициент Жаккара двух множеств слов.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for w := range a {
+		if _, ok := b[w]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
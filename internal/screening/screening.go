@@ -0,0 +1,98 @@
+// Package screening проверяет текст объявлений перед сохранением:
+// запрещённые слова, контакты для связи в обход площадки, дубликаты.
+package screening
+
+import (
+	"context"
+	"fmt"
+)
+
+// Verdict — решение фильтра по объявлению.
+type Verdict string
+
+const (
+	// Allow — объявление сохраняется как обычно.
+	Allow Verdict = "allow"
+	// Flag — объявление сохраняется, но скрывается до решения модератора.
+	Flag Verdict = "flag"
+	// Reject — объявление не сохраняется.
+	Reject Verdict = "reject"
+)
+
+// ParseVerdict разбирает решение из конфигурации.
+func ParseVerdict(s string) (Verdict, error) {
+	switch v := Verdict(s); v {
+	case Allow, Flag, Reject:
+		return v, nil
+	}
+	return "", fmt.Errorf("неизвестное решение фильтра: %q", s)
+}
+
+func (v Verdict) rank() int {
+	switch v {
+	case Flag:
+		return 1
+	case Reject:
+		return 2
+	}
+	return 0
+}
+
+// Content — проверяемый текст объявления. AdID — ID редактируемого
+// объявления или 0 для нового.
+type Content struct {
+	AdID        uint
+	UserID      uint
+	Title       string
+	Description string
+}
+
+// Decision — итог проверки. Reasons — общие причины, которые можно
+// показать автору объявления. Details уточняют, что именно нашли
+// фильтры (например, какое запрещённое слово), и предназначены только
+// модераторам. При Allow оба списка пусты.
+type Decision struct {
+	Verdict Verdict
+	Reasons []string
+	Details []string
+}
+
+// ContentFilter проверяет объявление. Ошибка означает, что проверить
+// не удалось, а не что объявление плохое.
+type ContentFilter interface {
+	Check(ctx context.Context, c *Content) (Decision, error)
+}
+
+// Chain применяет фильтры по очереди и возвращает самое строгое решение
+// с причинами всех сработавших фильтров. Первый Reject прерывает проверку.
+type Chain []ContentFilter
+
+func (c Chain) Check(ctx context.Context, content *Content) (Decision, error) {
+	result := Decision{Verdict: Allow}
+	for _, f := range c {
+		d, err := f.Check(ctx, content)
+		if err != nil {
+			return Decision{}, err
+		}
+		if d.Verdict == Allow {
+			continue
+		}
+		result.Reasons = append(result.Reasons, d.Reasons...)
+		result.Details = append(result.Details, d.Details...)
+		if d.Verdict.rank() > result.Verdict.rank() {
+			result.Verdict = d.Verdict
+		}
+		if result.Verdict == Reject {
+			break
+		}
+	}
+	return result, nil
+}
+
+// decide возвращает verdict с причиной и подробностями или Allow, если причины нет.
+func decide(verdict Verdict, reason, detail string) Decision {
+	if reason == "" || verdict == Allow {
+		return Decision{Verdict: Allow}
+	}
+	return Decision{Verdict: verdict, Reasons: []string{reason}, Details: []string{detail}}
+}
@@ -0,0 +1,176 @@
+package screening
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+)
+
+type stubAds []models.Ad
+
+func (s stubAds) List(_ context.Context, filter repository.AdFilter) ([]models.Ad, error) {
+	var out []models.Ad
+	for _, ad := range s {
+		if filter.UserID != nil && ad.UserID != *filter.UserID {
+			continue
+		}
+		out = append(out, ad)
+	}
+	return out, nil
+}
+
+type failingFilter struct{}
+
+func (failingFilter) Check(context.Context, *Content) (Decision, error) {
+	return Decision{}, errors.New("сервис недоступен")
+}
+
+func TestWordFilter(t *testing.T) {
+	f, err := NewWordFilter([]string{"Ствол", " "}, []string{`патрон\S*`}, Reject)
+	if err != nil {
+		t.Fatalf("NewWordFilter: %v", err)
+	}
+
+	cases := []struct {
+		text string
+		want Verdict
+	}{
+		{"Продам СТВОЛ, недорого", Reject},
+		{"ствол", Reject},
+		{"Ствола дерева для поделок", Allow},
+		{"Коробка патронов", Reject},
+		{"Велосипед в хорошем состоянии", Allow},
+	}
+	for _, tc := range cases {
+		d, err := f.Check(context.Background(), &Content{Description: tc.text})
+		if err != nil {
+			t.Fatalf("Check(%q): %v", tc.text, err)
+		}
+		if d.Verdict != tc.want {
+			t.Errorf("Check(%q) = %s, ожидали %s", tc.text, d.Verdict, tc.want)
+		}
+	}
+
+	d, _ := f.Check(context.Background(), &Content{Title: "Ствол"})
+	if len(d.Reasons) != 1 || strings.Contains(d.Reasons[0], "Ствол") || len(d.Details) != 1 || !strings.Contains(d.Details[0], "Ствол") {
+		t.Errorf("Слово должно быть только в подробностях для модератора: %+v", d)
+	}
+
+	if _, err := NewWordFilter(nil, []string{"(("}, Reject); err == nil {
+		t.Error("Неверное выражение должно давать ошибку")
+	}
+}
+
+func TestContactFilter(t *testing.T) {
+	f := NewContactFilter(Flag)
+
+	cases := []struct {
+		text string
+		want Verdict
+	}{
+		{"Пишите на seller@example.com", Flag},
+		{"Подробнее на www.shop.example", Flag},
+		{"Смотрите фото на avito.ru", Flag},
+		{"Смотрите на сайте магазин.рф", Flag},
+		{"Звоните +7 (912) 345-67-89", Flag},
+		{"Звоните 8-912-345-67-89", Flag},
+		{"Цена 15 000 руб., торг", Allow},
+		{"Размер 1.5 м, вес 2.3 кг", Allow},
+		{"Диагональ 15.6 дюйма, 2021 года", Allow},
+	}
+	for _, tc := range cases {
+		d, err := f.Check(context.Background(), &Content{Title: "Объявление", Description: tc.text})
+		if err != nil {
+			t.Fatalf("Check(%q): %v", tc.text, err)
+		}
+		if d.Verdict != tc.want {
+			t.Errorf("Check(%q) = %s %v, ожидали %s", tc.text, d.Verdict, d.Reasons, tc.want)
+		}
+	}
+}
+
+func TestDuplicateFilter(t *testing.T) {
+	ads := stubAds{
+		{ID: 1, UserID: 1, Title: "Продам велосипед", Description: "Горный, 21 скорость, почти новый"},
+		{ID: 2, UserID: 2, Title: "Продам диван", Description: "Раскладной, серый"},
+	}
+	f := NewDuplicateFilter(ads, 0.8, Reject)
+
+	d, _ := f.Check(context.Background(), &Content{UserID: 1, Title: "ПРОДАМ велосипед!", Description: "Горный, 21 скорость, почти новый"})
+	if d.Verdict != Reject {
+		t.Errorf("Повтор своего объявления должен отклоняться: %+v", d)
+	}
+	d, _ = f.Check(context.Background(), &Content{AdID: 1, UserID: 1, Title: "Продам велосипед", Description: "Горный, 21 скорость, почти новый"})
+	if d.Verdict != Allow {
+		t.Errorf("Объявление не дублирует само себя: %+v", d)
+	}
+	d, _ = f.Check(context.Background(), &Content{UserID: 2, Title: "Продам велосипед", Description: "Горный, 21 скорость, почти новый"})
+	if d.Verdict != Allow {
+		t.Errorf("Объявления разных авторов не дубликаты: %+v", d)
+	}
+	d, _ = f.Check(context.Background(), &Content{UserID: 1, Title: "Продам велосипед", Description: "Детский, трёхколёсный, красный"})
+	if d.Verdict != Allow {
+		t.Errorf("Другой текст не дубликат: %+v", d)
+	}
+}
+
+func TestChain(t *testing.T) {
+	words, _ := NewWordFilter([]string{"оружие"}, nil, Reject)
+	chain := Chain{NewContactFilter(Flag), words}
+
+	d, err := chain.Check(context.Background(), &Content{Description: "Пишите на a@b.ru"})
+	if err != nil || d.Verdict != Flag || len(d.Reasons) != 1 {
+		t.Errorf("Контакт: %+v, %v", d, err)
+	}
+	d, _ = chain.Check(context.Background(), &Content{Description: "Оружие, пишите на a@b.ru"})
+	if d.Verdict != Reject || len(d.Reasons) != 2 {
+		t.Errorf("Побеждает самое строгое решение с причинами всех фильтров: %+v", d)
+	}
+	d, _ = chain.Check(context.Background(), &Content{Description: "Велосипед"})
+	if d.Verdict != Allow || len(d.Reasons) != 0 {
+		t.Errorf("Чистый текст: %+v", d)
+	}
+
+	// После Reject остальные фильтры не вызываются
+	d, err = Chain{words, failingFilter{}}.Check(context.Background(), &Content{Description: "оружие"})
+	if err != nil || d.Verdict != Reject {
+		t.Errorf("Reject должен прерывать проверку: %+v, %v", d, err)
+	}
+	if _, err := (Chain{failingFilter{}}).Check(context.Background(), &Content{}); err == nil {
+		t.Error("Ошибка фильтра должна возвращаться")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig("")
+	if err != nil || cfg.ContactVerdict != Flag || cfg.DuplicateVerdict != Reject {
+		t.Errorf("Настройки по умолчанию: %+v, %v", cfg, err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "filters.json")
+	os.WriteFile(path, []byte(`{"banned_words": ["оружие"], "contact_verdict": "allow"}`), 0o600)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	chain, err := NewChain(cfg, stubAds{})
+	if err != nil || len(chain) != 2 {
+		t.Errorf("Отключённый фильтр контактов не должен попасть в цепочку: %d фильтров, %v", len(chain), err)
+	}
+
+	os.WriteFile(path, []byte(`{"contact_verdict": "block"}`), 0o600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("Неизвестное решение должно давать ошибку")
+	}
+	os.WriteFile(path, []byte(`{"duplicate_threshold": 1.5}`), 0o600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("Порог вне (0, 1] должен давать ошибку")
+	}
+}
@@ -0,0 +1,52 @@
+package screening
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WordFilter ищет в тексте запрещённые слова и выражения. Слова
+// сравниваются без учёта регистра и только целиком: «ствол» не находит
+// «ствола», для словоформ используйте регулярные выражения.
+type WordFilter struct {
+	verdict  Verdict
+	patterns []*regexp.Regexp
+	labels   []string
+}
+
+// NewWordFilter собирает фильтр из списка слов и регулярных выражений
+// (синтаксис RE2). Найденное совпадение приводит к решению verdict.
+func NewWordFilter(words, patterns []string, verdict Verdict) (*WordFilter, error) {
+	f := &WordFilter{verdict: verdict}
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+		re := regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])` + regexp.QuoteMeta(w) + `(?:$|[^\p{L}\p{N}])`)
+		f.patterns = append(f.patterns, re)
+		f.labels = append(f.labels, w)
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("выражение %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+		f.labels = append(f.labels, p)
+	}
+	return f, nil
+}
+
+func (f *WordFilter) Check(_ context.Context, c *Content) (Decision, error) {
+	text := c.Title + "\n" + c.Description
+	for i, re := range f.patterns {
+		if re.MatchString(text) {
+			// Само слово автору не сообщается, чтобы фильтр было труднее обойти
+			return decide(f.verdict, "запрещённое содержимое", "запрещённое содержимое: "+f.labels[i]), nil
+		}
+	}
+	return Decision{Verdict: Allow}, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCursor возвращается для подделанного или повреждённого курсора.
+var ErrInvalidCursor = errors.New("невалидный курсор")
+
+// SignCursor сериализует v в JSON и подписывает HMAC-SHA256 ключом JWT_SECRET.
+// Результат — непрозрачная строка вида payload.signature в base64url.
+func SignCursor(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(cursorMAC(payload)), nil
+}
+
+// ParseCursor проверяет подпись курсора и разбирает его содержимое в v.
+func ParseCursor(cursor string, v any) error {
+	encPayload, encSig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, cursorMAC(payload)) {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// cursorMAC отделяет подписи курсоров от JWT префиксом домена,
+// чтобы одну подпись нельзя было выдать за другую.
+func cursorMAC(payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret())
+	mac.Write([]byte("cursor:"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -1,54 +1,101 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtSecret []byte
+const (
+	// AccessTokenTTL — время жизни access-токена (JWT).
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL — время жизни непрозрачного refresh-токена.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
 
-func init() {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET не задан в переменных окружения")
-	}
-	jwtSecret = []byte(secret)
+var (
+	jwtSecret     []byte
+	jwtSecretOnce sync.Once
+)
+
+// secret читает JWT_SECRET при первом обращении, а не при импорте пакета,
+// чтобы тесты успевали выставить переменную окружения в TestMain.
+func secret() []byte {
+	jwtSecretOnce.Do(func() {
+		s := os.Getenv("JWT_SECRET")
+		if s == "" {
+			log.Fatal("JWT_SECRET не задан в переменных окружения")
+		}
+		jwtSecret = []byte(s)
+	})
+	return jwtSecret
 }
 
+// Claims — содержимое access-токена. Роль и права фиксируются при выпуске
+// и обновляются вместе с токеном.
 type Claims struct {
-	Username string `json:"username"`
+	Username    string   `json:"username"`
+	SessionID   string   `json:"sid"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"perms,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(username string) (string, error) {
+// GenerateJWT выпускает короткоживущий access-токен, привязанный к сессии.
+func GenerateJWT(username, sessionID, role string, permissions []string) (string, error) {
 	claims := &Claims{
-		Username: username,
+		Username:    username,
+		SessionID:   sessionID,
+		Role:        role,
+		Permissions: permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "go-marketplace",
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(secret())
 }
 
-func ParseJWT(tokenStr string) (string, error) {
+func ParseJWT(tokenStr string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("неожиданный алгоритм подписи")
+		}
+		return secret(), nil
 	})
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims.Username, nil
+		return claims, nil
 	}
 
-	return "", errors.New("токен недействителен или истёк")
+	return nil, errors.New("токен недействителен или истёк")
+}
+
+// NewOpaqueToken генерирует случайный токен длиной n байт в base64url.
+func NewOpaqueToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken возвращает SHA-256 хэш токена; в базе хранятся только хэши.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
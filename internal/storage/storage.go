@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound возвращается, когда объекта с таким ключом нет в хранилище.
+var ErrNotFound = errors.New("объект не найден")
+
+// Object — содержимое объекта, полученное из хранилища.
+// Вызывающий обязан закрыть Body.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// Storage — хранилище бинарных объектов (картинок объявлений).
+// Ключи имеют вид пути: "ads/1/abc.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey проверяет, что ключ не выходит за пределы хранилища.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
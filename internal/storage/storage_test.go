@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 — минимальная замена S3 для тестов: хранит объекты в памяти
+// и пересчитывает подпись каждого запроса тем же ключом.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+	signer  *S3Storage
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	got := r.Header.Get("Authorization")
+	check := r.Clone(r.Context())
+	check.Header = r.Header.Clone()
+	check.Header.Del("Authorization")
+	f.signer.sign(check, body)
+	if got == "" || got != check.Header.Get("Authorization") {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[r.URL.Path])
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func exerciseStorage(t *testing.T, s Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "ads/1/photo.png", strings.NewReader("png-data"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	obj, err := s.Get(ctx, "ads/1/photo.png")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if !bytes.Equal(data, []byte("png-data")) || obj.ContentType != "image/png" {
+		t.Fatalf("Get вернул %q (%s)", data, obj.ContentType)
+	}
+
+	if err := s.Delete(ctx, "ads/1/photo.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "ads/1/photo.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("После удаления ожидали ErrNotFound, получили %v", err)
+	}
+	if err := s.Put(ctx, "../escape.png", strings.NewReader("x"), "image/png"); err == nil {
+		t.Fatal("Ключ с '..' должен отклоняться")
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exerciseStorage(t, s)
+}
+
+func TestS3Storage(t *testing.T) {
+	cfg := S3Config{Region: "eu-central-1", Bucket: "images", AccessKey: "test", SecretKey: "secret"}
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	cfg.Endpoint = srv.URL
+	s := NewS3Storage(cfg, srv.Client())
+	fixed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.now = func() time.Time { return fixed }
+	fake.signer = s
+
+	exerciseStorage(t, s)
+
+	// Запрос с чужим секретом хранилище должно отвергнуть
+	bad := NewS3Storage(S3Config{Endpoint: srv.URL, Region: cfg.Region, Bucket: cfg.Bucket, AccessKey: "test", SecretKey: "wrong"}, srv.Client())
+	bad.now = s.now
+	if err := bad.Put(context.Background(), "ads/1/x.png", strings.NewReader("x"), "image/png"); err == nil {
+		t.Fatal("Ожидали ошибку подписи")
+	}
+}
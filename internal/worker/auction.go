@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+)
+
+// AuctionResultPayload — уведомление победителю и продавцу о завершении аукциона.
+// Без победителя Amount и WinnerID пусты.
+type AuctionResultPayload struct {
+	AdID       uint     `json:"ad_id"`
+	Title      string   `json:"title"`
+	Amount     *float64 `json:"amount"`
+	WinnerID   *uint    `json:"winner_id"`
+	ReserveMet bool     `json:"reserve_met"`
+}
+
+// AuctionCloser завершает аукционы, время которых вышло. Если лидер есть
+// и резервная цена достигнута, он становится победителем и объявление
+// резервируется за ним, иначе объявление переходит в expired.
+type AuctionCloser struct {
+	tx            repository.Transactor
+	auctions      repository.AuctionRepository
+	ads           repository.AdRepository
+	outbox        repository.OutboxRepository
+	notifications repository.NotificationRepository
+}
+
+func NewAuctionCloser(
+	tx repository.Transactor,
+	auctions repository.AuctionRepository,
+	ads repository.AdRepository,
+	outbox repository.OutboxRepository,
+	notifications repository.NotificationRepository,
+) *AuctionCloser {
+	return &AuctionCloser{tx: tx, auctions: auctions, ads: ads, outbox: outbox, notifications: notifications}
+}
+
+// Run раз в interval завершает закончившиеся аукционы. Блокируется до отмены ctx.
+func (c *AuctionCloser) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := c.CloseDue(ctx, time.Now()); err != nil {
+			log.Printf("Ошибка завершения аукционов: %v", err)
+		} else if n > 0 {
+			log.Printf("Завершено аукционов: %d", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CloseDue синхронно завершает аукционы, закончившиеся к now,
+// и возвращает число завершённых.
+func (c *AuctionCloser) CloseDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := c.auctions.ListDue(ctx, now, 100)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for i := range due {
+		err := c.close(ctx, &due[i], now)
+		// Ставку успели сделать между выборкой и закрытием — аукцион
+		// мог продлиться, разберёмся на следующем проходе.
+		if errors.Is(err, repository.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+func (c *AuctionCloser) close(ctx context.Context, auction *models.Auction, now time.Time) error {
+	ad, err := c.ads.GetByID(ctx, auction.AdID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Объявление удалили — просто закрываем торги
+		auction.ClosedAt = &now
+		return c.auctions.Close(ctx, auction)
+	}
+	if err != nil {
+		return err
+	}
+
+	if auction.ReserveMet() {
+		auction.WinnerID = auction.LeaderID
+	}
+	auction.ClosedAt = &now
+
+	target := models.AdStatusExpired
+	if auction.WinnerID != nil {
+		target = models.AdStatusReserved
+	}
+
+	err = c.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := c.auctions.Close(ctx, auction); err != nil {
+			return err
+		}
+		if ad.Status != models.AdStatusPublished {
+			return nil
+		}
+		if err := c.ads.UpdateStatus(ctx, ad.ID, ad.Status, target, ad.ExpiresAt); err != nil {
+			return err
+		}
+		if auction.WinnerID != nil {
+			if err := c.ads.SetBuyer(ctx, ad.ID, auction.WinnerID); err != nil {
+				return err
+			}
+			ad.BuyerID = auction.WinnerID
+		}
+
+		ad.Status, ad.UpdatedAt = target, now
+		event, err := NewAdEvent(WebhookAdUpdated, ad)
+		if err != nil {
+			return err
+		}
+		return c.outbox.Add(ctx, event)
+	})
+	if err != nil {
+		return err
+	}
+
+	payload := AuctionResultPayload{
+		AdID:       ad.ID,
+		Title:      ad.Title,
+		WinnerID:   auction.WinnerID,
+		ReserveMet: auction.ReserveMet(),
+	}
+	if auction.WinnerID != nil {
+		payload.Amount = auction.CurrentBid
+		c.notify(ctx, *auction.WinnerID, models.NotificationAuctionWon, payload)
+	}
+	c.notify(ctx, ad.UserID, models.NotificationAuctionEnded, payload)
+	return nil
+}
+
+// notify записывает уведомление. Ошибки только логируются: торги уже закрыты.
+func (c *AuctionCloser) notify(ctx context.Context, userID uint, notificationType string, payload AuctionResultPayload) {
+	raw, err := json.Marshal(payload)
+	if err == nil {
+		err = c.notifications.Create(ctx, &models.Notification{UserID: userID, Type: notificationType, Payload: string(raw)})
+	}
+	if err != nil {
+		log.Printf("Ошибка уведомления %s пользователю %d: %v", notificationType, userID, err)
+	}
+}
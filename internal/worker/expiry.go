@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+)
+
+// RunAdExpiry раз в interval переводит объявления с истёкшим сроком показа
+// в состояние expired. Блокируется до отмены ctx.
+func RunAdExpiry(ctx context.Context, ads repository.AdRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := ads.ExpirePublished(ctx, time.Now())
+		if err != nil {
+			log.Printf("Ошибка снятия истёкших объявлений: %v", err)
+		} else if n > 0 {
+			log.Printf("Снято с показа истёкших объявлений: %d", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOfferExpiry раз в interval переводит предложения цены, на которые
+// не ответили вовремя, в состояние expired. Блокируется до отмены ctx.
+func RunOfferExpiry(ctx context.Context, offers repository.OfferRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := offers.ExpirePending(ctx, time.Now())
+		if err != nil {
+			log.Printf("Ошибка снятия истёкших предложений цены: %v", err)
+		} else if n > 0 {
+			log.Printf("Истекло предложений цены: %d", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+)
+
+// FavoriteDeliverFunc доставляет изменение объявления ad подписчикам userIDs.
+type FavoriteDeliverFunc func(ctx context.Context, ad AdEventData, userIDs []uint)
+
+// FavoritesNotifier рассылает изменения объявлений тем, у кого они
+// в избранном. Подключается к OutboxDispatcher: изменение приходит
+// из outbox уже после коммита и повторяется при сбое, поэтому запрос
+// продавца не ждёт рассылки, а рассылка не теряется при перезапуске.
+type FavoritesNotifier struct {
+	favorites repository.FavoriteRepository
+	deliver   FavoriteDeliverFunc
+}
+
+func NewFavoritesNotifier(favorites repository.FavoriteRepository, deliver FavoriteDeliverFunc) *FavoritesNotifier {
+	return &FavoritesNotifier{favorites: favorites, deliver: deliver}
+}
+
+// Publish рассылает событие ad.updated всем, у кого объявление в избранном.
+// Остальные события пропускаются.
+func (n *FavoritesNotifier) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	if event.Type != WebhookAdUpdated {
+		return nil
+	}
+
+	var ad AdEventData
+	if err := json.Unmarshal([]byte(event.Payload), &ad); err != nil {
+		// Повтор не исправит тело события
+		log.Printf("Событие %d: не удалось разобрать данные объявления: %v", event.ID, err)
+		return nil
+	}
+
+	userIDs, err := n.favorites.UserIDsByAd(ctx, event.AggregateID)
+	if err != nil || len(userIDs) == 0 {
+		return err
+	}
+	n.deliver(ctx, ad, userIDs)
+	return nil
+}
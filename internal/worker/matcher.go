@@ -0,0 +1,152 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+)
+
+// SavedSearchMatchPayload — содержимое уведомления о новом объявлении,
+// подходящем под сохранённый поиск.
+type SavedSearchMatchPayload struct {
+	SearchID   uint    `json:"search_id"`
+	SearchName string  `json:"search_name"`
+	AdID       uint    `json:"ad_id"`
+	Title      string  `json:"title"`
+	Price      float64 `json:"price"`
+}
+
+// SearchMatcher сверяет опубликованные объявления с сохранёнными поисками
+// и записывает уведомления их владельцам. Подключается к OutboxDispatcher
+// и получает объявления из событий outbox.
+type SearchMatcher struct {
+	ads           repository.AdRepository
+	searches      repository.SavedSearchRepository
+	categories    repository.CategoryRepository
+	notifications repository.NotificationRepository
+}
+
+func NewSearchMatcher(
+	ads repository.AdRepository,
+	searches repository.SavedSearchRepository,
+	categories repository.CategoryRepository,
+	notifications repository.NotificationRepository,
+) *SearchMatcher {
+	return &SearchMatcher{
+		ads:           ads,
+		searches:      searches,
+		categories:    categories,
+		notifications: notifications,
+	}
+}
+
+// Publish сверяет с сохранёнными поисками объявление из событий ad.created
+// и ad.updated. Повторная сверка не дублирует уведомления, поэтому
+// повтор события после сбоя безопасен.
+func (m *SearchMatcher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	if event.Type != WebhookAdCreated && event.Type != WebhookAdUpdated {
+		return nil
+	}
+	err := m.Match(ctx, event.AggregateID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil // объявление успели удалить
+	}
+	return err
+}
+
+// Match синхронно сверяет объявление с сохранёнными поисками. Каждое условие
+// проверяется тем же запросом, что и лента, поэтому результат совпадает
+// с выдачей GET /ads. Пользователь получает одно уведомление на объявление,
+// даже если оно подошло под несколько его поисков или было опубликовано повторно.
+func (m *SearchMatcher) Match(ctx context.Context, adID uint) error {
+	ad, err := m.ads.GetByID(ctx, adID)
+	if err != nil {
+		return err
+	}
+	if ad.Status != models.AdStatusPublished {
+		return nil
+	}
+
+	candidates, err := m.searches.ListCandidates(ctx, ad.Price, ad.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	notified := make(map[uint]bool)
+	descendants := make(map[uint][]uint)
+
+	for _, search := range candidates {
+		if notified[search.UserID] {
+			continue
+		}
+
+		filter := repository.AdFilter{
+			IDs:         []uint{ad.ID},
+			Statuses:    []models.AdStatus{models.AdStatusPublished},
+			ActiveAt:    now,
+			VisibleOnly: true,
+			Query:       search.Query,
+			MinPrice:    search.MinPrice,
+			MaxPrice:    search.MaxPrice,
+		}
+		if search.CategoryID != nil {
+			ids, ok := descendants[*search.CategoryID]
+			if !ok {
+				ids, err = m.categories.DescendantIDs(ctx, *search.CategoryID)
+				if errors.Is(err, repository.ErrNotFound) {
+					continue // раздел удалён, поиск больше ничего не найдёт
+				}
+				if err != nil {
+					return err
+				}
+				descendants[*search.CategoryID] = ids
+			}
+			filter.CategoryIDs = ids
+		}
+
+		n, err := m.ads.Count(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+
+		if err := m.notify(ctx, &search, ad); err != nil {
+			return err
+		}
+		notified[search.UserID] = true
+	}
+	return nil
+}
+
+func (m *SearchMatcher) notify(ctx context.Context, search *models.SavedSearch, ad *models.Ad) error {
+	payload, err := json.Marshal(SavedSearchMatchPayload{
+		SearchID:   search.ID,
+		SearchName: search.Name,
+		AdID:       ad.ID,
+		Title:      ad.Title,
+		Price:      ad.Price,
+	})
+	if err != nil {
+		return err
+	}
+
+	dedupKey := models.NotificationSavedSearchMatch + ":" + strconv.FormatUint(uint64(ad.ID), 10)
+	err = m.notifications.Create(ctx, &models.Notification{
+		UserID:   search.UserID,
+		Type:     models.NotificationSavedSearchMatch,
+		Payload:  string(payload),
+		DedupKey: &dedupKey,
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+)
+
+// Publisher доставляет событие из outbox потребителю: вебхукам, брокеру
+// сообщений, поисковому индексу. Доставка «хотя бы один раз»: после сбоя
+// событие публикуется повторно, поэтому Publish должен быть идемпотентным
+// или допускать дубли. Ошибка означает, что событие нужно повторить.
+type Publisher interface {
+	Publish(ctx context.Context, event *models.OutboxEvent) error
+}
+
+// Publishers рассылает событие нескольким потребителям по очереди.
+// Если один из них вернул ошибку, событие повторится для всех.
+type Publishers []Publisher
+
+func (p Publishers) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdEventData — данные событий ad.* для потребителей outbox. OldPrice
+// заполняется, только если событие изменило цену.
+type AdEventData struct {
+	ID          uint               `json:"id"`
+	UserID      uint               `json:"user_id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	ImageURL    string             `json:"image_url"`
+	Price       float64            `json:"price"`
+	OldPrice    *float64           `json:"old_price,omitempty"`
+	CategoryID  *uint              `json:"category_id"`
+	ListingType models.ListingType `json:"listing_type"`
+	Status      models.AdStatus    `json:"status"`
+	ExpiresAt   *time.Time         `json:"expires_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// NewAdEvent готовит событие outbox о текущем состоянии объявления.
+func NewAdEvent(eventType string, ad *models.Ad) (*models.OutboxEvent, error) {
+	return newAdEvent(eventType, ad, nil)
+}
+
+// NewAdPriceEvent готовит событие ad.updated об изменении цены с oldPrice.
+func NewAdPriceEvent(ad *models.Ad, oldPrice float64) (*models.OutboxEvent, error) {
+	return newAdEvent(WebhookAdUpdated, ad, &oldPrice)
+}
+
+func newAdEvent(eventType string, ad *models.Ad, oldPrice *float64) (*models.OutboxEvent, error) {
+	payload, err := json.Marshal(AdEventData{
+		ID:          ad.ID,
+		UserID:      ad.UserID,
+		Title:       ad.Title,
+		Description: ad.Description,
+		ImageURL:    ad.ImageURL,
+		Price:       ad.Price,
+		OldPrice:    oldPrice,
+		CategoryID:  ad.CategoryID,
+		ListingType: ad.ListingType,
+		Status:      ad.Status,
+		ExpiresAt:   ad.ExpiresAt,
+		CreatedAt:   ad.CreatedAt,
+		UpdatedAt:   ad.UpdatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.OutboxEvent{
+		Type:        eventType,
+		AggregateID: ad.ID,
+		UserID:      ad.UserID,
+		Payload:     string(payload),
+	}, nil
+}
+
+// OutboxDispatcher читает outbox, публикует события через Publisher
+// и отмечает опубликованные. Неудачные публикации повторяются
+// с экспоненциальной задержкой, пока не пройдут.
+type OutboxDispatcher struct {
+	outbox    repository.OutboxRepository
+	publisher Publisher
+
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Lease — на сколько откладывается событие, пока идёт публикация.
+	// Если процесс упадёт, другой экземпляр заберёт его по истечении Lease.
+	Lease     time.Duration
+	BatchSize int
+	// Retention — сколько хранить опубликованные события.
+	Retention time.Duration
+}
+
+func NewOutboxDispatcher(outbox repository.OutboxRepository, publisher Publisher) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		outbox:      outbox,
+		publisher:   publisher,
+		BaseBackoff: 5 * time.Second,
+		MaxBackoff:  10 * time.Minute,
+		Lease:       time.Minute,
+		BatchSize:   100,
+		Retention:   7 * 24 * time.Hour,
+	}
+}
+
+// Run раз в interval публикует накопившиеся события и удаляет старые
+// опубликованные. Блокируется до отмены ctx.
+func (d *OutboxDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.Dispatch(ctx)
+			if err != nil {
+				log.Printf("Ошибка публикации событий outbox: %v", err)
+			}
+			// Полная пачка — скорее всего, в очереди есть ещё
+			if err != nil || n < d.BatchSize {
+				break
+			}
+		}
+		if _, err := d.outbox.PurgePublished(ctx, time.Now().Add(-d.Retention)); err != nil {
+			log.Printf("Ошибка очистки outbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch синхронно публикует наступившие события и возвращает число обработанных.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context) (int, error) {
+	events, err := d.outbox.ClaimPending(ctx, time.Now(), d.Lease, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range events {
+		event := &events[i]
+		if err := d.publisher.Publish(ctx, event); err != nil {
+			event.Attempts++
+			log.Printf("Ошибка публикации события %d (%s), попытка %d: %v", event.ID, event.Type, event.Attempts, err)
+			next := time.Now().Add(backoff(d.BaseBackoff, d.MaxBackoff, event.Attempts))
+			if err := d.outbox.MarkFailed(ctx, event.ID, event.Attempts, next, truncate(err.Error(), 500)); err != nil {
+				return i, err
+			}
+			continue
+		}
+		if err := d.outbox.MarkPublished(ctx, event.ID, time.Now()); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+// backoff возвращает задержку перед следующей попыткой после attempts
+// неудачных: base, затем вдвое дольше каждый раз, но не больше limit.
+func backoff(base, limit time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookAddress — адрес вебхука ведёт в локальную или внутреннюю сеть.
+var ErrWebhookAddress = errors.New("адрес вебхука ведёт во внутреннюю сеть")
+
+// sharedAddressSpace — адреса операторского NAT (RFC 6598), снаружи недоступны.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicWebhookAddr сообщает, можно ли отправлять вебхук на этот адрес.
+// Закрыты loopback, частные и link-local сети (в том числе адрес
+// метаданных облака 169.254.169.254), а также служебные диапазоны.
+func PublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// CheckWebhookHost проверяет все адреса, в которые разрешается host.
+// Проверка при регистрации не защищает от смены DNS-записи, поэтому
+// адрес повторно проверяется при каждом соединении (см. NewWebhookClient).
+func CheckWebhookHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !PublicWebhookAddr(addr) {
+			return ErrWebhookAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !PublicWebhookAddr(addr) {
+			return ErrWebhookAddress
+		}
+	}
+	return nil
+}
+
+// NewWebhookClient возвращает клиент для доставки вебхуков. Адрес
+// проверяется уже после разрешения имени, непосредственно перед каждым
+// соединением, а перенаправления не выполняются: ответ 3xx считается
+// неудачной доставкой.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !PublicWebhookAddr(addr) {
+				return ErrWebhookAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Через прокси соединение шло бы к прокси, и проверка адреса теряла бы смысл
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,232 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/WalnutBagel/go-marketplace/internal/models"
+	"github.com/WalnutBagel/go-marketplace/internal/repository"
+)
+
+// События об объявлениях, на которые можно подписать вебхук.
+const (
+	WebhookAdCreated = "ad.created"
+	WebhookAdUpdated = "ad.updated"
+	WebhookAdDeleted = "ad.deleted"
+)
+
+// WebhookEvents — все поддерживаемые события.
+var WebhookEvents = []string{WebhookAdCreated, WebhookAdUpdated, WebhookAdDeleted}
+
+// Заголовки запроса доставки.
+const (
+	WebhookEventHeader     = "X-Marketplace-Event"
+	WebhookDeliveryHeader  = "X-Marketplace-Delivery"
+	WebhookSignatureHeader = "X-Marketplace-Signature"
+)
+
+// WebhookPayload — тело запроса, которое получает вебхук.
+// ID — номер события: при повторной публикации после сбоя одно
+// и то же событие может прийти дважды с разными ID доставки.
+type WebhookPayload struct {
+	ID        uint            `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SignWebhook считает подпись тела запроса: HMAC-SHA256 секретом вебхука
+// от строки "timestamp.body". Метка времени входит в подпись, чтобы
+// получатель мог отбросить перехваченный и повторно отправленный запрос.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + strconv.FormatInt(timestamp, 10) + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDispatcher записывает доставки событий в журнал и в фоне
+// отправляет их, повторяя неудачные попытки с экспоненциальной задержкой.
+type WebhookDispatcher struct {
+	webhooks repository.WebhookRepository
+	client   *http.Client
+
+	// MaxAttempts — после стольких неудачных попыток доставка помечается failed.
+	MaxAttempts int
+	// BaseBackoff — задержка после первой неудачи; каждая следующая вдвое дольше,
+	// но не больше MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BatchSize — сколько доставок забирается за один проход.
+	BatchSize int
+}
+
+// NewWebhookDispatcher создаёт диспетчер. Без client используется
+// NewWebhookClient, который не ходит во внутреннюю сеть.
+func NewWebhookDispatcher(webhooks repository.WebhookRepository, client *http.Client) *WebhookDispatcher {
+	if client == nil {
+		client = NewWebhookClient(10 * time.Second)
+	}
+	return &WebhookDispatcher{
+		webhooks:    webhooks,
+		client:      client,
+		MaxAttempts: 8,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+		BatchSize:   50,
+	}
+}
+
+// Publish ставит событие outbox в очередь доставки на все подписанные
+// вебхуки: владельца объявления и администраторские вебхуки со всеми
+// объявлениями. Остальные события пропускаются.
+func (d *WebhookDispatcher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	if !slices.Contains(WebhookEvents, event.Type) {
+		return nil
+	}
+
+	webhooks, err := d.webhooks.ListSubscribed(ctx, event.Type, event.UserID)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		ID:        event.ID,
+		Event:     event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		err := d.webhooks.CreateDelivery(ctx, &models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event.Type,
+			Payload:       string(body),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run раз в interval отправляет доставки, время которых наступило.
+// Блокируется до отмены ctx.
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil {
+			log.Printf("Ошибка доставки вебхуков: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue синхронно делает по одной попытке для каждой наступившей
+// доставки и возвращает число обработанных.
+func (d *WebhookDispatcher) DeliverDue(ctx context.Context) (int, error) {
+	// Аренда чуть дольше таймаута клиента: пока попытка идёт,
+	// доставку не заберёт другой экземпляр.
+	lease := d.client.Timeout + time.Minute
+	deliveries, err := d.webhooks.ClaimDueDeliveries(ctx, time.Now(), lease, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deliveries {
+		if err := d.attempt(ctx, &deliveries[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (d *WebhookDispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	webhook, err := d.webhooks.GetByID(ctx, delivery.WebhookID)
+	if err != nil {
+		// Вебхук удалён вместе с журналом между выборкой и попыткой.
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	status, sendErr := d.send(ctx, webhook, delivery, now)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliverySucceeded
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = truncate(sendErr.Error(), 500)
+	default:
+		delivery.NextAttemptAt = now.Add(backoff(d.BaseBackoff, d.MaxBackoff, delivery.Attempts))
+		delivery.LastError = truncate(sendErr.Error(), 500)
+	}
+	return d.webhooks.UpdateDelivery(ctx, delivery)
+}
+
+// send отправляет доставку и возвращает код ответа. Успехом считается любой 2xx.
+func (d *WebhookDispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, now.Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("ответ %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}